@@ -0,0 +1,316 @@
+// Package capture records radio traffic to files and replays it, so that
+// protocol experiments and tests can use real recorded traffic instead of
+// waveforms decoded by hand.
+//
+// Captures are JSON Lines with one Record per line, e.g.
+//
+//	{"time":"2020-01-01T12:00:00Z","direction":"rx","payload":"57160a2e04053d260e","rssi":-60,"lqi":3,"crc_ok":true}
+package capture
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hatstand/shinywaffle"
+	"github.com/hatstand/shinywaffle/emulator"
+	"github.com/hatstand/shinywaffle/radiator"
+)
+
+// How long to wait for an emulated radio to return to RX before giving up on
+// delivering a packet to it.
+const deliverTimeout = time.Second
+
+// Direction is whether a frame was received or transmitted.
+type Direction string
+
+const (
+	Received    Direction = "rx"
+	Transmitted Direction = "tx"
+)
+
+// Payload is a packet's contents, encoded in hexadecimal in captures.
+type Payload []byte
+
+func (p Payload) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(p)), nil
+}
+
+func (p *Payload) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid payload %q: %w", text, err)
+	}
+	*p = b
+	return nil
+}
+
+// Record is a single frame in a capture. Signal strength, link quality and
+// the CRC check are only known for received frames.
+type Record struct {
+	Time      time.Time `json:"time"`
+	Direction Direction `json:"direction"`
+	Payload   Payload   `json:"payload"`
+	RSSI      int       `json:"rssi,omitempty"`
+	LQI       byte      `json:"lqi,omitempty"`
+	CRCOK     bool      `json:"crc_ok,omitempty"`
+}
+
+// FromFrame returns the record of a received frame.
+func FromFrame(f shinywaffle.Frame) Record {
+	return Record{
+		Time:      f.Time,
+		Direction: Received,
+		Payload:   Payload(f.Payload),
+		RSSI:      f.RSSI,
+		LQI:       f.LQI,
+		CRCOK:     f.CRCOK,
+	}
+}
+
+// Frame returns the frame as it was received.
+func (r Record) Frame() shinywaffle.Frame {
+	return shinywaffle.Frame{
+		Payload: []byte(r.Payload),
+		RSSI:    r.RSSI,
+		LQI:     r.LQI,
+		CRCOK:   r.CRCOK,
+		Time:    r.Time,
+	}
+}
+
+// Writer appends records to a capture. It is safe for concurrent use so that
+// received and transmitted frames can be recorded to the same capture.
+type Writer struct {
+	lock sync.Mutex
+	enc  *json.Encoder
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{enc: json.NewEncoder(w)}
+}
+
+// Write appends r to the capture.
+func (w *Writer) Write(r Record) error {
+	if r.Direction != Received && r.Direction != Transmitted {
+		return fmt.Errorf("invalid direction: %q", r.Direction)
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	return nil
+}
+
+// Reader reads records from a capture.
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{scanner: bufio.NewScanner(r)}
+}
+
+// Read returns the next record in the capture, or io.EOF at the end. Blank
+// lines are skipped.
+func (r *Reader) Read() (Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			return Record{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		if record.Direction != Received && record.Direction != Transmitted {
+			return Record{}, fmt.Errorf("line %d: invalid direction: %q", r.line, record.Direction)
+		}
+		return record, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Record{}, fmt.Errorf("failed to read capture: %w", err)
+	}
+	return Record{}, io.EOF
+}
+
+// ReadAll returns every record in a capture.
+func ReadAll(r io.Reader) ([]Record, error) {
+	reader := NewReader(r)
+	var records []Record
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+// RecordFrames writes every frame from frames to w and passes it on to the
+// returned channel, which is closed when frames is. Failures to write are
+// passed to onError, which may be nil.
+func RecordFrames(frames <-chan shinywaffle.Frame, w *Writer, onError func(error)) <-chan shinywaffle.Frame {
+	out := make(chan shinywaffle.Frame, cap(frames))
+	go func() {
+		defer close(out)
+		for f := range frames {
+			if err := w.Write(FromFrame(f)); err != nil && onError != nil {
+				onError(err)
+			}
+			out <- f
+		}
+	}()
+	return out
+}
+
+// RecordingSender records every packet successfully sent by a radiator.Sender.
+type RecordingSender struct {
+	sender  radiator.Sender
+	w       *Writer
+	onError func(error)
+}
+
+// NewRecordingSender records packets sent by sender to w. Failures to write
+// are passed to onError, which may be nil, rather than failing the send.
+func NewRecordingSender(sender radiator.Sender, w *Writer, onError func(error)) *RecordingSender {
+	return &RecordingSender{sender: sender, w: w, onError: onError}
+}
+
+// Send sends packet and records it with the time transmission started.
+func (s *RecordingSender) Send(packet []byte) error {
+	_, err := s.Transmit(packet)
+	return err
+}
+
+// Transmit is Send, also reporting how long the wrapped sender waited for a
+// clear channel if it is a shinywaffle.CC1101.
+func (s *RecordingSender) Transmit(packet []byte) (shinywaffle.TransmitResult, error) {
+	start := time.Now()
+	result := shinywaffle.TransmitResult{Attempts: 1}
+	var err error
+	if t, ok := s.sender.(interface {
+		Transmit(packet []byte) (shinywaffle.TransmitResult, error)
+	}); ok {
+		result, err = t.Transmit(packet)
+	} else {
+		err = s.sender.Send(packet)
+	}
+	if err != nil {
+		return result, err
+	}
+	err = s.w.Write(Record{
+		Time:      start,
+		Direction: Transmitted,
+		Payload:   append(Payload(nil), packet...),
+	})
+	if err != nil && s.onError != nil {
+		s.onError(err)
+	}
+	return result, nil
+}
+
+// wait sleeps for the time between two records divided by speed. A speed of
+// zero or less doesn't wait at all.
+func wait(ctx context.Context, previous, next time.Time, speed float64) error {
+	if speed <= 0 || previous.IsZero() || !next.After(previous) {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(time.Duration(float64(next.Sub(previous)) / speed))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Replay sends the received frames in records on the returned channel with
+// their recorded times, e.g. to be decoded by a sniffer. Frames are spaced
+// out as they were recorded, sped up by speed, or sent as fast as they are
+// read if speed is zero. The channel is closed at the end of the records or
+// when ctx is cancelled.
+func Replay(ctx context.Context, records []Record, speed float64) <-chan shinywaffle.Frame {
+	frames := make(chan shinywaffle.Frame)
+	go func() {
+		defer close(frames)
+		var previous time.Time
+		for _, r := range records {
+			if r.Direction != Received {
+				continue
+			}
+			if err := wait(ctx, previous, r.Time, speed); err != nil {
+				return
+			}
+			previous = r.Time
+			select {
+			case frames <- r.Frame():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return frames
+}
+
+// ReplayToRadio delivers the received frames in records over the air to an
+// emulated CC1101, paced as for Replay, so that they go through the driver.
+// Each frame waits up to a second for the radio to be ready to receive it.
+// It returns the number of frames the radio accepted.
+func ReplayToRadio(ctx context.Context, radio *emulator.Radio, records []Record, speed float64) (int, error) {
+	delivered := 0
+	var previous time.Time
+	for _, r := range records {
+		if r.Direction != Received {
+			continue
+		}
+		if err := wait(ctx, previous, r.Time, speed); err != nil {
+			return delivered, err
+		}
+		previous = r.Time
+		packet := emulator.Packet{
+			Payload:    []byte(r.Payload),
+			RSSI:       r.RSSI,
+			LQI:        r.LQI,
+			CorruptCRC: !r.CRCOK,
+		}
+		ok, err := deliver(ctx, radio, packet)
+		if err != nil {
+			return delivered, err
+		}
+		if ok {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// deliver retries receiving packet until the radio accepts it or
+// deliverTimeout passes, e.g. while the driver is reading out the previous
+// packet.
+func deliver(ctx context.Context, radio *emulator.Radio, packet emulator.Packet) (bool, error) {
+	deadline := time.Now().Add(deliverTimeout)
+	for !radio.Receive(packet) {
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,184 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle"
+	"github.com/hatstand/shinywaffle/emulator"
+	"github.com/hatstand/shinywaffle/radiator"
+	"github.com/hatstand/shinywaffle/radiator/radiatortest"
+	"github.com/hatstand/shinywaffle/sniffer"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var start = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func loadCapture(path string) []Record {
+	f, err := os.Open(path)
+	So(err, ShouldBeNil)
+	defer f.Close()
+	records, err := ReadAll(f)
+	So(err, ShouldBeNil)
+	return records
+}
+
+func TestCapture(t *testing.T) {
+	Convey("Records round trip", t, func() {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		rx := Record{Time: start, Direction: Received, Payload: Payload{0x57, 0x16}, RSSI: -60, LQI: 3, CRCOK: true}
+		tx := Record{Time: start.Add(time.Second), Direction: Transmitted, Payload: Payload{0x01}}
+		So(w.Write(rx), ShouldBeNil)
+		So(w.Write(tx), ShouldBeNil)
+		So(buf.String(), ShouldStartWith, `{"time":"2020-01-01T12:00:00Z","direction":"rx","payload":"5716","rssi":-60,"lqi":3,"crc_ok":true}`+"\n")
+
+		records, err := ReadAll(&buf)
+		So(err, ShouldBeNil)
+		So(records, ShouldResemble, []Record{rx, tx})
+	})
+
+	Convey("Invalid records", t, func() {
+		So(NewWriter(&bytes.Buffer{}).Write(Record{Direction: "sideways"}), ShouldNotBeNil)
+
+		_, err := ReadAll(strings.NewReader(`{"direction":"rx","payload":"zz"}`))
+		So(err, ShouldNotBeNil)
+		_, err = ReadAll(strings.NewReader("\n" + `{"direction":"up"}`))
+		So(err.Error(), ShouldContainSubstring, "line 2")
+	})
+
+	Convey("Frames", t, func() {
+		f := shinywaffle.Frame{Payload: []byte{0x42}, RSSI: -70, LQI: 4, CRCOK: true, Time: start}
+		So(FromFrame(f).Frame(), ShouldResemble, f)
+	})
+
+	Convey("Recording received frames", t, func() {
+		var buf bytes.Buffer
+		frames := make(chan shinywaffle.Frame, 1)
+		recorded := RecordFrames(frames, NewWriter(&buf), nil)
+		f := shinywaffle.Frame{Payload: []byte{0x42}, RSSI: -70, CRCOK: true, Time: start}
+		frames <- f
+		So(<-recorded, ShouldResemble, f)
+		close(frames)
+		_, ok := <-recorded
+		So(ok, ShouldBeFalse)
+
+		records, err := ReadAll(&buf)
+		So(err, ShouldBeNil)
+		So(records, ShouldResemble, []Record{FromFrame(f)})
+	})
+
+	Convey("Recording transmitted frames", t, func() {
+		var buf bytes.Buffer
+		sender := &radiatortest.Sender{}
+		recording := NewRecordingSender(sender, NewWriter(&buf), nil)
+		So(recording.Send([]byte{0x01, 0x02}), ShouldBeNil)
+		So(sender.Packets(), ShouldResemble, [][]byte{{0x01, 0x02}})
+
+		sender.Err = errors.New("busy")
+		So(recording.Send([]byte{0x03}), ShouldNotBeNil)
+
+		records, err := ReadAll(&buf)
+		So(err, ShouldBeNil)
+		So(records, ShouldHaveLength, 1)
+		So(records[0].Direction, ShouldEqual, Transmitted)
+		So(records[0].Payload, ShouldResemble, Payload{0x01, 0x02})
+	})
+
+	Convey("Failing to record a sent packet doesn't fail the send", t, func() {
+		sender := &radiatortest.Sender{}
+		var errs []error
+		recording := NewRecordingSender(sender, NewWriter(failingWriter{}), func(err error) {
+			errs = append(errs, err)
+		})
+		So(recording.Send([]byte{0x01}), ShouldBeNil)
+		So(sender.Packets(), ShouldHaveLength, 1)
+		So(errs, ShouldHaveLength, 1)
+	})
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestReplay(t *testing.T) {
+	Convey("Replays received frames", t, func() {
+		records := loadCapture("testdata/study.jsonl")
+		var frames []shinywaffle.Frame
+		for f := range Replay(context.Background(), records, 0) {
+			frames = append(frames, f)
+		}
+		So(frames, ShouldHaveLength, 5)
+		So(frames[0], ShouldResemble, records[0].Frame())
+		So(frames[4], ShouldResemble, records[5].Frame())
+	})
+
+	Convey("Paces frames by speed", t, func() {
+		records := []Record{
+			{Time: start, Direction: Received, Payload: Payload{0x01}},
+			{Time: start.Add(time.Second), Direction: Received, Payload: Payload{0x02}},
+		}
+		begin := time.Now()
+		for range Replay(context.Background(), records, 20) {
+		}
+		So(time.Since(begin), ShouldBeGreaterThanOrEqualTo, 50*time.Millisecond)
+	})
+
+	Convey("Decodes a recorded wall remote", t, func() {
+		registry := radiator.NewRegistry()
+		registry.Add(radiator.Info{Address: 0x2e04, Zone: "Study"})
+		frames := Replay(context.Background(), loadCapture("testdata/study.jsonl"), 0)
+		var events []*sniffer.Event
+		var failed int
+		for e := range sniffer.New(registry).Run(context.Background(), frames, func(shinywaffle.Frame, error) {
+			failed++
+		}) {
+			events = append(events, e)
+		}
+		// The repeated packets and the junk are dropped.
+		So(events, ShouldHaveLength, 2)
+		So(failed, ShouldEqual, 1)
+		So(events[0].Zone, ShouldEqual, "Study")
+		So(events[0].Setting.Day, ShouldEqual, 30.5)
+		So(events[1].Setting.Day, ShouldEqual, 20)
+		So(*events[1].Previous, ShouldResemble, events[0].Setting)
+	})
+
+	Convey("Replays through an emulated radio", t, func() {
+		radio := emulator.New()
+		cc1101, err := shinywaffle.New(radio, radio.GDO0(), radio.GDO2(), shinywaffle.DefaultProfile())
+		So(err, ShouldBeNil)
+		defer cc1101.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		frames, err := cc1101.Listen(ctx)
+		So(err, ShouldBeNil)
+
+		var good []Record
+		for _, r := range loadCapture("testdata/study.jsonl") {
+			if r.CRCOK {
+				good = append(good, r)
+			}
+		}
+		delivered, err := ReplayToRadio(ctx, radio, good, 0)
+		So(err, ShouldBeNil)
+		So(delivered, ShouldEqual, 4)
+		for _, r := range good {
+			f := <-frames
+			So(f.Payload, ShouldResemble, []byte(r.Payload))
+			So(f.RSSI, ShouldEqual, r.RSSI)
+		}
+
+		cancel()
+		for range frames {
+		}
+	})
+}
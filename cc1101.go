@@ -5,16 +5,16 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/hatstand/shinywaffle/config"
-	"github.com/kidoman/embd"
-	_ "github.com/kidoman/embd/host/rpi"
+	"github.com/hatstand/shinywaffle/hal"
 )
 
 var gdo0pin = flag.Int("gdo0", 25, "GPIO pin connected to CC1101 GDO0 (BCM numbering)")
 var gdo2pin = flag.Int("gdo2", 24, "GPIO pin connected to CC1101 GDO2 (BCM numbering)")
+var rfProfile = flag.String("rf_profile", "", "Path to a SmartRF Studio export or textproto of CC1101 register values. Defaults to 868.3MHz 2-FSK")
 
 const (
 	// Read/write flags.
@@ -31,24 +31,57 @@ const (
 	// Bitmask for reading state out of chip status byte.
 	STATE = 0x70
 
-	CRC_OK      = 0x80
-	RSSI        = 0
-	LQI         = 1
-	RSSI_OFFSET = 74
+	CRC_OK = 0x80
+	// PKTSTATUS bits.
+	PKTSTATUS_CS  = 0x40
+	PKTSTATUS_CCA = 0x10
+	RSSI          = 0
+	LQI           = 1
+	RSSI_OFFSET   = 74
+
+	// Set in the chip status byte until the crystal is running after reset or sleep.
+	CHIP_RDYN = 0x80
 
 	// Strobes
-	SRES  = 0x30 // Reset
-	SRX   = 0x34 // Set receive mode
-	STX   = 0x35 // Set transmit mode
-	SIDLE = 0x36
-	SFRX  = 0x3a // Flush RX FIFO buffer
-	SFTX  = 0x3b // Flush TX FIFO buffer
-	SNOP  = 0x3d
-
-	// Status Registers
-	PARTNUM = 0xf0
-	VERSION = 0xf1
-	RXBYTES = 0x3b
+	SRES    = 0x30 // Reset
+	SFSTXON = 0x31 // Enable and calibrate frequency synthesizer
+	SXOFF   = 0x32 // Turn off crystal oscillator
+	SCAL    = 0x33 // Calibrate frequency synthesizer and turn it off
+	SRX     = 0x34 // Set receive mode
+	STX     = 0x35 // Set transmit mode
+	SIDLE   = 0x36
+	SWOR    = 0x38 // Start automatic RX polling sequence (Wake-on-Radio)
+	SPWD    = 0x39 // Enter power down mode when CSn goes high
+	SFRX    = 0x3a // Flush RX FIFO buffer
+	SFTX    = 0x3b // Flush TX FIFO buffer
+	SWORRST = 0x3c // Reset real time clock to Event1 value
+	SNOP    = 0x3d
+
+	// Status Registers. These share addresses with the strobes so are read
+	// with the burst bit set.
+	PARTNUM     = 0xf0
+	VERSION     = 0xf1
+	FREQEST     = 0xf2
+	LQI_STATUS  = 0xf3
+	RSSI_STATUS = 0xf4
+	MARCSTATE   = 0xf5
+	WORTIME1    = 0xf6
+	WORTIME0    = 0xf7
+	PKTSTATUS   = 0xf8
+	VCO_VC_DAC  = 0xf9
+	TXBYTES     = 0xfa
+	RXBYTES     = 0xfb
+
+	// Main Radio Control State Machine states read from MARCSTATE.
+	MARCSTATE_SLEEP            = 0x00
+	MARCSTATE_IDLE             = 0x01
+	MARCSTATE_RX               = 0x0d
+	MARCSTATE_RX_END           = 0x0e
+	MARCSTATE_RX_RST           = 0x0f
+	MARCSTATE_RXFIFO_OVERFLOW  = 0x11
+	MARCSTATE_TX               = 0x13
+	MARCSTATE_TX_END           = 0x14
+	MARCSTATE_TXFIFO_UNDERFLOW = 0x16
 
 	// Config Registers
 	IOCFG2 = 0x00
@@ -125,72 +158,130 @@ func convertRSSI(rssi int) int {
 	}
 }
 
+const (
+	// Number of received frames buffered for a slow consumer before dropping them.
+	frameBufferSize = 16
+	// How often the receive loop checks that the radio has not got stuck.
+	rxWatchdogInterval = 10 * time.Second
+)
+
+// Frame is a packet received by the CC1101 along with the link quality
+// reported in the status bytes appended to it.
+type Frame struct {
+	Payload []byte
+	// Received signal strength in dBm.
+	RSSI int
+	// Link quality indicator. Lower is better.
+	LQI byte
+	// Whether the packet passed the CRC check.
+	CRCOK bool
+	// When the packet was read out of the RX FIFO.
+	Time time.Time
+}
+
 type CC1101 struct {
-	bus embd.SPIBus
+	bus hal.SPI
 	// Configured to emit a rising edge on receiving packets that pass the CRC check.
 	// See IOCFG0.
-	gdo0 embd.DigitalPin
+	gdo0 hal.Pin
 	// Configured to emit a rising edge when the sync word has been transmitted
 	// and to emit a falling edge once a packet has been transmitted.
 	// See IOCFG2.
-	gdo2 embd.DigitalPin
-	lock sync.Mutex
+	gdo2 hal.Pin
+	// Register values written by Init.
+	profile *RegisterProfile
+	// Frequency of the crystal in Hz, or zero for DefaultCrystalHz.
+	crystalHz float64
+	lock      sync.Mutex
+	// Whether Listen is running and the radio should return to RX when idle.
+	listening bool
+	// Whether the radio should poll with Wake-on-Radio rather than stay in RX.
+	wakeOnRadio bool
+	// Whether the radio is in SLEEP, either powered down or between
+	// Wake-on-Radio polls, and has lost its TEST registers and PATABLE.
+	sleeping bool
+	// PATABLE written by SetTxPower, restored after sleeping.
+	paTable []byte
+	// Clear channel assessment settings, or nil to transmit immediately.
+	lbt *ListenBeforeTalk
 }
 
-func NewCC1101(packetCh chan<- []byte) CC1101 {
-	err := embd.InitSPI()
-	if err != nil {
-		panic(err)
+// NewCC1101 configures the radio with the profile given by the -rf_profile
+// flag, or the default profile if unset.
+func NewCC1101() (*CC1101, error) {
+	profile := DefaultProfile()
+	if *rfProfile != "" {
+		var err error
+		profile, err = LoadRegisterProfile(*rfProfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load RF profile: %w", err)
+		}
 	}
+	return NewCC1101WithProfile(profile)
+}
 
-	err = embd.InitGPIO()
-	if err != nil {
-		panic(err)
+// NewCC1101WithProfile configures the radio with the given register profile.
+func NewCC1101WithProfile(profile *RegisterProfile) (*CC1101, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid RF profile %q: %w", profile.Name, err)
 	}
 
-	gdo0, err := embd.NewDigitalPin(*gdo0pin)
+	bus, gdo0, gdo2, err := openHardware()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	gdo0.SetDirection(embd.In)
-
-	gdo2, err := embd.NewDigitalPin(*gdo2pin)
+	cc1101, err := New(bus, gdo0, gdo2, profile)
 	if err != nil {
-		panic(err)
+		bus.Close()
+		gdo0.Close()
+		gdo2.Close()
+		return nil, err
 	}
-	gdo2.SetDirection(embd.In)
-
-	bus := embd.NewSPIBus(embd.SPIMode0, 0, 50000, 8, 0)
+	if *lbtEnabled {
+		if err := cc1101.SetListenBeforeTalk(DefaultListenBeforeTalk()); err != nil {
+			cc1101.Close()
+			return nil, err
+		}
+	}
+	return cc1101, nil
+}
 
-	cc1101 := CC1101{
+// New configures a CC1101 connected to bus with GDO0 and GDO2 wired to the
+// given pins. If successful the CC1101 takes ownership of the bus and pins and
+// closes them in Close.
+func New(bus hal.SPI, gdo0 hal.Pin, gdo2 hal.Pin, profile *RegisterProfile) (*CC1101, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid RF profile %q: %w", profile.Name, err)
+	}
+	cc1101 := &CC1101{
 		bus:  bus,
 		gdo0: gdo0,
 		gdo2: gdo2,
+		// Copied as the setters change it.
+		profile: &RegisterProfile{
+			Name:      profile.Name,
+			registers: append([]register(nil), profile.registers...),
+		},
 	}
-	cc1101.Reset()
-	cc1101.SelfTest()
-	cc1101.Init()
-
-	if packetCh != nil {
-		cc1101.SetIdle()
-		cc1101.SetRx()
-
-		log.Print("Waiting for packets...")
-		gdo0.Watch(embd.EdgeRising, func(pin embd.DigitalPin) {
-			log.Println("Packet arrived")
-			defer cc1101.SetRx()
-			defer cc1101.SetIdle()
-
-			recv, err := cc1101.Receive()
-			if err != nil {
-				log.Println("Failed to receive: ", err)
-			} else {
-				packetCh <- recv
-			}
-		})
+	if err := cc1101.start(); err != nil {
+		return nil, err
 	}
+	return cc1101, nil
+}
 
-	return cc1101
+// start resets the chip, checks that it is a CC1101 and configures it.
+func (c *CC1101) start() error {
+	if err := c.Reset(); err != nil {
+		return fmt.Errorf("failed to reset CC1101: %w", err)
+	}
+	if err := c.SelfTest(); err != nil {
+		return fmt.Errorf("CC1101 self test failed: %w", err)
+	}
+	if err := c.Init(); err != nil {
+		return fmt.Errorf("failed to configure CC1101: %w", err)
+	}
+	log.Printf("Configured %q: %v", c.profile.Name, c.profile.Settings(c.crystal()))
+	return nil
 }
 
 func (c *CC1101) Close() {
@@ -198,8 +289,6 @@ func (c *CC1101) Close() {
 	c.bus.Close()
 	c.gdo0.Close()
 	c.gdo2.Close()
-	embd.CloseGPIO()
-	embd.CloseSPI()
 }
 
 func (cc1101 *CC1101) Strobe(address byte) (byte, error) {
@@ -220,14 +309,12 @@ func (cc1101 *CC1101) ReadSingleByte(address byte) (byte, error) {
 	return data[1], nil
 }
 
+// ReadBurst reads num consecutive registers starting at address, or num bytes
+// from the RX FIFO. The address is only sent once and the chip increments it
+// for each byte clocked out.
 func (c *CC1101) ReadBurst(address byte, num byte) ([]byte, error) {
-	var buf []byte
-
-	for i := byte(0); i < num+1; i++ {
-		addr := (address + i*8) | READ_BURST
-		buf = append(buf, addr)
-	}
-
+	buf := make([]byte, int(num)+1)
+	buf[0] = address | READ_BURST
 	err := c.bus.TransferAndReceiveData(buf)
 	if err != nil {
 		return nil, err
@@ -240,6 +327,8 @@ func (cc1101 *CC1101) WriteSingleByte(address byte, in byte) error {
 	return cc1101.bus.TransferAndReceiveData(data)
 }
 
+// WriteBurst writes data to consecutive registers starting at address, or
+// into the TX FIFO or PATABLE.
 func (c *CC1101) WriteBurst(address byte, data []byte) error {
 	var buf []byte
 	buf = append(buf, address|WRITE_BURST)
@@ -256,54 +345,48 @@ func (cc1101 *CC1101) Reset() error {
 	return err
 }
 
+// register is a configuration register and the value it should hold.
+type register struct {
+	address byte
+	value   byte
+}
+
+// Init writes the register profile, including any changes made by the
+// setters, and then reads it back to check that the chip is actually
+// listening to us.
 func (c *CC1101) Init() error {
-	c.WriteSingleByte(FSCTRL1, config.FSCTRL1)
-	c.WriteSingleByte(FSCTRL0, config.FSCTRL0)
-
-	c.WriteSingleByte(FREQ2, config.FREQ2)
-	c.WriteSingleByte(FREQ1, config.FREQ1)
-	c.WriteSingleByte(FREQ0, config.FREQ0)
-
-	c.WriteSingleByte(MDMCFG4, config.MDMCFG4)
-	c.WriteSingleByte(MDMCFG3, config.MDMCFG3)
-	c.WriteSingleByte(MDMCFG2, config.MDMCFG2)
-	c.WriteSingleByte(MDMCFG1, config.MDMCFG1)
-	c.WriteSingleByte(MDMCFG0, config.MDMCFG0)
-
-	c.WriteSingleByte(CHANNR, config.CHANNR)
-	c.WriteSingleByte(DEVIATN, config.DEVIATN)
-	c.WriteSingleByte(FREND1, config.FREND1)
-	c.WriteSingleByte(FREND0, config.FREND0)
-	c.WriteSingleByte(MCSM0, config.MCSM0)
-	c.WriteSingleByte(FOCCFG, config.FOCCFG)
-	c.WriteSingleByte(BSCFG, config.BSCFG)
-
-	c.WriteSingleByte(AGCCTRL2, config.AGCCTRL2)
-	c.WriteSingleByte(AGCCTRL1, config.AGCCTRL1)
-	c.WriteSingleByte(AGCCTRL0, config.AGCCTRL0)
-
-	c.WriteSingleByte(FSCAL3, config.FSCAL3)
-	c.WriteSingleByte(FSCAL2, config.FSCAL2)
-	c.WriteSingleByte(FSCAL1, config.FSCAL1)
-	c.WriteSingleByte(FSCAL0, config.FSCAL0)
-
-	c.WriteSingleByte(FSTEST, config.FSTEST)
-	c.WriteSingleByte(TEST2, config.TEST2)
-	c.WriteSingleByte(TEST1, config.TEST1)
-	c.WriteSingleByte(TEST0, config.TEST0)
-
-	c.WriteSingleByte(IOCFG2, config.IOCFG2)
-	c.WriteSingleByte(IOCFG1, config.IOCFG1)
-	c.WriteSingleByte(IOCFG0, config.IOCFG0)
-
-	// Two status bytes appended to payload: RSSI LQI and CRC OK.
-	c.WriteSingleByte(PKTCTRL1, config.PKTCTRL1)
-	// No address check, data whitening off, CRC enable, variable length packets.
-	c.WriteSingleByte(PKTCTRL0, config.PKTCTRL0)
-
-	c.WriteSingleByte(ADDR, config.ADDR)
-	// Max packet length 61 bytes.
-	c.WriteSingleByte(PKTLEN, config.PKTLEN)
+	if c.profile == nil {
+		c.profile = DefaultProfile()
+	}
+	for _, r := range c.profile.registers {
+		if err := c.WriteSingleByte(r.address, r.value); err != nil {
+			return fmt.Errorf("failed to write %s: %w", registerName(r.address), err)
+		}
+	}
+	if c.paTable != nil {
+		if err := c.WriteBurst(PATABLE, c.paTable); err != nil {
+			return fmt.Errorf("failed to write PATABLE: %w", err)
+		}
+	}
+	return c.verifyRegisters(c.profile.registers)
+}
+
+// verifyRegisters reads back every configuration register in a single burst
+// and compares them against the expected values.
+func (c *CC1101) verifyRegisters(registers []register) error {
+	values, err := c.ReadBurst(IOCFG2, TEST0-IOCFG2+1)
+	if err != nil {
+		return fmt.Errorf("failed to read back registers: %w", err)
+	}
+	var mismatches []string
+	for _, r := range registers {
+		if got := values[r.address-IOCFG2]; got != r.value {
+			mismatches = append(mismatches, fmt.Sprintf("%s: wrote 0x%02x read 0x%02x", registerName(r.address), r.value, got))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("register verification failed: %s", strings.Join(mismatches, ", "))
+	}
 	return nil
 }
 
@@ -325,12 +408,76 @@ func (cc1101 *CC1101) SelfTest() error {
 	return nil
 }
 
+// Status is a snapshot of the CC1101 status registers.
+type Status struct {
+	// Main Radio Control State Machine state, one of the MARCSTATE_ constants.
+	State byte
+	// Number of bytes in the RX FIFO.
+	RXBytes int
+	// Whether the RX FIFO has overflowed.
+	RXOverflow bool
+	// Number of bytes in the TX FIFO.
+	TXBytes int
+	// Whether the TX FIFO has underflowed.
+	TXUnderflow bool
+	// Current received signal strength in dBm.
+	RSSI int
+	// Link quality indicator of the last packet. Lower is better.
+	LQI byte
+	// Whether the last packet passed the CRC check.
+	CRCOK bool
+	// Estimated frequency offset of the carrier in Hz.
+	FrequencyOffsetHz float64
+	// Whether the RSSI is above the carrier sense threshold.
+	CarrierSense bool
+	// Whether the channel is clear according to the CCA mode in MCSM1.
+	ClearChannel bool
+}
+
+func (s Status) String() string {
+	return fmt.Sprintf("state: 0x%02x rx: %d overflow: %v tx: %d underflow: %v rssi: %ddBm lqi: %d crc: %v offset: %.0fHz cs: %v cca: %v",
+		s.State, s.RXBytes, s.RXOverflow, s.TXBytes, s.TXUnderflow, s.RSSI, s.LQI, s.CRCOK, s.FrequencyOffsetHz, s.CarrierSense, s.ClearChannel)
+}
+
+// Status reads the status registers.
+func (c *CC1101) Status() (Status, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Status registers can't be burst read so each is read individually.
+	values := make(map[byte]byte)
+	for _, address := range []byte{MARCSTATE, RXBYTES, TXBYTES, RSSI_STATUS, LQI_STATUS, FREQEST, PKTSTATUS} {
+		v, err := c.ReadSingleByte(address)
+		if err != nil {
+			return Status{}, fmt.Errorf("failed to read status register 0x%02x: %w", address, err)
+		}
+		values[address] = v
+	}
+	return Status{
+		State:             values[MARCSTATE] & 0x1f,
+		RXBytes:           int(values[RXBYTES] & BYTES_IN_RXFIFO),
+		RXOverflow:        values[RXBYTES]&OVERFLOW != 0,
+		TXBytes:           int(values[TXBYTES] & BYTES_IN_RXFIFO),
+		TXUnderflow:       values[TXBYTES]&OVERFLOW != 0,
+		RSSI:              convertRSSI(int(values[RSSI_STATUS])),
+		LQI:               values[LQI_STATUS] &^ CRC_OK,
+		CRCOK:             values[LQI_STATUS]&CRC_OK != 0,
+		FrequencyOffsetHz: float64(int8(values[FREQEST])) * c.crystal() / (1 << 14),
+		CarrierSense:      values[PKTSTATUS]&PKTSTATUS_CS != 0,
+		ClearChannel:      values[PKTSTATUS]&PKTSTATUS_CCA != 0,
+	}, nil
+}
+
 func (cc1101 *CC1101) SetSyncWord(word uint16) error {
 	err := cc1101.WriteSingleByte(SYNC1, byte(word>>8))
 	if err != nil {
 		return err
 	}
-	return cc1101.WriteSingleByte(SYNC0, byte(word&0xff))
+	if err := cc1101.WriteSingleByte(SYNC0, byte(word&0xff)); err != nil {
+		return err
+	}
+	cc1101.keep(SYNC1, byte(word>>8), byte(word&0xff))
+	return nil
 }
 
 func (c *CC1101) SetState(state byte) error {
@@ -357,10 +504,14 @@ func (c *CC1101) FlushRx() {
 	c.Strobe(SFRX)
 }
 
-func (c *CC1101) Receive() ([]byte, error) {
+// Receive reads a packet out of the RX FIFO. It returns nil if the FIFO is empty.
+func (c *CC1101) Receive() (*Frame, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	return c.receive()
+}
 
+func (c *CC1101) receive() (*Frame, error) {
 	rxbytes, err := c.ReadSingleByte(RXBYTES)
 	if err != nil {
 		return nil, err
@@ -372,54 +523,214 @@ func (c *CC1101) Receive() ([]byte, error) {
 		return nil, fmt.Errorf("FIFO Overflow")
 	}
 
-	if rxbytes&BYTES_IN_RXFIFO > 0 {
-		numBytes, err := c.ReadSingleByte(RXFIFO)
+	if rxbytes&BYTES_IN_RXFIFO == 0 {
+		return nil, nil
+	}
+
+	numBytes, err := c.ReadSingleByte(RXFIFO)
+	if err != nil {
+		return nil, err
+	}
+	frame := &Frame{
+		Payload: []byte{},
+		Time:    time.Now(),
+	}
+	if numBytes > 0 {
+		frame.Payload, err = c.ReadBurst(RXFIFO, numBytes)
 		if err != nil {
 			return nil, err
 		}
-		var recv []byte
-		if numBytes > 0 {
-			recv, err = c.ReadBurst(RXFIFO, numBytes)
+	}
+	// Two status bytes appended to payload: RSSI and LQI with CRC OK.
+	status, err := c.ReadBurst(RXFIFO, 2)
+	if err != nil {
+		return nil, err
+	}
+	frame.RSSI = convertRSSI(int(status[RSSI]))
+	frame.LQI = status[LQI] &^ CRC_OK
+	frame.CRCOK = status[LQI]&CRC_OK != 0
+	return frame, nil
+}
+
+// Listen puts the radio into receive mode and returns a channel of received
+// frames. The receive loop re-arms RX after every packet, recovers from FIFO
+// overflows and stuck states, and drops frames rather than blocking if the
+// consumer falls behind. The channel is closed once ctx is cancelled.
+func (c *CC1101) Listen(ctx context.Context) (<-chan Frame, error) {
+	interrupts := make(chan struct{}, 1)
+	if err := c.gdo0.Watch(hal.EdgeRising, func() {
+		select {
+		case interrupts <- struct{}{}:
+		default:
+			// A receive is already pending.
+		}
+	}); err != nil {
+		return nil, fmt.Errorf("failed to watch GDO0: %w", err)
+	}
+
+	c.lock.Lock()
+	c.listening = true
+	err := c.wake()
+	if err == nil {
+		err = c.rearm()
+	}
+	c.lock.Unlock()
+	if err != nil {
+		c.stopListening()
+		return nil, fmt.Errorf("failed to enter RX: %w", err)
+	}
+
+	frames := make(chan Frame, frameBufferSize)
+	go c.listen(ctx, interrupts, frames)
+	return frames, nil
+}
+
+func (c *CC1101) listen(ctx context.Context, interrupts <-chan struct{}, frames chan<- Frame) {
+	defer close(frames)
+	defer c.stopListening()
+
+	watchdog := time.NewTicker(rxWatchdogInterval)
+	defer watchdog.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-interrupts:
+			frame, err := c.receiveAndRearm()
 			if err != nil {
-				return nil, err
+				log.Printf("Failed to receive: %v", err)
+				continue
+			}
+			if frame == nil || len(frame.Payload) == 0 {
+				continue
+			}
+			select {
+			case frames <- *frame:
+			default:
+				log.Printf("Dropping frame as consumer is too slow: %v", frame.Payload)
+			}
+		case <-watchdog.C:
+			if err := c.checkRx(); err != nil {
+				log.Printf("Failed to check RX state: %v", err)
 			}
 		}
-		_, err = c.ReadBurst(RXFIFO, 2)
-		if err != nil {
-			return nil, err
-		}
+	}
+}
 
-		return recv, nil
-	} else {
-		return []byte{}, nil
+func (c *CC1101) receiveAndRearm() (*Frame, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// A packet wakes the radio from Wake-on-Radio into RX and then IDLE.
+	c.sleeping = false
+	// Always return to RX, even if the receive failed as the FIFO has been flushed.
+	defer c.rearm()
+	return c.receive()
+}
+
+// checkRx puts the radio back into RX if it has overflowed or ended up idle.
+func (c *CC1101) checkRx() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.wakeOnRadio {
+		// Reading MARCSTATE would wake the radio so just restart polling.
+		return c.enterWakeOnRadio()
+	}
+
+	state, err := c.ReadSingleByte(MARCSTATE)
+	if err != nil {
+		return err
+	}
+	switch state & 0x1f {
+	case MARCSTATE_RX, MARCSTATE_RX_END, MARCSTATE_RX_RST:
+		return nil
+	case MARCSTATE_RXFIFO_OVERFLOW:
+		log.Print("RX FIFO overflowed, flushing")
+		c.FlushRx()
+	default:
+		log.Printf("Radio stuck in state 0x%02x, returning to RX", state)
+		c.SetIdle()
+	}
+	return c.SetRx()
+}
+
+func (c *CC1101) stopListening() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.listening = false
+	if err := c.gdo0.StopWatching(); err != nil {
+		log.Printf("failed to stop watching GDO0: %v", err)
 	}
+	if err := c.wake(); err != nil {
+		log.Printf("failed to wake radio: %v", err)
+	}
+	c.SetIdle()
 }
 
+// rearm returns to RX, or to polling if Wake-on-Radio is enabled.
+func (c *CC1101) rearm() error {
+	if c.wakeOnRadio {
+		return c.enterWakeOnRadio()
+	}
+	return c.SetRx()
+}
+
+// Send transmits packet, waiting for a clear channel first if listen before
+// talk is enabled.
 func (c *CC1101) Send(packet []byte) error {
+	_, err := c.Transmit(packet)
+	return err
+}
+
+// Transmit sends packet and reports how long it waited for the channel.
+func (c *CC1101) Transmit(packet []byte) (TransmitResult, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	if len(packet) > 60 {
-		return fmt.Errorf("Packet too long: %d", len(packet))
+		return TransmitResult{}, fmt.Errorf("Packet too long: %d", len(packet))
+	}
+	if err := c.wake(); err != nil {
+		return TransmitResult{}, err
 	}
 	if err := c.WriteSingleByte(TXFIFO, byte(len(packet))); err != nil {
-		return err
+		return TransmitResult{}, err
 	}
 	if err := c.WriteBurst(TXFIFO, packet); err != nil {
-		return err
+		return TransmitResult{}, err
 	}
 
-	c.SetTx()
+	result := TransmitResult{Attempts: 1}
+	if c.listening {
+		if c.lbt == nil {
+			// Leave RX so that STX transmits regardless of the channel state.
+			c.SetIdle()
+		}
+		// Return to RX once the packet has been sent.
+		defer c.rearm()
+	}
 	defer c.Strobe(SFTX)
 	defer c.Strobe(SIDLE)
+	if c.lbt == nil {
+		c.SetTx()
+	} else {
+		var err error
+		if result, err = c.transmitWhenClear(); err != nil {
+			return result, err
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	doneCh := make(chan interface{})
-	if err := c.gdo2.Watch(embd.EdgeFalling, func(dp embd.DigitalPin) {
-		doneCh <- nil
+	doneCh := make(chan interface{}, 1)
+	if err := c.gdo2.Watch(hal.EdgeFalling, func() {
+		select {
+		case doneCh <- nil:
+		default:
+		}
 	}); err != nil {
-		return fmt.Errorf("Error waiting for packet send: %w", err)
+		return result, fmt.Errorf("Error waiting for packet send: %w", err)
 	}
 	// Watch should always be unregistered.
 	defer func() {
@@ -431,9 +742,9 @@ func (c *CC1101) Send(packet []byte) error {
 	for {
 		select {
 		case <-doneCh:
-			return nil
+			return result, nil
 		case <-ctx.Done():
-			return fmt.Errorf("Timed out waiting for packet send")
+			return result, fmt.Errorf("Timed out waiting for packet send")
 		}
 	}
 }
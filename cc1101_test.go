@@ -1,22 +1,25 @@
 package shinywaffle
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/hatstand/shinywaffle/hal"
 	"github.com/hatstand/shinywaffle/mocks"
-	"github.com/kidoman/embd"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
 
-func WithBusAndPins(t *testing.T, f func(bus *mocks.MockSPIBus, gdo0 *mocks.MockDigitalPin, gdo2 *mocks.MockDigitalPin, cc1101 *CC1101)) func() {
+func WithBusAndPins(t *testing.T, f func(bus *mocks.MockSPI, gdo0 *mocks.MockPin, gdo2 *mocks.MockPin, cc1101 *CC1101)) func() {
 	return func() {
 		mock := gomock.NewController(t)
 		defer mock.Finish()
-		bus := mocks.NewMockSPIBus(mock)
-		gdo0 := mocks.NewMockDigitalPin(mock)
-		gdo2 := mocks.NewMockDigitalPin(mock)
+		bus := mocks.NewMockSPI(mock)
+		gdo0 := mocks.NewMockPin(mock)
+		gdo2 := mocks.NewMockPin(mock)
 		cc1101 := &CC1101{
 			bus:  bus,
 			gdo0: gdo0,
@@ -26,14 +29,109 @@ func WithBusAndPins(t *testing.T, f func(bus *mocks.MockSPIBus, gdo0 *mocks.Mock
 	}
 }
 
-func WithBus(t *testing.T, f func(bus *mocks.MockSPIBus, cc1101 *CC1101)) func() {
-	return WithBusAndPins(t, func(bus *mocks.MockSPIBus, gdo0 *mocks.MockDigitalPin, gdo2 *mocks.MockDigitalPin, cc1101 *CC1101) {
+func WithBus(t *testing.T, f func(bus *mocks.MockSPI, cc1101 *CC1101)) func() {
+	return WithBusAndPins(t, func(bus *mocks.MockSPI, gdo0 *mocks.MockPin, gdo2 *mocks.MockPin, cc1101 *CC1101) {
 		f(bus, cc1101)
 	})
 }
 
+// burstRead matches a burst read of n registers starting at address.
+type burstRead struct {
+	address byte
+	n       int
+}
+
+func (m burstRead) Matches(x interface{}) bool {
+	buf, ok := x.([]byte)
+	return ok && len(buf) == m.n+1 && buf[0] == m.address|READ_BURST
+}
+
+func (m burstRead) String() string {
+	return fmt.Sprintf("is a burst read of %d bytes from 0x%02x", m.n, m.address)
+}
+
+func expectInit(bus *mocks.MockSPI, registers []register) *gomock.Call {
+	var calls []*gomock.Call
+	for _, r := range registers {
+		calls = append(calls, bus.EXPECT().TransferAndReceiveData([]byte{r.address | WRITE_SINGLE_BYTE, r.value}))
+	}
+	values := make([]byte, TEST0-IOCFG2+2)
+	for _, r := range registers {
+		values[r.address-IOCFG2+1] = r.value
+	}
+	calls = append(calls, bus.EXPECT().TransferAndReceiveData(burstRead{IOCFG2, TEST0 - IOCFG2 + 1}).SetArg(0, values))
+	gomock.InOrder(calls...)
+	return calls[len(calls)-1]
+}
+
+func TestInit(t *testing.T) {
+	Convey("Init", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		expectInit(bus, DefaultProfile().registers)
+		So(cc1101.Init(), ShouldBeNil)
+	}))
+
+	Convey("Register mismatch", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		readback := make([]byte, TEST0-IOCFG2+2)
+		for _, r := range DefaultProfile().registers {
+			readback[r.address-IOCFG2+1] = r.value
+		}
+		readback[FREQ1-IOCFG2+1] = 0xff
+		expectInit(bus, DefaultProfile().registers).SetArg(0, readback)
+
+		err := cc1101.Init()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "FREQ1: wrote 0x65 read 0xff")
+	}))
+
+	Convey("SPI failure", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		bus.EXPECT().TransferAndReceiveData(gomock.Any()).Return(errors.New("bus error"))
+		So(cc1101.Init(), ShouldNotBeNil)
+	}))
+}
+
+func TestNew(t *testing.T) {
+	Convey("New", t, func() {
+		mock := gomock.NewController(t)
+		defer mock.Finish()
+		bus := mocks.NewMockSPI(mock)
+		gdo0 := mocks.NewMockPin(mock)
+		gdo2 := mocks.NewMockPin(mock)
+
+		gomock.InOrder(
+			bus.EXPECT().TransferAndReceiveData([]byte{SRES, 0x00}),
+			bus.EXPECT().TransferAndReceiveData([]byte{VERSION | READ_SINGLE_BYTE, 0x00}).SetArg(0, []byte{0x00, 0x14}),
+			bus.EXPECT().TransferAndReceiveData([]byte{PARTNUM | READ_SINGLE_BYTE, 0x00}).SetArg(0, []byte{0x00, 0x00}),
+			expectInit(bus, DefaultProfile().registers),
+		)
+		cc1101, err := New(bus, gdo0, gdo2, DefaultProfile())
+		So(err, ShouldBeNil)
+
+		gomock.InOrder(
+			bus.EXPECT().TransferAndReceiveData([]byte{SRES, 0x00}),
+			bus.EXPECT().Close(),
+		)
+		gdo0.EXPECT().Close()
+		gdo2.EXPECT().Close()
+		cc1101.Close()
+	})
+
+	Convey("Not a CC1101", t, func() {
+		mock := gomock.NewController(t)
+		defer mock.Finish()
+		bus := mocks.NewMockSPI(mock)
+
+		gomock.InOrder(
+			bus.EXPECT().TransferAndReceiveData([]byte{SRES, 0x00}),
+			bus.EXPECT().TransferAndReceiveData([]byte{VERSION | READ_SINGLE_BYTE, 0x00}).SetArg(0, []byte{0x00, 0x00}),
+			bus.EXPECT().TransferAndReceiveData([]byte{PARTNUM | READ_SINGLE_BYTE, 0x00}).SetArg(0, []byte{0x00, 0x00}),
+		)
+		_, err := New(bus, mocks.NewMockPin(mock), mocks.NewMockPin(mock), DefaultProfile())
+		So(err, ShouldNotBeNil)
+	})
+}
+
 func TestSelfTest(t *testing.T) {
-	Convey("Init", t, WithBus(t, func(bus *mocks.MockSPIBus, cc1101 *CC1101) {
+	Convey("Init", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
 		bus.EXPECT().TransferAndReceiveData([]byte{VERSION | READ_SINGLE_BYTE, 0x00}).Return(nil).SetArg(0, []byte{0x00, 0x14})
 		bus.EXPECT().TransferAndReceiveData([]byte{PARTNUM | READ_SINGLE_BYTE, 0x00}).Return(nil).SetArg(0, []byte{0x00, 0x00})
 
@@ -42,7 +140,7 @@ func TestSelfTest(t *testing.T) {
 }
 
 func TestStrobe(t *testing.T) {
-	Convey("Strobe", t, WithBus(t, func(bus *mocks.MockSPIBus, cc1101 *CC1101) {
+	Convey("Strobe", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
 		bus.EXPECT().TransferAndReceiveData([]byte{0x42, 0x00}).Return(nil).SetArg(0, []byte{0x43, 0x00})
 
 		ret, err := cc1101.Strobe(0x42)
@@ -52,7 +150,7 @@ func TestStrobe(t *testing.T) {
 }
 
 func TestReset(t *testing.T) {
-	Convey("Reset", t, WithBus(t, func(bus *mocks.MockSPIBus, cc1101 *CC1101) {
+	Convey("Reset", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
 		bus.EXPECT().TransferAndReceiveData([]byte{SRES, 0x00}).Return(nil)
 
 		So(cc1101.Reset(), ShouldBeNil)
@@ -60,19 +158,19 @@ func TestReset(t *testing.T) {
 }
 
 func TestSetState(t *testing.T) {
-	Convey("RX", t, WithBus(t, func(bus *mocks.MockSPIBus, cc1101 *CC1101) {
+	Convey("RX", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
 		bus.EXPECT().TransferAndReceiveData([]byte{SRX, 0x00}).Return(nil)
 		So(cc1101.SetRx(), ShouldBeNil)
 	}))
-	Convey("TX", t, WithBus(t, func(bus *mocks.MockSPIBus, cc1101 *CC1101) {
+	Convey("TX", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
 		bus.EXPECT().TransferAndReceiveData([]byte{STX, 0x00}).Return(nil)
 		So(cc1101.SetTx(), ShouldBeNil)
 	}))
-	Convey("IDLE", t, WithBus(t, func(bus *mocks.MockSPIBus, cc1101 *CC1101) {
+	Convey("IDLE", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
 		bus.EXPECT().TransferAndReceiveData([]byte{SIDLE, 0x00}).Return(nil)
 		So(cc1101.SetIdle(), ShouldBeNil)
 	}))
-	Convey("Flush RX buffer", t, WithBus(t, func(bus *mocks.MockSPIBus, cc1101 *CC1101) {
+	Convey("Flush RX buffer", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
 		gomock.InOrder(
 			bus.EXPECT().TransferAndReceiveData([]byte{SIDLE, 0x00}).Return(nil),
 			bus.EXPECT().TransferAndReceiveData([]byte{SFRX, 0x00}).Return(nil),
@@ -82,7 +180,7 @@ func TestSetState(t *testing.T) {
 }
 
 func TestSendPacket(t *testing.T) {
-	Convey("Send", t, WithBusAndPins(t, func(bus *mocks.MockSPIBus, gdo0 *mocks.MockDigitalPin, gdo2 *mocks.MockDigitalPin, cc1101 *CC1101) {
+	Convey("Send", t, WithBusAndPins(t, func(bus *mocks.MockSPI, gdo0 *mocks.MockPin, gdo2 *mocks.MockPin, cc1101 *CC1101) {
 		packet := []byte{0x42, 0x43, 0x44}
 		gomock.InOrder(
 			// Write the packet length.
@@ -92,9 +190,9 @@ func TestSendPacket(t *testing.T) {
 			// Switch to send mode.
 			bus.EXPECT().TransferAndReceiveData([]byte{STX, 0x00}),
 			// Wait for packet data to transmit (falling edge on gdo2).
-			gdo2.EXPECT().Watch(embd.EdgeFalling, gomock.Any()).Do(
-				func(edge embd.Edge, cb func(dp embd.DigitalPin)) {
-					go cb(gdo2)
+			gdo2.EXPECT().Watch(hal.EdgeFalling, gomock.Any()).Do(
+				func(edge hal.Edge, cb func()) {
+					go cb()
 				},
 			),
 			gdo2.EXPECT().StopWatching(),
@@ -108,7 +206,7 @@ func TestSendPacket(t *testing.T) {
 }
 
 func TestReceivePacket(t *testing.T) {
-	Convey("Send", t, WithBusAndPins(t, func(bus *mocks.MockSPIBus, gdo0 *mocks.MockDigitalPin, gdo2 *mocks.MockDigitalPin, cc1101 *CC1101) {
+	Convey("Send", t, WithBusAndPins(t, func(bus *mocks.MockSPI, gdo0 *mocks.MockPin, gdo2 *mocks.MockPin, cc1101 *CC1101) {
 		addr := byte(RXFIFO | READ_BURST)
 		packet := []byte{0x42, 0x43, 0x44}
 		response := []byte{0x00}
@@ -119,32 +217,119 @@ func TestReceivePacket(t *testing.T) {
 			// Read first RXFIFO byte for packet length.
 			bus.EXPECT().TransferAndReceiveData([]byte{RXFIFO | READ_SINGLE_BYTE, 0x00}).Return(nil).SetArg(0, []byte{0x00, 0x03}),
 			// Read packet data out of RXFIFO.
-			bus.EXPECT().TransferAndReceiveData([]byte{
-				addr,
-				addr + 1*8 | READ_BURST,
-				addr + 2*8 | READ_BURST,
-				addr + 3*8 | READ_BURST,
-			}).SetArg(0, response),
+			bus.EXPECT().TransferAndReceiveData([]byte{addr, 0x00, 0x00, 0x00}).SetArg(0, response),
 			// Read packet status bytes.
-			bus.EXPECT().TransferAndReceiveData([]byte{
-				addr,
-				addr + 1*8 | READ_BURST,
-				addr + 2*8 | READ_BURST,
-			}),
+			bus.EXPECT().TransferAndReceiveData([]byte{addr, 0x00, 0x00}).SetArg(0, []byte{0x00, 0xd0, CRC_OK | 0x2a}),
 			// Flush RX buffer.
 			bus.EXPECT().TransferAndReceiveData([]byte{SIDLE, 0x00}),
 			bus.EXPECT().TransferAndReceiveData([]byte{SFRX, 0x00}),
 		)
 		recv, err := cc1101.Receive()
 		So(err, ShouldBeNil)
-		So(recv, ShouldResemble, packet)
+		So(recv.Payload, ShouldResemble, packet)
+		So(recv.RSSI, ShouldEqual, -98)
+		So(recv.LQI, ShouldEqual, 0x2a)
+		So(recv.CRCOK, ShouldBeTrue)
+		So(recv.Time, ShouldNotBeZeroValue)
+	}))
+}
+
+func TestBurst(t *testing.T) {
+	Convey("ReadBurst sends the address once", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		bus.EXPECT().TransferAndReceiveData([]byte{FREQ2 | READ_BURST, 0x00, 0x00, 0x00}).SetArg(0, []byte{0x0f, 0x21, 0x65, 0x6a})
+		values, err := cc1101.ReadBurst(FREQ2, 3)
+		So(err, ShouldBeNil)
+		So(values, ShouldResemble, []byte{0x21, 0x65, 0x6a})
+	}))
+
+	Convey("WriteBurst", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		bus.EXPECT().TransferAndReceiveData([]byte{PATABLE | WRITE_BURST, 0x00, 0x50})
+		So(cc1101.WriteBurst(PATABLE, []byte{0x00, 0x50}), ShouldBeNil)
+	}))
+}
+
+func TestStatus(t *testing.T) {
+	Convey("Status", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		gomock.InOrder(
+			bus.EXPECT().TransferAndReceiveData([]byte{MARCSTATE, 0x00}).SetArg(0, []byte{0x00, MARCSTATE_RXFIFO_OVERFLOW}),
+			bus.EXPECT().TransferAndReceiveData([]byte{RXBYTES, 0x00}).SetArg(0, []byte{0x00, OVERFLOW | 0x40}),
+			bus.EXPECT().TransferAndReceiveData([]byte{TXBYTES, 0x00}).SetArg(0, []byte{0x00, 0x03}),
+			bus.EXPECT().TransferAndReceiveData([]byte{RSSI_STATUS, 0x00}).SetArg(0, []byte{0x00, 0xd0}),
+			bus.EXPECT().TransferAndReceiveData([]byte{LQI_STATUS, 0x00}).SetArg(0, []byte{0x00, CRC_OK | 0x2a}),
+			// -2 * 26MHz / 2^14
+			bus.EXPECT().TransferAndReceiveData([]byte{FREQEST, 0x00}).SetArg(0, []byte{0x00, 0xfe}),
+			bus.EXPECT().TransferAndReceiveData([]byte{PKTSTATUS, 0x00}).SetArg(0, []byte{0x00, PKTSTATUS_CS}),
+		)
+		status, err := cc1101.Status()
+		So(err, ShouldBeNil)
+		So(status, ShouldResemble, Status{
+			State:             MARCSTATE_RXFIFO_OVERFLOW,
+			RXBytes:           0x40,
+			RXOverflow:        true,
+			TXBytes:           3,
+			RSSI:              -98,
+			LQI:               0x2a,
+			CRCOK:             true,
+			FrequencyOffsetHz: -3173.828125,
+			CarrierSense:      true,
+		})
 	}))
 }
 
 func TestSetSyncWord(t *testing.T) {
-	Convey("SetSyncWord", t, WithBus(t, func(bus *mocks.MockSPIBus, cc1101 *CC1101) {
+	Convey("SetSyncWord", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
 		bus.EXPECT().TransferAndReceiveData([]byte{SYNC1 | WRITE_SINGLE_BYTE, 0x42})
 		bus.EXPECT().TransferAndReceiveData([]byte{SYNC0 | WRITE_SINGLE_BYTE, 0x43})
 		So(cc1101.SetSyncWord(0x4243), ShouldBeNil)
 	}))
 }
+
+func TestListen(t *testing.T) {
+	Convey("Listen", t, WithBusAndPins(t, func(bus *mocks.MockSPI, gdo0 *mocks.MockPin, gdo2 *mocks.MockPin, cc1101 *CC1101) {
+		addr := byte(RXFIFO | READ_BURST)
+		packet := []byte{0x42, 0x43, 0x44}
+		response := []byte{0x00}
+		response = append(response, packet...)
+		interrupt := make(chan func(), 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		gomock.InOrder(
+			// Watch for packets arriving.
+			gdo0.EXPECT().Watch(hal.EdgeRising, gomock.Any()).Do(
+				func(edge hal.Edge, cb func()) {
+					interrupt <- cb
+				},
+			),
+			// Enter RX.
+			bus.EXPECT().TransferAndReceiveData([]byte{SRX, 0x00}),
+			// Read the packet.
+			bus.EXPECT().TransferAndReceiveData([]byte{RXBYTES | READ_SINGLE_BYTE, 0x00}).Return(nil).SetArg(0, []byte{0x00, 0x03}),
+			bus.EXPECT().TransferAndReceiveData([]byte{RXFIFO | READ_SINGLE_BYTE, 0x00}).Return(nil).SetArg(0, []byte{0x00, 0x03}),
+			bus.EXPECT().TransferAndReceiveData([]byte{addr, 0x00, 0x00, 0x00}).SetArg(0, response),
+			bus.EXPECT().TransferAndReceiveData([]byte{addr, 0x00, 0x00}).SetArg(0, []byte{0x00, 0x10, 0x05}),
+			bus.EXPECT().TransferAndReceiveData([]byte{SIDLE, 0x00}),
+			bus.EXPECT().TransferAndReceiveData([]byte{SFRX, 0x00}),
+			// Re-enter RX.
+			bus.EXPECT().TransferAndReceiveData([]byte{SRX, 0x00}),
+			// Stop listening on cancellation.
+			gdo0.EXPECT().StopWatching(),
+			bus.EXPECT().TransferAndReceiveData([]byte{SIDLE, 0x00}),
+		)
+
+		frames, err := cc1101.Listen(ctx)
+		So(err, ShouldBeNil)
+
+		cb := <-interrupt
+		cb()
+		f := <-frames
+		So(f.Payload, ShouldResemble, packet)
+		So(f.RSSI, ShouldEqual, -66)
+		So(f.LQI, ShouldEqual, 5)
+		So(f.CRCOK, ShouldBeFalse)
+
+		cancel()
+		_, ok := <-frames
+		So(ok, ShouldBeFalse)
+	}))
+}
@@ -1,64 +1,52 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"time"
 
 	"github.com/hatstand/shinywaffle"
+	"github.com/hatstand/shinywaffle/radiator"
 )
 
-// Radiator Addresses.
-const (
-	Kitchen = 0x2bdc
-	Study   = 0x2e04
-	Living  = 0x2bdb
-	Bedroom = 0x2b76
-)
-
-// Radiator Modes.
-const (
-	Day   = 0x05
-	Night = 0x03
-	Cold  = 0x09
-	Off   = 0x60
-	Auto  = 0x11
-)
-
-func convertTemp(temp float32) byte {
-	// Temperatures are represented as bytes in half-degree intervals, i.e. 0.5C -> 1, 1C -> 2
-	return byte(int(temp*10) * 2 / 10)
-}
-
-func NewPacket(address uint16, mode byte, dayTemp float32, nightTemp float32, coldTemp float32) []byte {
-	packet := []byte{0x57, 0x16, 0x0a}
-	packet = append(packet, byte((address&0xff00)>>8))
-	packet = append(packet, byte(address&0x00ff))
-	packet = append(packet, mode)
-	packet = append(packet, convertTemp(dayTemp))
-	packet = append(packet, convertTemp(nightTemp))
-	packet = append(packet, convertTemp(coldTemp))
-	return packet
-}
+var address = flag.String("address", "2bdc", "Address in hexadecimal of the radiator to turn off")
 
 func main() {
 	flag.Parse()
 
-	packetCh := make(chan []byte, 10)
-	cc1101 := shinywaffle.NewCC1101(packetCh)
+	addr, err := radiator.ParseAddress(*address)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cc1101, err := shinywaffle.NewCC1101()
+	if err != nil {
+		log.Fatalf("Failed to initialise CC1101: %v", err)
+	}
 	defer cc1101.Close()
 	cc1101.SetSyncWord(0xd391)
 
+	frames, err := cc1101.Listen(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+
 	time.Sleep(5 * time.Second)
-	packet := NewPacket(Kitchen, Off, 25.0, 15.0, 10.0)
+	packet, err := radiator.NewSettingPacket(addr, radiator.Setting{
+		Mode:    radiator.Off,
+		Day:     25.0,
+		Night:   15.0,
+		Defrost: 10.0,
+	}).Marshal()
+	if err != nil {
+		log.Fatalf("Failed to encode packet: %v", err)
+	}
 	cc1101.Send(packet)
 	cc1101.Send(packet)
 	cc1101.Send(packet)
 
-	for {
-		select {
-		case p := <-packetCh:
-			log.Printf("Received packet: %v\n", p)
-		}
+	for f := range frames {
+		log.Printf("Received packet: %v RSSI: %ddBm LQI: %d\n", f.Payload, f.RSSI, f.LQI)
 	}
 }
@@ -0,0 +1,2 @@
+//go:generate protoc --go_out=. -I$GOPATH/src/github.com/hatstand/shinywaffle/config profile.proto
+package config
@@ -0,0 +1,100 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: profile.proto
+
+/*
+Package config is a generated protocol buffer package.
+
+It is generated from these files:
+
+	profile.proto
+
+It has these top-level messages:
+
+	Profile
+	Register
+*/
+package config
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// A set of values for the CC1101 configuration registers.
+type Profile struct {
+	Name     string      `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Register []*Register `protobuf:"bytes,2,rep,name=register" json:"register,omitempty"`
+}
+
+func (m *Profile) Reset()                    { *m = Profile{} }
+func (m *Profile) String() string            { return proto.CompactTextString(m) }
+func (*Profile) ProtoMessage()               {}
+func (*Profile) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *Profile) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Profile) GetRegister() []*Register {
+	if m != nil {
+		return m.Register
+	}
+	return nil
+}
+
+type Register struct {
+	// Register name as used in the CC1101 datasheet, e.g. "FREQ2".
+	Name  string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Value uint32 `protobuf:"varint,2,opt,name=value" json:"value,omitempty"`
+}
+
+func (m *Register) Reset()                    { *m = Register{} }
+func (m *Register) String() string            { return proto.CompactTextString(m) }
+func (*Register) ProtoMessage()               {}
+func (*Register) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *Register) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Register) GetValue() uint32 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*Profile)(nil), "config.Profile")
+	proto.RegisterType((*Register)(nil), "config.Register")
+}
+
+func init() { proto.RegisterFile("profile.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 128 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x2d, 0x28, 0xca, 0x4f,
+	0xcb, 0xcc, 0x49, 0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x4b, 0xce, 0xcf, 0x4b, 0xcb,
+	0x4c, 0x57, 0xf2, 0xe6, 0x62, 0x0f, 0x80, 0x48, 0x08, 0x09, 0x71, 0xb1, 0xe4, 0x25, 0xe6, 0xa6,
+	0x4a, 0x30, 0x2a, 0x30, 0x6a, 0x70, 0x06, 0x81, 0xd9, 0x42, 0x3a, 0x5c, 0x1c, 0x45, 0xa9, 0xe9,
+	0x99, 0xc5, 0x25, 0xa9, 0x45, 0x12, 0x4c, 0x0a, 0xcc, 0x1a, 0xdc, 0x46, 0x02, 0x7a, 0x10, 0x9d,
+	0x7a, 0x41, 0x50, 0xf1, 0x20, 0xb8, 0x0a, 0x25, 0x13, 0x2e, 0x0e, 0x98, 0x28, 0x56, 0xd3, 0x44,
+	0xb8, 0x58, 0xcb, 0x12, 0x73, 0x4a, 0x53, 0x25, 0x98, 0x14, 0x18, 0x35, 0x78, 0x83, 0x20, 0x9c,
+	0x24, 0x36, 0xb0, 0x8b, 0x8c, 0x01, 0x03, 0x00, 0xf4, 0xa5, 0x08, 0x92, 0xa2, 0x00, 0x00, 0x00,
+}
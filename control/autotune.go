@@ -0,0 +1,317 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var errAutotuneCancelled = errors.New("cancelled")
+
+// AutotuneOptions configures relay feedback tuning.
+type AutotuneOptions struct {
+	// Temperature to oscillate around.
+	Setpoint float64
+	// The radiators are switched off above Setpoint+Hysteresis and on below
+	// Setpoint-Hysteresis, so that sensor noise doesn't switch them.
+	Hysteresis float64
+	// Outputs of the PID controller equivalent to the radiators being off
+	// and on, used to calculate the ultimate gain.
+	OutputLow  float64
+	OutputHigh float64
+	// Oscillations to measure, after the first which is distorted by
+	// approaching the setpoint.
+	Cycles int
+	// Tuning fails if it takes longer than this, or if the temperature goes
+	// more than MaxOvershoot above the setpoint.
+	MaxDuration  time.Duration
+	MaxOvershoot float64
+}
+
+func DefaultAutotuneOptions() AutotuneOptions {
+	return AutotuneOptions{
+		Hysteresis:   0.25,
+		OutputLow:    0,
+		OutputHigh:   100,
+		Cycles:       3,
+		MaxDuration:  6 * time.Hour,
+		MaxOvershoot: 3,
+	}
+}
+
+// Gains are proposed PID gains, with the integral and derivative gains per
+// second.
+type Gains struct {
+	Proportional float64
+	Integral     float64
+	Derivative   float64
+}
+
+// AutotuneResult is the outcome of relay feedback tuning.
+type AutotuneResult struct {
+	// Gain and period at which the zone would oscillate under proportional
+	// control alone.
+	UltimateGain   float64
+	UltimatePeriod time.Duration
+	ZieglerNichols Gains
+	TyreusLuyben   Gains
+}
+
+type oscillation struct {
+	period    time.Duration
+	amplitude float64
+}
+
+// Autotuner tunes a zone's PID gains by relay feedback (Åström–Hägglund).
+// It switches the radiators fully on below the setpoint and fully off above
+// it, which makes the temperature oscillate at the zone's ultimate period.
+// The ultimate gain follows from the amplitude of the oscillation.
+type Autotuner struct {
+	options AutotuneOptions
+	started time.Time
+
+	state HeatingState
+	// When the radiators were last switched on, or zero before the first
+	// time after the start.
+	lastOn time.Time
+	// Temperature extremes since the radiators were last switched on.
+	high, low    float64
+	oscillations []oscillation
+
+	result *AutotuneResult
+	err    error
+}
+
+func NewAutotuner(options AutotuneOptions, now time.Time) *Autotuner {
+	return &Autotuner{
+		options: options,
+		started: now,
+		state:   HeatingState_UNKNOWN,
+	}
+}
+
+// Running reports whether tuning is still in progress.
+func (a *Autotuner) Running() bool {
+	return a.result == nil && a.err == nil
+}
+
+// Result returns the result once tuning is done, or why it failed.
+func (a *Autotuner) Result() (*AutotuneResult, error) {
+	return a.result, a.err
+}
+
+// Cycles returns the number of oscillations measured so far.
+func (a *Autotuner) Cycles() int {
+	return len(a.oscillations)
+}
+
+func (a *Autotuner) fail(err error) {
+	if a.Running() {
+		a.err = err
+	}
+}
+
+// Update returns the state to put the radiators in for the current
+// temperature. The radiators are off once tuning is no longer running.
+func (a *Autotuner) Update(temp float64, now time.Time) HeatingState {
+	if !a.Running() {
+		return HeatingState_OFF
+	}
+	if now.Sub(a.started) > a.options.MaxDuration {
+		a.fail(fmt.Errorf("no steady oscillation after %v", a.options.MaxDuration))
+		return HeatingState_OFF
+	}
+	if temp > a.options.Setpoint+a.options.MaxOvershoot {
+		a.fail(fmt.Errorf("temperature %.1f more than %.1f above setpoint %.1f", temp, a.options.MaxOvershoot, a.options.Setpoint))
+		return HeatingState_OFF
+	}
+
+	a.high = math.Max(a.high, temp)
+	a.low = math.Min(a.low, temp)
+	switch {
+	case a.state == HeatingState_UNKNOWN:
+		a.high, a.low = temp, temp
+		a.state = HeatingState_OFF
+		if temp < a.options.Setpoint {
+			a.state = HeatingState_ON
+		}
+	case a.state == HeatingState_ON && temp >= a.options.Setpoint+a.options.Hysteresis:
+		a.state = HeatingState_OFF
+	case a.state == HeatingState_OFF && temp <= a.options.Setpoint-a.options.Hysteresis:
+		a.state = HeatingState_ON
+		a.switchedOn(temp, now)
+	}
+	return a.state
+}
+
+// switchedOn records a complete oscillation since the radiators were last
+// switched on, and finishes once enough have been measured.
+func (a *Autotuner) switchedOn(temp float64, now time.Time) {
+	if !a.lastOn.IsZero() {
+		a.oscillations = append(a.oscillations, oscillation{
+			period:    now.Sub(a.lastOn),
+			amplitude: (a.high - a.low) / 2,
+		})
+	}
+	a.lastOn = now
+	a.high, a.low = temp, temp
+	if len(a.oscillations) > a.options.Cycles {
+		a.result = a.calculate(a.oscillations[1:])
+	}
+}
+
+func (a *Autotuner) calculate(oscillations []oscillation) *AutotuneResult {
+	var period time.Duration
+	var amplitude float64
+	for _, o := range oscillations {
+		period += o.period
+		amplitude += o.amplitude
+	}
+	period /= time.Duration(len(oscillations))
+	amplitude /= float64(len(oscillations))
+
+	// The describing function of a relay with hysteresis gives the
+	// ultimate gain from the relay's amplitude d and the oscillation's a.
+	d := (a.options.OutputHigh - a.options.OutputLow) / 2
+	ku := 4 * d / (math.Pi * amplitude)
+	if amplitude > a.options.Hysteresis {
+		ku = 4 * d / (math.Pi * math.Sqrt(amplitude*amplitude-a.options.Hysteresis*a.options.Hysteresis))
+	}
+	pu := period.Seconds()
+	return &AutotuneResult{
+		UltimateGain:   ku,
+		UltimatePeriod: period,
+		ZieglerNichols: gains(0.6*ku, pu/2, pu/8),
+		TyreusLuyben:   gains(ku/2.2, 2.2*pu, pu/6.3),
+	}
+}
+
+// gains returns the gains for a proportional gain and integral and
+// derivative times in seconds.
+func gains(kp, ti, td float64) Gains {
+	return Gains{
+		Proportional: kp,
+		Integral:     kp / ti,
+		Derivative:   kp * td,
+	}
+}
+
+// pidConfig returns gains as a zone's PID config with the given limits.
+func (g Gains) pidConfig(limits *PidConfig) *PidConfig {
+	return &PidConfig{
+		Proportional:           g.Proportional,
+		Integral:               g.Integral,
+		Derivative:             g.Derivative,
+		OutputMin:              limits.GetOutputMin(),
+		OutputMax:              limits.GetOutputMax(),
+		IntegralMin:            limits.GetIntegralMin(),
+		IntegralMax:            limits.GetIntegralMax(),
+		ConditionalIntegration: limits.GetConditionalIntegration(),
+	}
+}
+
+// autotuneState returns the state tuning puts room in, if it is being tuned.
+// Tuning fails if the room's reading is stale, and once it ends the PID
+// controller takes the room over afresh.
+func (c *Controller) autotuneState(room *Room, now time.Time) (HeatingState, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if a := room.autotune; a != nil && a.Running() {
+		if age := now.Sub(room.readAt); age > staleReadingAge {
+			// Switching on a stale reading would measure nothing.
+			a.fail(fmt.Errorf("last reading is %v old", age.Round(time.Minute)))
+		}
+		state := a.Update(room.LastTemp, now)
+		if a.Running() {
+			room.tuning = true
+			return state, true
+		}
+		if result, err := a.Result(); err != nil {
+			c.logger.Warnf("Tuning %s failed: %v", room.config.GetName(), err)
+		} else {
+			c.logger.Infof("Tuned %s: %+v", room.config.GetName(), result)
+		}
+	}
+	if room.tuning {
+		// The PID controller takes over afresh from however tuning left
+		// the radiators.
+		room.tuning = false
+		room.pwm.Reset()
+		room.lastUpdate = time.Time{}
+	}
+	return HeatingState_UNKNOWN, false
+}
+
+func (s *Controller) StartAutotune(ctx context.Context, req *StartAutotuneRequest) (*StartAutotuneReply, error) {
+	room := s.Config[req.GetName()]
+	if room == nil {
+		return nil, fmt.Errorf("No such zone: %s", req.GetName())
+	}
+	options := DefaultAutotuneOptions()
+	options.Setpoint = float64(room.config.GetTargetTemperature())
+	if req.GetSetpoint() != 0 {
+		options.Setpoint = float64(req.GetSetpoint())
+	}
+	if req.GetMaxHours() > 0 {
+		options.MaxDuration = time.Duration(req.GetMaxHours()) * time.Hour
+	}
+	if options.Setpoint <= 0 {
+		return nil, fmt.Errorf("No setpoint to tune %s around", req.GetName())
+	}
+	options.OutputLow, options.OutputHigh = room.Pid.OutputLimits()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if room.autotune != nil && room.autotune.Running() {
+		return nil, fmt.Errorf("Already tuning %s", req.GetName())
+	}
+	room.autotune = NewAutotuner(options, s.now())
+	s.logger.Infof("Tuning %s around %.1f", req.GetName(), options.Setpoint)
+	return &StartAutotuneReply{}, nil
+}
+
+func (s *Controller) GetAutotune(ctx context.Context, req *GetAutotuneRequest) (*GetAutotuneReply, error) {
+	room := s.Config[req.GetName()]
+	if room == nil {
+		return nil, fmt.Errorf("No such zone: %s", req.GetName())
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	a := room.autotune
+	if a == nil {
+		return &GetAutotuneReply{State: AutotuneState_AUTOTUNE_NONE}, nil
+	}
+	reply := &GetAutotuneReply{
+		State:     AutotuneState_AUTOTUNE_RUNNING,
+		StartTime: a.started.Unix(),
+		Setpoint:  float32(a.options.Setpoint),
+		Cycles:    int32(a.Cycles()),
+	}
+	result, err := a.Result()
+	if err != nil {
+		reply.State = AutotuneState_AUTOTUNE_FAILED
+		reply.Error = err.Error()
+	} else if result != nil {
+		reply.State = AutotuneState_AUTOTUNE_DONE
+		reply.UltimateGain = result.UltimateGain
+		reply.UltimatePeriodSeconds = result.UltimatePeriod.Seconds()
+		reply.ZieglerNichols = result.ZieglerNichols.pidConfig(room.config.GetPid())
+		reply.TyreusLuyben = result.TyreusLuyben.pidConfig(room.config.GetPid())
+	}
+	return reply, nil
+}
+
+func (s *Controller) CancelAutotune(ctx context.Context, req *CancelAutotuneRequest) (*CancelAutotuneReply, error) {
+	room := s.Config[req.GetName()]
+	if room == nil {
+		return nil, fmt.Errorf("No such zone: %s", req.GetName())
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if room.autotune != nil {
+		room.autotune.fail(errAutotuneCancelled)
+	}
+	return &CancelAutotuneReply{}, nil
+}
@@ -0,0 +1,211 @@
+package control
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// laggedRoom is a room heated by a radiator that takes time to warm up and
+// cool down, which is what makes relay feedback oscillate.
+type laggedRoom struct {
+	radiator, room, outside float64
+}
+
+func (r *laggedRoom) step(state HeatingState) {
+	if state == HeatingState_ON {
+		r.radiator++
+	}
+	r.radiator -= (r.radiator - r.room) * 0.15
+	r.room += (r.radiator-r.room)*0.05 - (r.room-r.outside)*0.003
+}
+
+// runAutotune updates a every minute until it stops running or the limit.
+func runAutotune(a *Autotuner, r *laggedRoom, start time.Time, limit time.Duration) (time.Duration, int) {
+	switches := 0
+	last := HeatingState_UNKNOWN
+	for t := time.Duration(0); t < limit; t += time.Minute {
+		state := a.Update(r.room, start.Add(t))
+		if !a.Running() {
+			return t, switches
+		}
+		if state != last {
+			switches++
+			last = state
+		}
+		r.step(state)
+	}
+	return limit, switches
+}
+
+func TestAutotuner(t *testing.T) {
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	options := DefaultAutotuneOptions()
+	options.Setpoint = 20
+
+	Convey("Proposes gains from the oscillation", t, func() {
+		a := NewAutotuner(options, start)
+		took, switches := runAutotune(a, &laggedRoom{radiator: 18, room: 18, outside: 5}, start, 24*time.Hour)
+		result, err := a.Result()
+		So(err, ShouldBeNil)
+		So(result, ShouldNotBeNil)
+		So(took, ShouldBeLessThan, options.MaxDuration)
+		// On to start with, then off and on again to start the first
+		// oscillation and to end each one, apart from the switch on which
+		// finishes tuning.
+		So(switches, ShouldEqual, 1+2*(options.Cycles+2)-1)
+		So(a.Cycles(), ShouldEqual, options.Cycles+1)
+
+		So(result.UltimateGain, ShouldBeGreaterThan, 0)
+		So(result.UltimatePeriod, ShouldBeBetween, 10*time.Minute, 2*time.Hour)
+		pu := result.UltimatePeriod.Seconds()
+		zn := result.ZieglerNichols
+		So(zn.Proportional, ShouldAlmostEqual, 0.6*result.UltimateGain)
+		So(zn.Integral, ShouldAlmostEqual, zn.Proportional/(pu/2))
+		So(zn.Derivative, ShouldAlmostEqual, zn.Proportional*pu/8)
+		// Tyreus-Luyben is more conservative.
+		So(result.TyreusLuyben.Proportional, ShouldBeLessThan, zn.Proportional)
+		So(result.TyreusLuyben.Integral, ShouldBeLessThan, zn.Integral)
+
+		// The radiators are left off.
+		So(a.Update(15, start.Add(took+time.Minute)), ShouldEqual, HeatingState_OFF)
+	})
+
+	Convey("Gives up if the temperature doesn't oscillate", t, func() {
+		a := NewAutotuner(options, start)
+		// Too cold outside to ever reach the setpoint.
+		took, _ := runAutotune(a, &laggedRoom{radiator: -20, room: -20, outside: -40}, start, 24*time.Hour)
+		So(took, ShouldBeGreaterThan, options.MaxDuration)
+		_, err := a.Result()
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Gives up if the room overheats", t, func() {
+		a := NewAutotuner(options, start)
+		So(a.Update(19, start), ShouldEqual, HeatingState_ON)
+		So(a.Update(23.5, start.Add(time.Minute)), ShouldEqual, HeatingState_OFF)
+		So(a.Running(), ShouldBeFalse)
+		_, err := a.Result()
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestAutotuneService(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Tunes a zone through the API", t, func() {
+		radiators := &recordingController{}
+		c := newTestController(radiators)
+		room := &Room{
+			Pid:    newZonePID(nil),
+			pwm:    NewTimeProportioner(0, 0, 0),
+			config: &Zone{Name: "Study", TargetTemperature: 20},
+		}
+		c.Config["Study"] = room
+
+		reply, err := c.GetAutotune(ctx, &GetAutotuneRequest{Name: "Study"})
+		So(err, ShouldBeNil)
+		So(reply.State, ShouldEqual, AutotuneState_AUTOTUNE_NONE)
+
+		_, err = c.StartAutotune(ctx, &StartAutotuneRequest{Name: "Kitchen"})
+		So(err, ShouldNotBeNil)
+		_, err = c.StartAutotune(ctx, &StartAutotuneRequest{Name: "Study"})
+		So(err, ShouldBeNil)
+		_, err = c.StartAutotune(ctx, &StartAutotuneRequest{Name: "Study"})
+		So(err, ShouldNotBeNil)
+
+		reply, err = c.GetAutotune(ctx, &GetAutotuneRequest{Name: "Study"})
+		So(err, ShouldBeNil)
+		So(reply.State, ShouldEqual, AutotuneState_AUTOTUNE_RUNNING)
+		So(reply.Setpoint, ShouldEqual, 20)
+
+		// Tuning switches the room instead of the PID controller.
+		room.LastTemp = 15
+		room.readAt = time.Now()
+		room.lastUpdate = room.readAt
+		room.pwm.Update(100, room.readAt)
+		state, tuning := c.autotuneState(room, room.readAt)
+		So(tuning, ShouldBeTrue)
+		So(state, ShouldEqual, HeatingState_ON)
+
+		// Simulate tuning to completion.
+		r := &laggedRoom{radiator: 18, room: 18, outside: 5}
+		now := room.autotune.started
+		for i := 0; i < 24*60 && room.autotune.Running(); i++ {
+			room.LastTemp = r.room
+			room.readAt = now
+			state, _ := c.autotuneState(room, now)
+			r.step(state)
+			now = now.Add(time.Minute)
+		}
+		reply, err = c.GetAutotune(ctx, &GetAutotuneRequest{Name: "Study"})
+		So(err, ShouldBeNil)
+		So(reply.State, ShouldEqual, AutotuneState_AUTOTUNE_DONE)
+		So(reply.UltimateGain, ShouldBeGreaterThan, 0)
+		So(reply.ZieglerNichols.GetProportional(), ShouldBeGreaterThan, 0)
+		So(reply.TyreusLuyben.GetIntegral(), ShouldBeGreaterThan, 0)
+
+		// Back to the PID controller, which starts afresh.
+		So(room.lastUpdate.IsZero(), ShouldBeTrue)
+		So(room.pwm.State(), ShouldEqual, HeatingState_OFF)
+		So(room.pwm.Update(50, now), ShouldEqual, HeatingState_ON)
+		_, tuning = c.autotuneState(room, now)
+		So(tuning, ShouldBeFalse)
+	})
+
+	Convey("Gives up on stale readings", t, func() {
+		c := newTestController(&recordingController{})
+		room := &Room{
+			Pid:      newZonePID(nil),
+			pwm:      NewTimeProportioner(0, 0, 0),
+			config:   &Zone{Name: "Study", TargetTemperature: 20},
+			LastTemp: 15,
+		}
+		c.Config["Study"] = room
+		_, err := c.StartAutotune(ctx, &StartAutotuneRequest{Name: "Study"})
+		So(err, ShouldBeNil)
+
+		now := room.autotune.started
+		room.readAt = now
+		state, tuning := c.autotuneState(room, now)
+		So(tuning, ShouldBeTrue)
+		So(state, ShouldEqual, HeatingState_ON)
+
+		now = now.Add(staleReadingAge + time.Minute)
+		_, tuning = c.autotuneState(room, now)
+		So(tuning, ShouldBeFalse)
+		reply, err := c.GetAutotune(ctx, &GetAutotuneRequest{Name: "Study"})
+		So(err, ShouldBeNil)
+		So(reply.State, ShouldEqual, AutotuneState_AUTOTUNE_FAILED)
+		So(reply.Error, ShouldContainSubstring, "old")
+	})
+
+	Convey("Cancelling", t, func() {
+		c := newTestController(&recordingController{})
+		room := &Room{Pid: newZonePID(nil), config: &Zone{Name: "Study"}}
+		c.Config["Study"] = room
+
+		// There is no target temperature to tune around.
+		_, err := c.StartAutotune(ctx, &StartAutotuneRequest{Name: "Study"})
+		So(err, ShouldNotBeNil)
+
+		_, err = c.StartAutotune(ctx, &StartAutotuneRequest{Name: "Study", Setpoint: 19, MaxHours: 2})
+		So(err, ShouldBeNil)
+		So(room.autotune.options.MaxDuration, ShouldEqual, 2*time.Hour)
+		_, err = c.CancelAutotune(ctx, &CancelAutotuneRequest{Name: "Study"})
+		So(err, ShouldBeNil)
+
+		reply, err := c.GetAutotune(ctx, &GetAutotuneRequest{Name: "Study"})
+		So(err, ShouldBeNil)
+		So(reply.State, ShouldEqual, AutotuneState_AUTOTUNE_FAILED)
+		So(reply.Error, ShouldEqual, "cancelled")
+		_, tuning := c.autotuneState(room, time.Now())
+		So(tuning, ShouldBeFalse)
+
+		// Can be started again.
+		_, err = c.StartAutotune(ctx, &StartAutotuneRequest{Name: "Study", Setpoint: 19})
+		So(err, ShouldBeNil)
+	})
+}
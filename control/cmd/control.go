@@ -20,8 +20,13 @@ import (
 	texporter "github.com/GoogleCloudPlatform/opentelemetry-operations-go/exporter/trace"
 	"github.com/coreos/go-systemd/daemon"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/hatstand/shinywaffle"
 	"github.com/hatstand/shinywaffle/calendar"
+	"github.com/hatstand/shinywaffle/capture"
 	"github.com/hatstand/shinywaffle/control"
+	"github.com/hatstand/shinywaffle/pairing"
+	"github.com/hatstand/shinywaffle/radiator"
+	"github.com/hatstand/shinywaffle/sniffer"
 	"github.com/hatstand/shinywaffle/telemetry"
 	"github.com/hatstand/shinywaffle/weather"
 	"github.com/jonstaryuk/gcloudzap"
@@ -43,10 +48,13 @@ var config = flag.String("config", "config.textproto", "Path to config proto")
 var dryRun = flag.Bool("n", false, "Disables radiator commands")
 var port = flag.Int("port", 8081, "Status port")
 var grpcPort = flag.Int("grpc", 8082, "GRPC service port")
+var dutyCycle = flag.Float64("duty_cycle", 0.01, "Fraction of each hour the radio may spend transmitting")
+var captureFile = flag.String("capture", "", "Path to append every frame received and transmitted to as a capture")
 
 var (
 	statusHtml = template.Must(template.New("status.html").Funcs(template.FuncMap{
 		"convertColour": convertColour,
+		"clock":         clock,
 	}).ParseFiles("status.html", "weather.html"))
 )
 
@@ -56,6 +64,11 @@ func convertColour(temp float64) int {
 	return int(240 + clamped)
 }
 
+// clock formats seconds since the epoch as a local time of day.
+func clock(unix int64) string {
+	return time.Unix(unix, 0).Format("15:04")
+}
+
 type Interval struct {
 	Width  int // Percentage from 0-100 of 24 hours
 	Offset int // Percentage from 0-100 of 24 hours
@@ -64,20 +77,49 @@ type Interval struct {
 type stubRadiatorController struct {
 }
 
-func (*stubRadiatorController) TurnOn(addr []byte) {
-	log.Printf("Turning on radiator: %v\n", addr)
+func (*stubRadiatorController) Set(addr radiator.Address, setting radiator.Setting) error {
+	log.Printf("Setting radiator %v to %v day: %.1f night: %.1f defrost: %.1f\n",
+		addr, setting.Mode, setting.Day, setting.Night, setting.Defrost)
+	return nil
 }
 
-func (*stubRadiatorController) TurnOff(addr []byte) {
-	log.Printf("Turning off radiator: %v\n", addr)
+func createRadiatorController(ctx context.Context, sender radiator.Sender, logger *zap.SugaredLogger) (control.RadiatorController, *control.TransmitScheduler) {
+	if sender == nil {
+		return &stubRadiatorController{}, nil
+	}
+	options := control.DefaultSchedulerOptions()
+	options.DutyCycle = *dutyCycle
+	scheduler := control.NewTransmitScheduler(sender, options, logger)
+	go scheduler.Run(ctx)
+	return scheduler, scheduler
 }
 
-func createRadiatorController() control.RadiatorController {
-	if *dryRun {
-		return &stubRadiatorController{}
-	} else {
-		return control.NewRadioController()
+// watchRemotes passes radiator packets sent by wall remotes to the controller
+// so that it can detect manual overrides, and to the pairer to confirm pairing.
+// Received frames are recorded to w if it is not nil.
+func watchRemotes(ctx context.Context, radio *shinywaffle.CC1101, w *capture.Writer, controller *control.Controller, pairer *pairing.Pairer, logger *zap.SugaredLogger) error {
+	frames, err := radio.Listen(ctx)
+	if err != nil {
+		return err
+	}
+	if w != nil {
+		frames = capture.RecordFrames(frames, w, func(err error) {
+			logger.Warnf("Failed to record frame: %v", err)
+		})
 	}
+	events := sniffer.New(controller.Registry()).Run(ctx, frames, func(f shinywaffle.Frame, err error) {
+		logger.Debugf("Failed to decode packet %x: %v", f.Payload, err)
+	})
+	go func() {
+		for e := range events {
+			logger.Infof("Received %v", e)
+			controller.HandleEvent(e)
+			pairer.HandleEvent(e)
+		}
+		for range frames {
+		}
+	}()
+	return nil
 }
 
 type ServeMux struct {
@@ -224,11 +266,49 @@ func main() {
 		logger.Fatalf("Failed to start calendar service: %v", err)
 	}
 
-	controller, err := control.NewController(*config, createRadiatorController(), calendarService, logger)
+	var radio *shinywaffle.CC1101
+	var sender radiator.Sender
+	var recorder *capture.Writer
+	if !*dryRun {
+		radio, err = shinywaffle.NewCC1101()
+		if err != nil {
+			logger.Fatalf("Failed to initialise radio: %v", err)
+		}
+		defer radio.Close()
+		sender = radio
+		if *captureFile != "" {
+			f, err := os.OpenFile(*captureFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+			if err != nil {
+				logger.Fatalf("Failed to open capture: %v", err)
+			}
+			defer f.Close()
+			recorder = capture.NewWriter(f)
+			sender = capture.NewRecordingSender(radio, recorder, func(err error) {
+				logger.Warnf("Failed to record sent packet: %v", err)
+			})
+		}
+	}
+	radiators, scheduler := createRadiatorController(ctx, sender, logger)
+	if scheduler != nil {
+		if err := telemetry.PublishTransmitStats(scheduler.Stats); err != nil {
+			logger.Fatalf("failed to configure transmit telemetry: %v", err)
+		}
+	}
+
+	controller, err := control.NewController(*config, radiators, calendarService, control.OpenWeatherMap{Location: "London"}, logger)
 	if err != nil {
 		logger.Fatalf("Failed to create controller: %v", err)
 	}
 	go controller.ControlRadiators(ctx)
+	if radio != nil {
+		// Pairing bursts can't wait in the queue but still use up the
+		// duty cycle budget.
+		pairer := pairing.NewPairer(scheduler.Sender(), pairing.DefaultOptions())
+		controller.SetPairer(pairer)
+		if err := watchRemotes(ctx, radio, recorder, controller, pairer, logger); err != nil {
+			logger.Fatalf("Failed to listen for wall remotes: %v", err)
+		}
+	}
 
 	s := grpc.NewServer()
 	control.RegisterHeatingControlServiceServer(s, controller)
@@ -0,0 +1,174 @@
+package control
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hatstand/shinywaffle/radiator"
+)
+
+// LoadConfig reads a Config textproto.
+func LoadConfig(path string) (*Config, error) {
+	configText, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read config file: %s %v", path, err)
+	}
+	var config Config
+	if err := proto.UnmarshalText(string(configText), &config); err != nil {
+		return nil, fmt.Errorf("Failed to parse config file: %v", err)
+	}
+	if err := ValidateConfig(&config); err != nil {
+		return nil, fmt.Errorf("Invalid config file %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// ValidateConfig checks that zones have unique names and sensible PID
+// tuning, radiators have well formed, unique addresses and the duty cycle
+// leaves room for the minimum on and off times.
+func ValidateConfig(config *Config) error {
+	if config.GetCyclePeriodMinutes() < 0 || config.GetMinOnMinutes() < 0 || config.GetMinOffMinutes() < 0 {
+		return fmt.Errorf("negative cycle period or minimum on or off time")
+	}
+	if config.GetMaxPreheatMinutes() < 0 {
+		return fmt.Errorf("negative maximum preheat time")
+	}
+	pwm := NewTimeProportioner(
+		time.Duration(config.GetCyclePeriodMinutes())*time.Minute,
+		time.Duration(config.GetMinOnMinutes())*time.Minute,
+		time.Duration(config.GetMinOffMinutes())*time.Minute,
+	)
+	if pwm.minOn+pwm.minOff > pwm.period {
+		return fmt.Errorf("minimum on and off times of %v and %v don't fit in a cycle of %v", pwm.minOn, pwm.minOff, pwm.period)
+	}
+	for _, z := range config.GetZone() {
+		if err := validatePID(z.GetPid()); err != nil {
+			return fmt.Errorf("zone %s: %w", z.GetName(), err)
+		}
+	}
+	_, err := NewRegistry(config)
+	return err
+}
+
+func validatePID(pid *PidConfig) error {
+	if pid == nil {
+		return nil
+	}
+	if pid.GetProportional() < 0 || pid.GetIntegral() < 0 || pid.GetDerivative() < 0 {
+		return fmt.Errorf("negative PID gain")
+	}
+	if pid.GetOutputMin() > pid.GetOutputMax() {
+		return fmt.Errorf("output min %v above max %v", pid.GetOutputMin(), pid.GetOutputMax())
+	}
+	if pid.GetIntegralMin() > pid.GetIntegralMax() {
+		return fmt.Errorf("integral min %v above max %v", pid.GetIntegralMin(), pid.GetIntegralMax())
+	}
+	return nil
+}
+
+// NewRegistry returns a registry of every radiator in config.
+func NewRegistry(config *Config) (*radiator.Registry, error) {
+	registry := radiator.NewRegistry()
+	zones := make(map[string]bool)
+	for _, z := range config.GetZone() {
+		if z.GetName() == "" {
+			return nil, fmt.Errorf("zone with no name")
+		}
+		if zones[z.GetName()] {
+			return nil, fmt.Errorf("zone %s configured more than once", z.GetName())
+		}
+		zones[z.GetName()] = true
+		for _, r := range z.GetRadiator() {
+			addr, err := radiator.AddressFromBytes(r.GetAddress())
+			if err != nil {
+				return nil, fmt.Errorf("zone %s: %w", z.GetName(), err)
+			}
+			if r.GetWatts() < 0 {
+				return nil, fmt.Errorf("zone %s: radiator %v has negative power", z.GetName(), addr)
+			}
+			if err := registry.Add(radiatorInfo(z, r, addr)); err != nil {
+				return nil, fmt.Errorf("zone %s: %w", z.GetName(), err)
+			}
+		}
+	}
+	return registry, nil
+}
+
+func radiatorInfo(z *Zone, r *Radiator, addr radiator.Address) radiator.Info {
+	return radiator.Info{
+		Address: addr,
+		Name:    r.GetName(),
+		Zone:    z.GetName(),
+		Watts:   int(r.GetWatts()),
+	}
+}
+
+// SaveConfig replaces the config at path. Comments in the original file are
+// not preserved.
+func SaveConfig(path string, config *Config) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if err := proto.MarshalText(f, config); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// AddRadiator adds a radiator at addr to the named zone.
+func AddRadiator(config *Config, zone string, addr radiator.Address, name string) error {
+	var found *Zone
+	for _, z := range config.GetZone() {
+		if z.GetName() == zone {
+			found = z
+		}
+		for _, r := range z.GetRadiator() {
+			if string(r.GetAddress()) == string(addr.Bytes()) {
+				return fmt.Errorf("radiator %v is already in %s", addr, z.GetName())
+			}
+		}
+	}
+	if found == nil {
+		return fmt.Errorf("No such zone: %s", zone)
+	}
+	found.Radiator = append(found.Radiator, &Radiator{Address: addr.Bytes(), Name: name})
+	return nil
+}
+
+// removeRadiator removes the radiator at addr from config.
+func removeRadiator(config *Config, addr radiator.Address) {
+	for _, z := range config.GetZone() {
+		for i, r := range z.GetRadiator() {
+			if string(r.GetAddress()) == string(addr.Bytes()) {
+				z.Radiator = append(z.Radiator[:i], z.Radiator[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// RadiatorAddresses returns the addresses of every radiator in config.
+func RadiatorAddresses(config *Config) []radiator.Address {
+	var addrs []radiator.Address
+	for _, z := range config.GetZone() {
+		for _, r := range z.GetRadiator() {
+			if addr, err := radiator.AddressFromBytes(r.GetAddress()); err == nil {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,71 @@
+package control
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hatstand/shinywaffle/radiator"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func parseConfig(text string) *Config {
+	var config Config
+	So(proto.UnmarshalText(text, &config), ShouldBeNil)
+	return &config
+}
+
+func TestValidateConfig(t *testing.T) {
+	Convey("Valid", t, func() {
+		registry, err := NewRegistry(parseConfig(`
+			zone { name: "Study" radiator { address: "\x2e\x04" name: "Window" watts: 1000 } }
+			zone { name: "Living Room" radiator { address: "\x2b\xdb" } radiator { address: "\x2b\xdc" } }
+		`))
+		So(err, ShouldBeNil)
+		info, ok := registry.Lookup(0x2e04)
+		So(ok, ShouldBeTrue)
+		So(info, ShouldResemble, radiator.Info{Address: 0x2e04, Name: "Window", Zone: "Study", Watts: 1000})
+		So(registry.Zone("Living Room"), ShouldHaveLength, 2)
+	})
+
+	Convey("Malformed address", t, func() {
+		So(ValidateConfig(parseConfig(`zone { name: "Study" radiator { address: "\x2e" } }`)), ShouldNotBeNil)
+		So(ValidateConfig(parseConfig(`zone { name: "Study" radiator { } }`)), ShouldNotBeNil)
+	})
+
+	Convey("Duplicate address", t, func() {
+		So(ValidateConfig(parseConfig(`
+			zone { name: "Study" radiator { address: "\x2e\x04" } }
+			zone { name: "Kitchen" radiator { address: "\x2e\x04" } }
+		`)), ShouldNotBeNil)
+	})
+
+	Convey("Duplicate zone", t, func() {
+		So(ValidateConfig(parseConfig(`zone { name: "Study" } zone { name: "Study" }`)), ShouldNotBeNil)
+		So(ValidateConfig(parseConfig(`zone { }`)), ShouldNotBeNil)
+	})
+
+	Convey("Duty cycle", t, func() {
+		So(ValidateConfig(parseConfig(`cycle_period_minutes: 10 min_on_minutes: 5 min_off_minutes: 5`)), ShouldBeNil)
+		So(ValidateConfig(parseConfig(`cycle_period_minutes: 10 min_on_minutes: 6 min_off_minutes: 5`)), ShouldNotBeNil)
+		// Defaults to 3 minutes each.
+		So(ValidateConfig(parseConfig(`cycle_period_minutes: 5`)), ShouldNotBeNil)
+		So(ValidateConfig(parseConfig(`min_on_minutes: -1`)), ShouldNotBeNil)
+	})
+
+	Convey("PID tuning", t, func() {
+		So(ValidateConfig(parseConfig(`zone { name: "Study" pid { proportional: 2 integral: 0.01 output_max: 80 } }`)), ShouldBeNil)
+		So(ValidateConfig(parseConfig(`zone { name: "Study" pid { proportional: -1 } }`)), ShouldNotBeNil)
+		So(ValidateConfig(parseConfig(`zone { name: "Study" pid { output_min: 10 } }`)), ShouldNotBeNil)
+		So(ValidateConfig(parseConfig(`zone { name: "Study" pid { integral_min: 10 integral_max: 5 } }`)), ShouldNotBeNil)
+	})
+
+	Convey("Preheating", t, func() {
+		So(ValidateConfig(parseConfig(`max_preheat_minutes: 240`)), ShouldBeNil)
+		So(ValidateConfig(parseConfig(`max_preheat_minutes: -1`)), ShouldNotBeNil)
+	})
+
+	Convey("Negative power", t, func() {
+		So(ValidateConfig(parseConfig(`zone { name: "Study" radiator { address: "\x2e\x04" watts: -1 } }`)), ShouldNotBeNil)
+	})
+}
@@ -5,18 +5,33 @@
 Package control is a generated protocol buffer package.
 
 It is generated from these files:
+
 	control.proto
 	service.proto
 
 It has these top-level messages:
+
 	Radiator
+	PidConfig
 	Zone
 	GetZonesRequest
 	GetZonesReply
 	GetZoneStatusRequest
+	Override
 	GetZoneStatusReply
+	RadiatorStatus
 	SetZoneScheduleRequest
 	SetZoneScheduleReply
+	ClearOverrideRequest
+	ClearOverrideReply
+	PairRadiatorRequest
+	PairRadiatorReply
+	StartAutotuneRequest
+	StartAutotuneReply
+	GetAutotuneRequest
+	GetAutotuneReply
+	CancelAutotuneRequest
+	CancelAutotuneReply
 	Config
 */
 package control
@@ -37,7 +52,12 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type Radiator struct {
+	// Big endian 16 bit address the radiator is paired as.
 	Address []byte `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// Friendly name, e.g. "Kitchen window".
+	Name string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	// Rated power in watts, if known.
+	Watts int32 `protobuf:"varint,3,opt,name=watts" json:"watts,omitempty"`
 }
 
 func (m *Radiator) Reset()                    { *m = Radiator{} }
@@ -52,6 +72,20 @@ func (m *Radiator) GetAddress() []byte {
 	return nil
 }
 
+func (m *Radiator) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Radiator) GetWatts() int32 {
+	if m != nil {
+		return m.Watts
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Radiator)(nil), "control.Radiator")
 }
@@ -59,10 +93,12 @@ func init() {
 func init() { proto.RegisterFile("control.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 80 bytes of a gzipped FileDescriptorProto
+	// 111 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x4d, 0xce, 0xcf, 0x2b,
-	0x29, 0xca, 0xcf, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x87, 0x72, 0x95, 0x54, 0xb8,
+	0x29, 0xca, 0xcf, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x87, 0x72, 0x95, 0xfc, 0xb8,
 	0x38, 0x82, 0x12, 0x53, 0x32, 0x13, 0x4b, 0xf2, 0x8b, 0x84, 0x24, 0xb8, 0xd8, 0x13, 0x53, 0x52,
-	0x8a, 0x52, 0x8b, 0x8b, 0x25, 0x18, 0x15, 0x18, 0x35, 0x78, 0x82, 0x60, 0xdc, 0x24, 0x36, 0xb0,
-	0x2e, 0x63, 0x40, 0x00, 0x00, 0x00, 0xff, 0xff, 0xf8, 0xad, 0x0e, 0x63, 0x46, 0x00, 0x00, 0x00,
+	0x8a, 0x52, 0x8b, 0x8b, 0x25, 0x18, 0x15, 0x18, 0x35, 0x78, 0x82, 0x60, 0x5c, 0x21, 0x21, 0x2e,
+	0x96, 0xbc, 0xc4, 0xdc, 0x54, 0x09, 0x26, 0x05, 0x46, 0x0d, 0xce, 0x20, 0x30, 0x5b, 0x48, 0x84,
+	0x8b, 0xb5, 0x3c, 0xb1, 0xa4, 0xa4, 0x58, 0x82, 0x59, 0x81, 0x51, 0x83, 0x35, 0x08, 0xc2, 0x49,
+	0x62, 0x03, 0x9b, 0x6f, 0x0c, 0x18, 0x00, 0x4a, 0x57, 0x15, 0xc0, 0x70, 0x00, 0x00, 0x00,
 }
@@ -0,0 +1,140 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hatstand/shinywaffle/radiator"
+	"github.com/hatstand/shinywaffle/sniffer"
+)
+
+// override is a setting someone chose for a radiator with its wall remote.
+type override struct {
+	address radiator.Address
+	setting radiator.Setting
+	start   time.Time
+	end     time.Time
+}
+
+// HandleEvent holds the zone of a radiator in the setting sent to it by
+// someone using its wall remote.
+func (c *Controller) HandleEvent(e *sniffer.Event) {
+	if e.Pairing {
+		return
+	}
+	info, ok := c.registry.Lookup(e.Address)
+	if !ok {
+		return
+	}
+	room := c.Config[info.Zone]
+	if room == nil {
+		return
+	}
+	addr := e.Address
+	c.lock.Lock()
+	last, ok := c.commanded[addr]
+	c.lock.Unlock()
+	if ok && last.setting == e.Setting {
+		// Most likely another controller repeating our own command.
+		return
+	}
+	// Fetching the schedule mustn't hold up commanding other rooms.
+	end := e.Time.Add(c.overrideHold)
+	if c.overrideUntilScheduleChange {
+		if next, ok := c.nextScheduleChange(room, e.Time); ok && next.Before(end) {
+			end = next
+		}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	room.override = &override{
+		address: addr,
+		setting: e.Setting,
+		start:   e.Time,
+		end:     end,
+	}
+	c.logger.Infof("Manual override in %s: %v set to %v day: %.1f night: %.1f defrost: %.1f until %v",
+		room.config.GetName(), addr, e.Setting.Mode, e.Setting.Day, e.Setting.Night, e.Setting.Defrost, end)
+}
+
+// holdOverride reports whether room is held by an override at now, clearing
+// it if it has expired.
+func (c *Controller) holdOverride(room *Room, now time.Time) bool {
+	if room.override == nil {
+		return false
+	}
+	if now.Before(room.override.end) {
+		return true
+	}
+	c.logger.Infof("Manual override in %s expired", room.config.GetName())
+	c.clearOverride(room)
+	return false
+}
+
+// clearOverride returns room to its schedule. The radiators are in an
+// unknown state so they are all sent a command on the next tick.
+func (c *Controller) clearOverride(room *Room) {
+	room.override = nil
+	for _, r := range c.registry.Zone(room.config.GetName()) {
+		delete(c.commanded, r.Address)
+	}
+}
+
+// nextScheduleChange returns the next time after now that room's schedule
+// starts or stops heating.
+func (c *Controller) nextScheduleChange(room *Room, now time.Time) (time.Time, bool) {
+	if c.schedule == nil {
+		return time.Time{}, false
+	}
+	periods, err := c.schedule.GetSchedule(room.config.CalendarId)
+	if err != nil {
+		c.logger.Warnf("Failed to fetch schedule for room %s: %v", room.config.GetName(), err)
+		return time.Time{}, false
+	}
+	var next time.Time
+	for _, period := range periods {
+		for _, s := range []string{period.Start, period.End} {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				continue
+			}
+			if t.After(now) && (next.IsZero() || t.Before(next)) {
+				next = t
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
+
+func (c *Controller) overrideStatus(room *Room) *Override {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	o := room.override
+	if o == nil {
+		return nil
+	}
+	return &Override{
+		Address:            o.address.Bytes(),
+		Mode:               o.setting.Mode.String(),
+		DayTemperature:     o.setting.Day,
+		NightTemperature:   o.setting.Night,
+		DefrostTemperature: o.setting.Defrost,
+		StartTime:          o.start.Unix(),
+		EndTime:            o.end.Unix(),
+	}
+}
+
+func (s *Controller) ClearOverride(ctx context.Context, req *ClearOverrideRequest) (*ClearOverrideReply, error) {
+	room := s.Config[req.GetName()]
+	if room == nil {
+		return nil, fmt.Errorf("No such zone: %s", req.GetName())
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if room.override != nil {
+		s.logger.Infof("Clearing manual override in %s", req.GetName())
+		s.clearOverride(room)
+	}
+	return &ClearOverrideReply{}, nil
+}
@@ -0,0 +1,110 @@
+package control
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle/radiator"
+	"github.com/hatstand/shinywaffle/sniffer"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOverride(t *testing.T) {
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	manual := radiator.Setting{Mode: radiator.Night, Day: 21, Night: 19, Defrost: 7}
+	remoteEvent := func(at time.Time, setting radiator.Setting) *sniffer.Event {
+		return &sniffer.Event{Time: at, Zone: "Study", Address: 0x1234, Setting: setting}
+	}
+	withStudy := func(f func(radiators *recordingController, c *Controller, room *Room)) func() {
+		return func() {
+			radiators := &recordingController{}
+			c := newTestController(radiators)
+			room := &Room{config: &Zone{Name: "Study"}}
+			c.Config["Study"] = room
+			c.registry.Add(radiator.Info{Address: 0x1234, Zone: "Study"})
+			c.registry.Add(radiator.Info{Address: 0x1235, Zone: "Study"})
+			f(radiators, c, room)
+		}
+	}
+
+	Convey("Remote changes are held", t, withStudy(func(radiators *recordingController, c *Controller, room *Room) {
+		c.commandRoom(room, HeatingState_ON, start)
+		So(radiators.calls, ShouldHaveLength, 2)
+
+		c.HandleEvent(remoteEvent(start.Add(time.Minute), manual))
+		So(room.override, ShouldNotBeNil)
+		So(room.override.end, ShouldEqual, start.Add(time.Minute+2*time.Hour))
+
+		status := c.overrideStatus(room)
+		So(status.GetMode(), ShouldEqual, "NIGHT")
+		So(status.GetNightTemperature(), ShouldEqual, 19)
+		So(status.GetEndTime(), ShouldEqual, start.Add(time.Minute+2*time.Hour).Unix())
+
+		// The rest of the zone follows the changed radiator rather than
+		// the schedule.
+		c.commandRoom(room, HeatingState_OFF, start.Add(2*time.Minute))
+		c.commandRoom(room, HeatingState_ON, start.Add(10*time.Minute))
+		So(radiators.calls, ShouldHaveLength, 3)
+		So(radiators.calls[2], ShouldResemble, setCall{0x1235, manual})
+
+		// Once expired every radiator is sent the schedule again, even if
+		// its state hasn't changed.
+		c.commandRoom(room, HeatingState_ON, start.Add(3*time.Hour))
+		So(room.override, ShouldBeNil)
+		So(radiators.calls, ShouldHaveLength, 5)
+		So(radiators.calls[4].setting, ShouldResemble, radiatorSetting(room, HeatingState_ON))
+		So(c.overrideStatus(room), ShouldBeNil)
+	}))
+
+	Convey("Held until the schedule changes", t, withStudy(func(radiators *recordingController, c *Controller, room *Room) {
+		c.overrideUntilScheduleChange = true
+		c.schedule = fixedSchedule{{
+			Start: start.Add(30 * time.Minute).Format(time.RFC3339),
+			End:   start.Add(5 * time.Hour).Format(time.RFC3339),
+		}}
+		c.HandleEvent(remoteEvent(start, manual))
+		So(room.override.end, ShouldEqual, start.Add(30*time.Minute))
+
+		// The next change, the end of the period, is after the hold.
+		c.HandleEvent(remoteEvent(start.Add(time.Hour), manual))
+		So(room.override.end, ShouldEqual, start.Add(3*time.Hour))
+
+		// Otherwise the schedule is ignored.
+		c.overrideUntilScheduleChange = false
+		c.HandleEvent(remoteEvent(start.Add(4*time.Hour+30*time.Minute), manual))
+		So(room.override.end, ShouldEqual, start.Add(6*time.Hour+30*time.Minute))
+	}))
+
+	Convey("Our own commands are not overrides", t, withStudy(func(radiators *recordingController, c *Controller, room *Room) {
+		c.commandRoom(room, HeatingState_ON, start)
+		c.HandleEvent(remoteEvent(start, radiatorSetting(room, HeatingState_ON)))
+		So(room.override, ShouldBeNil)
+	}))
+
+	Convey("Unknown radiators and pairing are ignored", t, withStudy(func(radiators *recordingController, c *Controller, room *Room) {
+		e := remoteEvent(start, manual)
+		e.Address = 0x5678
+		c.HandleEvent(e)
+		e = remoteEvent(start, manual)
+		e.Pairing = true
+		c.HandleEvent(e)
+		So(room.override, ShouldBeNil)
+	}))
+
+	Convey("ClearOverride", t, withStudy(func(radiators *recordingController, c *Controller, room *Room) {
+		c.commandRoom(room, HeatingState_ON, start)
+		c.HandleEvent(remoteEvent(start, manual))
+		So(room.override, ShouldNotBeNil)
+
+		_, err := c.ClearOverride(context.Background(), &ClearOverrideRequest{Name: "Study"})
+		So(err, ShouldBeNil)
+		So(room.override, ShouldBeNil)
+		c.commandRoom(room, HeatingState_ON, start.Add(time.Minute))
+		So(radiators.calls, ShouldHaveLength, 4)
+
+		_, err = c.ClearOverride(context.Background(), &ClearOverrideRequest{Name: "Kitchen"})
+		So(err, ShouldNotBeNil)
+	}))
+}
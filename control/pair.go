@@ -0,0 +1,107 @@
+package control
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hatstand/shinywaffle/pairing"
+	"github.com/hatstand/shinywaffle/radiator"
+)
+
+// SetPairer enables PairRadiator using p to send pairing packets.
+func (s *Controller) SetPairer(p *pairing.Pairer) {
+	s.pairer = p
+}
+
+func (s *Controller) PairRadiator(ctx context.Context, req *PairRadiatorRequest) (*PairRadiatorReply, error) {
+	if s.pairer == nil {
+		return nil, fmt.Errorf("Pairing needs a radio")
+	}
+	if s.Config[req.GetZone()] == nil {
+		return nil, fmt.Errorf("No such zone: %s", req.GetZone())
+	}
+	setting, err := pairingSetting(req)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := s.pairingAddress(req.GetAddress())
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Pairing radiator %v in %s as %v", addr, req.GetZone(), setting)
+	result, err := s.pairer.Pair(ctx, addr, setting)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to pair radiator %v: %w", addr, err)
+	}
+	reply := &PairRadiatorReply{
+		Address:     addr.Bytes(),
+		Confirmed:   result.Confirmation != nil,
+		PacketsSent: int32(result.Sent),
+	}
+	if req.GetRequireConfirmation() && !reply.Confirmed {
+		return reply, nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if info, ok := s.registry.Lookup(addr); ok {
+		return nil, fmt.Errorf("radiator %v is already in %s", addr, info.Zone)
+	}
+	if err := AddRadiator(s.config, req.GetZone(), addr, req.GetName()); err != nil {
+		return nil, err
+	}
+	if s.configPath != "" {
+		if err := SaveConfig(s.configPath, s.config); err != nil {
+			// Leave the config as it is on disk so that pairing can be retried.
+			removeRadiator(s.config, addr)
+			return nil, err
+		}
+	}
+	if err := s.registry.Add(radiator.Info{Address: addr, Name: req.GetName(), Zone: req.GetZone()}); err != nil {
+		return nil, err
+	}
+	// The radiator is commanded on the next tick as it has never been sent
+	// anything.
+	return reply, nil
+}
+
+// pairingAddress returns addr if it is free, or allocates an address if addr
+// is unset.
+func (s *Controller) pairingAddress(addr []byte) (radiator.Address, error) {
+	if addr == nil {
+		return pairing.AllocateAddress(s.registry.Addresses())
+	}
+	a, err := radiator.AddressFromBytes(addr)
+	if err != nil {
+		return 0, err
+	}
+	if info, ok := s.registry.Lookup(a); ok {
+		// Pairing would make both radiators respond to the same commands.
+		return 0, fmt.Errorf("radiator %v is already in %s", a, info.Zone)
+	}
+	return a, nil
+}
+
+// pairingSetting returns the initial setting requested for a new radiator.
+func pairingSetting(req *PairRadiatorRequest) (radiator.Setting, error) {
+	setting := pairing.DefaultSetting
+	if req.GetMode() != "" {
+		m, err := radiator.ParseMode(req.GetMode())
+		if err != nil {
+			return setting, err
+		}
+		setting.Mode = m
+	}
+	if t := req.GetDayTemperature(); t != 0 {
+		setting.Day = t
+	}
+	if t := req.GetNightTemperature(); t != 0 {
+		setting.Night = t
+	}
+	if t := req.GetDefrostTemperature(); t != 0 {
+		setting.Defrost = t
+	}
+	return setting, setting.Validate()
+}
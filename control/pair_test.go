@@ -0,0 +1,115 @@
+package control
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle/pairing"
+	"github.com/hatstand/shinywaffle/radiator"
+	"github.com/hatstand/shinywaffle/radiator/radiatortest"
+	"go.uber.org/zap"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testConfig = `
+zone {
+  name: "Study"
+  radiator {
+    address: "\x2e\x04"
+  }
+}
+zone {
+  name: "Kitchen"
+}
+`
+
+func TestPairRadiator(t *testing.T) {
+	withConfig := func(f func(c *Controller, sender *radiatortest.Sender, path string)) func() {
+		return func() {
+			dir, err := ioutil.TempDir("", "pair")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "config.textproto")
+			So(ioutil.WriteFile(path, []byte(testConfig), 0644), ShouldBeNil)
+
+			c, err := NewController(path, &recordingController{}, nil, nil, zap.NewNop().Sugar())
+			So(err, ShouldBeNil)
+			sender := &radiatortest.Sender{}
+			c.SetPairer(pairing.NewPairer(sender, pairing.Options{Attempts: 2, Repeats: 3, Interval: time.Millisecond}))
+			f(c, sender, path)
+		}
+	}
+
+	Convey("Allocates an address and saves the config", t, withConfig(func(c *Controller, sender *radiatortest.Sender, path string) {
+		reply, err := c.PairRadiator(context.Background(), &PairRadiatorRequest{
+			Zone:           "Kitchen",
+			Mode:           "day",
+			DayTemperature: 21,
+		})
+		So(err, ShouldBeNil)
+		So(reply.GetAddress(), ShouldResemble, []byte{0x2e, 0x05})
+		So(reply.GetConfirmed(), ShouldBeFalse)
+		So(reply.GetPacketsSent(), ShouldEqual, 6)
+		sent, err := sender.Decoded()
+		So(err, ShouldBeNil)
+		So(sent[0], ShouldResemble, radiator.NewPairingPacket(0x2e05, radiator.Setting{
+			Mode:    radiator.Day,
+			Day:     21,
+			Night:   15,
+			Defrost: 10,
+		}))
+
+		info, ok := c.Registry().Lookup(0x2e05)
+		So(ok, ShouldBeTrue)
+		So(info.Zone, ShouldEqual, "Kitchen")
+		config, err := LoadConfig(path)
+		So(err, ShouldBeNil)
+		So(config.GetZone()[1].GetRadiator()[0].GetAddress(), ShouldResemble, []byte{0x2e, 0x05})
+	}))
+
+	Convey("Requires confirmation", t, withConfig(func(c *Controller, sender *radiatortest.Sender, path string) {
+		reply, err := c.PairRadiator(context.Background(), &PairRadiatorRequest{
+			Zone:                "Kitchen",
+			Address:             []byte{0x12, 0x34},
+			RequireConfirmation: true,
+		})
+		So(err, ShouldBeNil)
+		So(reply.GetConfirmed(), ShouldBeFalse)
+		_, ok := c.Registry().Lookup(0x1234)
+		So(ok, ShouldBeFalse)
+	}))
+
+	Convey("Failing to save the config can be retried", t, withConfig(func(c *Controller, sender *radiatortest.Sender, path string) {
+		c.configPath = filepath.Join(path, "missing", "config.textproto")
+		req := &PairRadiatorRequest{Zone: "Kitchen", Address: []byte{0x12, 0x34}}
+		_, err := c.PairRadiator(context.Background(), req)
+		So(err, ShouldNotBeNil)
+		_, ok := c.Registry().Lookup(0x1234)
+		So(ok, ShouldBeFalse)
+		So(c.config.GetZone()[1].GetRadiator(), ShouldBeEmpty)
+
+		c.configPath = path
+		_, err = c.PairRadiator(context.Background(), req)
+		So(err, ShouldBeNil)
+		_, ok = c.Registry().Lookup(0x1234)
+		So(ok, ShouldBeTrue)
+		config, err := LoadConfig(path)
+		So(err, ShouldBeNil)
+		So(config.GetZone()[1].GetRadiator(), ShouldHaveLength, 1)
+	}))
+
+	Convey("Invalid requests", t, withConfig(func(c *Controller, sender *radiatortest.Sender, path string) {
+		_, err := c.PairRadiator(context.Background(), &PairRadiatorRequest{Zone: "Bedroom"})
+		So(err, ShouldNotBeNil)
+		_, err = c.PairRadiator(context.Background(), &PairRadiatorRequest{Zone: "Kitchen", Mode: "SUN"})
+		So(err, ShouldNotBeNil)
+		_, err = c.PairRadiator(context.Background(), &PairRadiatorRequest{Zone: "Kitchen", Address: []byte{0x2e, 0x04}})
+		So(err, ShouldNotBeNil)
+		So(sender.Sent(), ShouldBeEmpty)
+	}))
+}
@@ -0,0 +1,154 @@
+package control
+
+import (
+	"time"
+)
+
+// Default gains, used for zones without their own. The integral and
+// derivative gains are per second.
+const (
+	kP = 1
+	kI = .5
+	kD = .0
+)
+
+// PID is a PID controller. Unlike pidctrl it allows the integral term to be
+// limited separately from the output, and optionally stops integrating while
+// the output is saturated (conditional integration) so that the integral
+// doesn't wind up while the radiators are already fully on or off.
+type PID struct {
+	kp, ki, kd     float64
+	outMin, outMax float64
+	intMin, intMax float64
+	// Whether to stop integrating while saturated.
+	conditional bool
+
+	setpoint  float64
+	integral  float64
+	prevValue float64
+	// Whether prevValue has been set by an update.
+	updated bool
+}
+
+// NewPID returns a controller with the given gains and output limits of 0 to
+// 100, the percentage of each cycle to heat for.
+func NewPID(kp, ki, kd float64) *PID {
+	return &PID{
+		kp:     kp,
+		ki:     ki,
+		kd:     kd,
+		outMin: 0,
+		outMax: 100,
+		intMin: 0,
+		intMax: 100,
+	}
+}
+
+// newZonePID returns a controller configured by a zone's PID config, or the
+// default gains if it has none.
+func newZonePID(config *PidConfig) *PID {
+	if config == nil {
+		return NewPID(kP, kI, kD)
+	}
+	pid := NewPID(config.GetProportional(), config.GetIntegral(), config.GetDerivative())
+	if config.GetOutputMax() > config.GetOutputMin() {
+		pid.SetOutputLimits(config.GetOutputMin(), config.GetOutputMax())
+	}
+	if config.GetIntegralMax() > config.GetIntegralMin() {
+		pid.SetIntegralLimits(config.GetIntegralMin(), config.GetIntegralMax())
+	}
+	pid.SetConditionalIntegration(config.GetConditionalIntegration())
+	return pid
+}
+
+// Set changes the setpoint.
+func (c *PID) Set(setpoint float64) {
+	c.setpoint = setpoint
+}
+
+// Get returns the setpoint.
+func (c *PID) Get() float64 {
+	return c.setpoint
+}
+
+// SetBumpless changes the setpoint and adjusts the integral term to make up
+// for the change in the proportional term, so that the output doesn't jump.
+// The integral term is still limited so a large change may still cause a
+// smaller jump.
+func (c *PID) SetBumpless(setpoint float64) {
+	c.integral = clamp(c.integral-c.kp*(setpoint-c.setpoint), c.intMin, c.intMax)
+	c.setpoint = setpoint
+}
+
+// Reset clears the integral term and the previous value, as if the
+// controller had just been created.
+func (c *PID) Reset() {
+	c.integral = 0
+	c.prevValue = 0
+	c.updated = false
+}
+
+// PID returns the proportional, integral and derivative gains.
+func (c *PID) PID() (p, i, d float64) {
+	return c.kp, c.ki, c.kd
+}
+
+// SetOutputLimits limits the output, and the integral term to the same
+// limits.
+func (c *PID) SetOutputLimits(min, max float64) {
+	c.outMin, c.outMax = min, max
+	c.SetIntegralLimits(min, max)
+}
+
+// OutputLimits returns the output limits.
+func (c *PID) OutputLimits() (min, max float64) {
+	return c.outMin, c.outMax
+}
+
+// SetIntegralLimits limits the integral term.
+func (c *PID) SetIntegralLimits(min, max float64) {
+	c.intMin, c.intMax = min, max
+	c.integral = clamp(c.integral, min, max)
+}
+
+// SetConditionalIntegration sets whether integration stops while the output
+// is saturated in the direction of the error.
+func (c *PID) SetConditionalIntegration(conditional bool) {
+	c.conditional = conditional
+}
+
+// Integral returns the integral term.
+func (c *PID) Integral() float64 {
+	return c.integral
+}
+
+// UpdateDuration updates the controller with the current value and the time
+// since the last update, and returns the new output.
+func (c *PID) UpdateDuration(value float64, duration time.Duration) float64 {
+	dt := duration.Seconds()
+	err := c.setpoint - value
+	var d float64
+	if c.updated && dt > 0 {
+		d = -(value - c.prevValue) / dt
+	}
+	c.prevValue = value
+	c.updated = true
+
+	integral := clamp(c.integral+err*dt*c.ki, c.intMin, c.intMax)
+	unclamped := c.kp*err + integral + c.kd*d
+	saturated := (unclamped > c.outMax && err > 0) || (unclamped < c.outMin && err < 0)
+	if !c.conditional || !saturated {
+		c.integral = integral
+	}
+	return clamp(c.kp*err+c.integral+c.kd*d, c.outMin, c.outMax)
+}
+
+func clamp(v, min, max float64) float64 {
+	if v > max {
+		return max
+	}
+	if v < min {
+		return min
+	}
+	return v
+}
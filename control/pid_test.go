@@ -0,0 +1,104 @@
+package control
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPID(t *testing.T) {
+	Convey("Proportional", t, func() {
+		pid := NewPID(10, 0, 0)
+		pid.Set(20)
+		So(pid.UpdateDuration(18, time.Minute), ShouldEqual, 20)
+		So(pid.UpdateDuration(10, time.Minute), ShouldEqual, 100)
+		So(pid.UpdateDuration(21, time.Minute), ShouldEqual, 0)
+	})
+
+	Convey("Integral is limited", t, func() {
+		pid := NewPID(0, 1, 0)
+		pid.SetIntegralLimits(0, 30)
+		pid.Set(20)
+		So(pid.UpdateDuration(19, 10*time.Second), ShouldEqual, 10)
+		So(pid.UpdateDuration(19, time.Hour), ShouldEqual, 30)
+		So(pid.Integral(), ShouldEqual, 30)
+		// Unwinds straight away once past the setpoint.
+		So(pid.UpdateDuration(21, 10*time.Second), ShouldEqual, 20)
+	})
+
+	Convey("Output limits also limit the integral", t, func() {
+		pid := NewPID(0, 1, 0)
+		pid.SetOutputLimits(0, 50)
+		pid.Set(20)
+		pid.UpdateDuration(10, time.Hour)
+		So(pid.Integral(), ShouldEqual, 50)
+		min, max := pid.OutputLimits()
+		So(min, ShouldEqual, 0)
+		So(max, ShouldEqual, 50)
+	})
+
+	Convey("Conditional integration", t, func() {
+		pid := NewPID(100, 0.01, 0)
+		pid.SetConditionalIntegration(true)
+		pid.Set(20)
+		// Saturated by the proportional term alone, so nothing is integrated.
+		So(pid.UpdateDuration(15, time.Hour), ShouldEqual, 100)
+		So(pid.Integral(), ShouldEqual, 0)
+		So(pid.UpdateDuration(19.9, time.Minute), ShouldAlmostEqual, 10.06, 0.001)
+		So(pid.Integral(), ShouldAlmostEqual, 0.06, 0.001)
+	})
+
+	Convey("Derivative", t, func() {
+		pid := NewPID(0, 0, 60)
+		pid.SetOutputLimits(-100, 100)
+		pid.Set(20)
+		// No derivative without a previous value.
+		So(pid.UpdateDuration(18, time.Minute), ShouldEqual, 0)
+		// Rising by 1 degree a minute.
+		So(pid.UpdateDuration(19, time.Minute), ShouldEqual, -1)
+	})
+
+	Convey("Bumpless setpoint changes", t, func() {
+		pid := NewPID(10, 0.01, 0)
+		pid.Set(20)
+		before := pid.UpdateDuration(19, 50*time.Second)
+		So(before, ShouldEqual, 10.5)
+		pid.SetBumpless(20.05)
+		So(pid.Integral(), ShouldEqual, 0)
+		So(pid.UpdateDuration(19, 0), ShouldAlmostEqual, before)
+		So(pid.Get(), ShouldEqual, 20.05)
+	})
+
+	Convey("Reset", t, func() {
+		pid := NewPID(0, 1, 60)
+		pid.SetOutputLimits(-100, 100)
+		pid.Set(20)
+		pid.UpdateDuration(19, time.Minute)
+		pid.Reset()
+		So(pid.Integral(), ShouldEqual, 0)
+		// No derivative kick from the value before the reset.
+		So(pid.UpdateDuration(10, 0), ShouldEqual, 0)
+	})
+
+	Convey("Zone config", t, func() {
+		pid := newZonePID(nil)
+		p, i, d := pid.PID()
+		So([]float64{p, i, d}, ShouldResemble, []float64{kP, kI, kD})
+		min, max := pid.OutputLimits()
+		So(min, ShouldEqual, 0)
+		So(max, ShouldEqual, 100)
+
+		pid = newZonePID(&PidConfig{
+			Proportional: 2,
+			Integral:     0.01,
+			OutputMax:    80,
+			IntegralMax:  20,
+		})
+		p, i, d = pid.PID()
+		So([]float64{p, i, d}, ShouldResemble, []float64{2, 0.01, 0})
+		_, max = pid.OutputLimits()
+		So(max, ShouldEqual, 80)
+		So(pid.intMax, ShouldEqual, 20)
+	})
+}
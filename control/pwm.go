@@ -0,0 +1,111 @@
+package control
+
+import (
+	"time"
+)
+
+const (
+	defaultCyclePeriod = 15 * time.Minute
+	defaultMinOnTime   = 3 * time.Minute
+	defaultMinOffTime  = 3 * time.Minute
+)
+
+// TimeProportioner turns a PID output between 0 and 100 into a heating state
+// by switching on for that percentage of each cycle, e.g. 60% of a 15 minute
+// cycle is 9 minutes on followed by 6 minutes off. Radiators are never
+// switched on or off for less than the minimum on and off times.
+type TimeProportioner struct {
+	period time.Duration
+	minOn  time.Duration
+	minOff time.Duration
+
+	cycleStart time.Time
+	state      HeatingState
+	// When the state last changed, or zero if it never has.
+	switched time.Time
+}
+
+// NewTimeProportioner returns a TimeProportioner with the given cycle period
+// and minimum on and off times. Non-positive durations are replaced by the
+// defaults.
+func NewTimeProportioner(period, minOn, minOff time.Duration) *TimeProportioner {
+	if period <= 0 {
+		period = defaultCyclePeriod
+	}
+	if minOn <= 0 {
+		minOn = defaultMinOnTime
+	}
+	if minOff <= 0 {
+		minOff = defaultMinOffTime
+	}
+	return &TimeProportioner{
+		period: period,
+		minOn:  minOn,
+		minOff: minOff,
+		state:  HeatingState_OFF,
+	}
+}
+
+// onTime returns how long to be on for in each cycle for output. Periods too
+// short to switch for are rounded to fully off or fully on.
+func (p *TimeProportioner) onTime(output float64) time.Duration {
+	if output <= 0 {
+		return 0
+	}
+	if output >= 100 {
+		return p.period
+	}
+	on := time.Duration(output / 100 * float64(p.period))
+	if on < p.minOn {
+		return 0
+	}
+	if p.period-on < p.minOff {
+		return p.period
+	}
+	return on
+}
+
+// Update returns the heating state at now for the latest PID output. The on
+// portion is at the start of each cycle and follows changes in output within
+// a cycle.
+func (p *TimeProportioner) Update(output float64, now time.Time) HeatingState {
+	if p.cycleStart.IsZero() || now.Before(p.cycleStart) {
+		p.cycleStart = now
+	}
+	if elapsed := now.Sub(p.cycleStart); elapsed >= p.period {
+		p.cycleStart = p.cycleStart.Add(elapsed / p.period * p.period)
+	}
+
+	want := HeatingState_OFF
+	if now.Sub(p.cycleStart) < p.onTime(output) {
+		want = HeatingState_ON
+	}
+	if want == p.state {
+		return p.state
+	}
+
+	minimum := p.minOff
+	if p.state == HeatingState_ON {
+		minimum = p.minOn
+	}
+	if !p.switched.IsZero() && now.Sub(p.switched) < minimum {
+		return p.state
+	}
+	p.state = want
+	p.switched = now
+	return p.state
+}
+
+// Reset starts afresh with the radiators off, forgetting the current cycle
+// and when they last switched, e.g. after something else has been switching
+// them.
+func (p *TimeProportioner) Reset() {
+	p.cycleStart = time.Time{}
+	p.state = HeatingState_OFF
+	p.switched = time.Time{}
+}
+
+// State returns the state last returned by Update.
+func (p *TimeProportioner) State() HeatingState {
+	return p.state
+}
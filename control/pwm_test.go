@@ -0,0 +1,105 @@
+package control
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// runProportioner updates p every minute for d with a constant output and
+// returns how many minutes it was on for and how many times it switched.
+func runProportioner(p *TimeProportioner, output float64, start time.Time, d time.Duration) (time.Duration, int) {
+	var on time.Duration
+	switches := 0
+	last := p.State()
+	for t := time.Duration(0); t < d; t += time.Minute {
+		state := p.Update(output, start.Add(t))
+		if state == HeatingState_ON {
+			on += time.Minute
+		}
+		if state != last {
+			switches++
+			last = state
+		}
+	}
+	return on, switches
+}
+
+func TestTimeProportioner(t *testing.T) {
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	Convey("Switches on for the output's share of each cycle", t, func() {
+		p := NewTimeProportioner(15*time.Minute, 3*time.Minute, 3*time.Minute)
+		So(p.Update(60, start), ShouldEqual, HeatingState_ON)
+		So(p.Update(60, start.Add(8*time.Minute)), ShouldEqual, HeatingState_ON)
+		So(p.Update(60, start.Add(9*time.Minute)), ShouldEqual, HeatingState_OFF)
+		So(p.Update(60, start.Add(14*time.Minute)), ShouldEqual, HeatingState_OFF)
+		So(p.Update(60, start.Add(15*time.Minute)), ShouldEqual, HeatingState_ON)
+
+		on, switches := runProportioner(p, 60, start.Add(30*time.Minute), 4*time.Hour)
+		So(on, ShouldEqual, 144*time.Minute)
+		// Off and on again in every cycle.
+		So(switches, ShouldEqual, 31)
+	})
+
+	Convey("Fully off and on", t, func() {
+		p := NewTimeProportioner(15*time.Minute, 3*time.Minute, 3*time.Minute)
+		on, switches := runProportioner(p, 0, start, time.Hour)
+		So(on, ShouldEqual, 0)
+		So(switches, ShouldEqual, 0)
+
+		on, switches = runProportioner(p, 100, start.Add(time.Hour), time.Hour)
+		So(on, ShouldEqual, time.Hour)
+		So(switches, ShouldEqual, 1)
+	})
+
+	Convey("Too short to switch for", t, func() {
+		p := NewTimeProportioner(15*time.Minute, 3*time.Minute, 3*time.Minute)
+		// 1.5 minutes on is rounded down to off.
+		on, _ := runProportioner(p, 10, start, time.Hour)
+		So(on, ShouldEqual, 0)
+
+		// 1.5 minutes off is rounded up to on.
+		on, switches := runProportioner(p, 90, start.Add(time.Hour), time.Hour)
+		So(on, ShouldEqual, time.Hour)
+		So(switches, ShouldEqual, 1)
+	})
+
+	Convey("Respects minimum times when the output changes", t, func() {
+		p := NewTimeProportioner(15*time.Minute, 3*time.Minute, 3*time.Minute)
+		So(p.Update(100, start), ShouldEqual, HeatingState_ON)
+		// Dropping to zero straight away still keeps it on for the minimum.
+		So(p.Update(0, start.Add(time.Minute)), ShouldEqual, HeatingState_ON)
+		So(p.Update(0, start.Add(3*time.Minute)), ShouldEqual, HeatingState_OFF)
+		// And jumping back up keeps it off for the minimum.
+		So(p.Update(100, start.Add(4*time.Minute)), ShouldEqual, HeatingState_OFF)
+		So(p.Update(100, start.Add(6*time.Minute)), ShouldEqual, HeatingState_ON)
+	})
+
+	Convey("Resumes after a gap in updates", t, func() {
+		p := NewTimeProportioner(15*time.Minute, 3*time.Minute, 3*time.Minute)
+		So(p.Update(60, start), ShouldEqual, HeatingState_ON)
+		// Cycles stay aligned to the first update.
+		So(p.Update(60, start.Add(10*time.Hour+10*time.Minute)), ShouldEqual, HeatingState_OFF)
+		So(p.Update(60, start.Add(10*time.Hour+15*time.Minute)), ShouldEqual, HeatingState_ON)
+	})
+
+	Convey("Reset starts a new cycle", t, func() {
+		p := NewTimeProportioner(15*time.Minute, 3*time.Minute, 3*time.Minute)
+		So(p.Update(100, start), ShouldEqual, HeatingState_ON)
+		p.Reset()
+		So(p.State(), ShouldEqual, HeatingState_OFF)
+		// Neither the old cycle nor the minimum on time hold it back.
+		So(p.Update(20, start.Add(time.Minute)), ShouldEqual, HeatingState_ON)
+		So(p.Update(20, start.Add(4*time.Minute)), ShouldEqual, HeatingState_OFF)
+	})
+
+	Convey("Defaults", t, func() {
+		p := NewTimeProportioner(0, 0, 0)
+		So(p.period, ShouldEqual, defaultCyclePeriod)
+		So(p.minOn, ShouldEqual, defaultMinOnTime)
+		So(p.minOff, ShouldEqual, defaultMinOffTime)
+		So(p.State(), ShouldEqual, HeatingState_OFF)
+	})
+}
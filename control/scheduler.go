@@ -0,0 +1,354 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/hatstand/shinywaffle"
+	"github.com/hatstand/shinywaffle/radiator"
+	"go.uber.org/zap"
+)
+
+// transmitter is a sender which reports how long it waited for a clear
+// channel, as implemented by shinywaffle.CC1101. Only the time actually
+// spent transmitting counts against the duty cycle budget.
+type transmitter interface {
+	Transmit(packet []byte) (shinywaffle.TransmitResult, error)
+}
+
+// SchedulerOptions configures a TransmitScheduler. Zero values are replaced
+// by the defaults.
+type SchedulerOptions struct {
+	// Times each command is transmitted as radiators occasionally miss one.
+	Repeats int
+	// Delay between repeats of a command, plus up to Jitter extra at random
+	// so that repeats don't line up with other transmitters.
+	RepeatInterval time.Duration
+	Jitter         time.Duration
+	// Fraction of BudgetWindow the radio may spend transmitting. The 868MHz
+	// band the radiators use is limited to a 1% duty cycle by ETSI EN 300 220.
+	DutyCycle    float64
+	BudgetWindow time.Duration
+	// Maximum number of radiators with pending commands.
+	MaxQueue int
+}
+
+func DefaultSchedulerOptions() SchedulerOptions {
+	return SchedulerOptions{
+		Repeats:        3,
+		RepeatInterval: 2 * time.Second,
+		Jitter:         time.Second,
+		DutyCycle:      0.01,
+		BudgetWindow:   time.Hour,
+		MaxQueue:       64,
+	}
+}
+
+func (o SchedulerOptions) withDefaults() SchedulerOptions {
+	d := DefaultSchedulerOptions()
+	if o.Repeats <= 0 {
+		o.Repeats = d.Repeats
+	}
+	if o.RepeatInterval <= 0 {
+		o.RepeatInterval = d.RepeatInterval
+	}
+	if o.Jitter < 0 {
+		o.Jitter = 0
+	}
+	if o.DutyCycle <= 0 || o.DutyCycle > 1 {
+		o.DutyCycle = d.DutyCycle
+	}
+	if o.BudgetWindow <= 0 {
+		o.BudgetWindow = d.BudgetWindow
+	}
+	if o.MaxQueue <= 0 {
+		o.MaxQueue = d.MaxQueue
+	}
+	return o
+}
+
+// TransmitStats are counters describing the scheduler's queue.
+type TransmitStats struct {
+	// Number of radiators with commands waiting to be sent.
+	QueueDepth int
+	// Packets transmitted.
+	Sent uint64
+	// Packets the radio failed to send.
+	Failed uint64
+	// Commands replaced by a newer command for the same radiator.
+	Coalesced uint64
+	// Commands rejected because the queue was full or abandoned because
+	// the duty cycle budget ran out before all of their repeats were sent.
+	Dropped uint64
+	// Time spent transmitting within the current budget window.
+	Airtime time.Duration
+}
+
+type pendingCommand struct {
+	addr    radiator.Address
+	packet  []byte
+	sent    int
+	next    time.Time
+	version int
+}
+
+type transmission struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// TransmitScheduler is a RadiatorController queueing commands for a radio.
+// Only the latest command for each radiator is kept and transmissions are
+// limited to a duty cycle budget.
+type TransmitScheduler struct {
+	sender  radiator.Sender
+	options SchedulerOptions
+	logger  *zap.SugaredLogger
+
+	lock    sync.Mutex
+	pending map[radiator.Address]*pendingCommand
+	// Recent transmissions within the budget window, oldest first.
+	history []transmission
+	// Duration of the last transmission, used to estimate the next.
+	lastAirtime time.Duration
+	stats       TransmitStats
+	wake        chan struct{}
+}
+
+func NewTransmitScheduler(sender radiator.Sender, options SchedulerOptions, logger *zap.SugaredLogger) *TransmitScheduler {
+	return &TransmitScheduler{
+		sender:  sender,
+		options: options.withDefaults(),
+		logger:  logger,
+		pending: make(map[radiator.Address]*pendingCommand),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Set queues setting to be sent to the radiator at addr, replacing any
+// command still pending for it.
+func (s *TransmitScheduler) Set(addr radiator.Address, setting radiator.Setting) error {
+	packet, err := encodeSetting(addr, setting)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if cmd, ok := s.pending[addr]; ok {
+		cmd.packet = packet
+		cmd.sent = 0
+		cmd.version++
+		s.stats.Coalesced++
+		if now := time.Now(); cmd.next.After(now) {
+			cmd.next = now
+		}
+	} else {
+		if len(s.pending) >= s.options.MaxQueue {
+			s.stats.Dropped++
+			return fmt.Errorf("transmit queue full, dropping command for %v", addr)
+		}
+		s.pending[addr] = &pendingCommand{
+			addr:   addr,
+			packet: packet,
+			next:   time.Now(),
+		}
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Stats returns the current queue counters.
+func (s *TransmitScheduler) Stats() TransmitStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.expireHistory(time.Now())
+	stats := s.stats
+	stats.QueueDepth = len(s.pending)
+	stats.Airtime = s.airtime()
+	return stats
+}
+
+// Run transmits queued commands until ctx is cancelled.
+func (s *TransmitScheduler) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		wait, cmd := s.nextCommand()
+		if cmd != nil {
+			s.transmit(cmd)
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer.Reset(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-timeout:
+		}
+	}
+}
+
+// nextCommand returns a command due to be sent now if the budget allows, or
+// otherwise how long to wait before checking again. A zero wait means the
+// queue is empty.
+func (s *TransmitScheduler) nextCommand() (time.Duration, *pendingCommand) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	var due *pendingCommand
+	for _, cmd := range s.pending {
+		if due == nil || cmd.next.Before(due.next) {
+			due = cmd
+		}
+	}
+	if due == nil {
+		return 0, nil
+	}
+	if due.next.After(now) {
+		return due.next.Sub(now), nil
+	}
+
+	s.expireHistory(now)
+	if len(s.history) == 0 || s.airtime()+s.lastAirtime <= s.budget() {
+		return 0, due
+	}
+	if due.sent > 0 {
+		// It's better to leave the budget for other radiators' first
+		// transmissions than to repeat this one.
+		s.logger.Warnf("Duty cycle budget exhausted, dropping repeats for %v", due.addr)
+		delete(s.pending, due.addr)
+		s.stats.Dropped++
+		return time.Nanosecond, nil
+	}
+	due.next = s.budgetAvailable()
+	return due.next.Sub(now), nil
+}
+
+func (s *TransmitScheduler) transmit(cmd *pendingCommand) {
+	s.lock.Lock()
+	packet, version := cmd.packet, cmd.version
+	s.lock.Unlock()
+
+	if err := s.send(packet); err != nil {
+		// The attempt still counts as one of the command's repeats.
+		s.logger.Warnf("Failed to send packet to %v: %v", cmd.addr, err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if cmd.version != version {
+		// Replaced while sending so the new command starts from scratch.
+		return
+	}
+	cmd.sent++
+	if cmd.sent >= s.options.Repeats {
+		delete(s.pending, cmd.addr)
+		return
+	}
+	cmd.next = time.Now().Add(s.options.RepeatInterval)
+	if s.options.Jitter > 0 {
+		cmd.next = cmd.next.Add(time.Duration(rand.Int63n(int64(s.options.Jitter))))
+	}
+}
+
+// send transmits packet straight away, counting the time spent transmitting
+// against the budget if it was sent.
+func (s *TransmitScheduler) send(packet []byte) error {
+	start := time.Now()
+	var busy time.Duration
+	var err error
+	if t, ok := s.sender.(transmitter); ok {
+		var result shinywaffle.TransmitResult
+		result, err = t.Transmit(packet)
+		busy = result.BusyTime
+	} else {
+		err = s.sender.Send(packet)
+	}
+	airtime := time.Since(start) - busy
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err != nil {
+		s.stats.Failed++
+		return err
+	}
+	s.history = append(s.history, transmission{start.Add(busy), airtime})
+	s.lastAirtime = airtime
+	s.stats.Sent++
+	return nil
+}
+
+// Sender returns a sender which transmits packets straight away rather than
+// queueing them, e.g. for the bursts of pairing packets, while still
+// counting them against the duty cycle budget.
+func (s *TransmitScheduler) Sender() radiator.Sender {
+	return directSender{s}
+}
+
+type directSender struct {
+	s *TransmitScheduler
+}
+
+func (d directSender) Send(packet []byte) error {
+	return d.s.send(packet)
+}
+
+func (s *TransmitScheduler) budget() time.Duration {
+	return time.Duration(float64(s.options.BudgetWindow) * s.options.DutyCycle)
+}
+
+func (s *TransmitScheduler) airtime() time.Duration {
+	var total time.Duration
+	for _, t := range s.history {
+		total += t.duration
+	}
+	return total
+}
+
+func (s *TransmitScheduler) expireHistory(now time.Time) {
+	i := 0
+	for i < len(s.history) && now.Sub(s.history[i].at) >= s.options.BudgetWindow {
+		i++
+	}
+	s.history = s.history[i:]
+}
+
+// budgetAvailable returns when enough old transmissions will have left the
+// window to allow another.
+func (s *TransmitScheduler) budgetAvailable() time.Time {
+	used := s.airtime()
+	for _, t := range s.history {
+		used -= t.duration
+		if used+s.lastAirtime <= s.budget() {
+			return t.at.Add(s.options.BudgetWindow)
+		}
+	}
+	return time.Now()
+}
+
+func encodeSetting(addr radiator.Address, setting radiator.Setting) ([]byte, error) {
+	packet, err := radiator.NewSettingPacket(addr, setting).Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode packet for %v: %w", addr, err)
+	}
+	return packet, nil
+}
@@ -0,0 +1,199 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle"
+	"github.com/hatstand/shinywaffle/radiator"
+	"github.com/hatstand/shinywaffle/radiator/radiatortest"
+	"go.uber.org/zap"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type sentPacket struct {
+	at     time.Time
+	packet *radiator.Packet
+}
+
+// busySender waits for a busy channel before sending each packet.
+type busySender struct {
+	*radiatortest.Sender
+	busy time.Duration
+}
+
+func (b busySender) Transmit(packet []byte) (shinywaffle.TransmitResult, error) {
+	time.Sleep(b.busy)
+	return shinywaffle.TransmitResult{Attempts: 2, BusyTime: b.busy}, b.Send(packet)
+}
+
+// WithScheduler runs f against a running scheduler sending with a fake sender.
+func WithScheduler(options SchedulerOptions, f func(sender *radiatortest.Sender, s *TransmitScheduler)) func() {
+	return func() {
+		sender := &radiatortest.Sender{Airtime: time.Millisecond}
+		s := NewTransmitScheduler(sender, options, zap.NewNop().Sugar())
+		f(sender, s)
+	}
+}
+
+func runScheduler(s *TransmitScheduler) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// waitForSent waits for at least n packets to be sent and decodes them.
+func waitForSent(sender *radiatortest.Sender, n int) []sentPacket {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && len(sender.Sent()) < n {
+		time.Sleep(time.Millisecond)
+	}
+	var sent []sentPacket
+	for _, s := range sender.Sent() {
+		p, err := radiator.Unmarshal(s.Packet)
+		So(err, ShouldBeNil)
+		sent = append(sent, sentPacket{s.At, p})
+	}
+	return sent
+}
+
+func TestTransmitScheduler(t *testing.T) {
+	options := SchedulerOptions{
+		Repeats:        3,
+		RepeatInterval: 10 * time.Millisecond,
+		Jitter:         5 * time.Millisecond,
+		DutyCycle:      1,
+		BudgetWindow:   time.Second,
+	}
+	day := radiator.Setting{Mode: radiator.Day, Day: 21, Night: 18, Defrost: 7}
+	night := radiator.Setting{Mode: radiator.Night, Day: 21, Night: 18, Defrost: 7}
+
+	Convey("Repeats are spaced out", t, WithScheduler(options, func(sender *radiatortest.Sender, s *TransmitScheduler) {
+		So(s.Set(0x1234, day), ShouldBeNil)
+		defer runScheduler(s)()
+
+		sent := waitForSent(sender, 3)
+		So(sent, ShouldHaveLength, 3)
+		for i, p := range sent {
+			So(p.packet.Address, ShouldEqual, 0x1234)
+			So(p.packet.Setting, ShouldResemble, day)
+			if i > 0 {
+				gap := p.at.Sub(sent[i-1].at)
+				So(gap, ShouldBeGreaterThanOrEqualTo, options.RepeatInterval)
+			}
+		}
+		stats := s.Stats()
+		So(stats.Sent, ShouldEqual, 3)
+		So(stats.QueueDepth, ShouldEqual, 0)
+	}))
+
+	Convey("Commands for the same radiator are coalesced", t, WithScheduler(options, func(sender *radiatortest.Sender, s *TransmitScheduler) {
+		So(s.Set(0x1234, day), ShouldBeNil)
+		So(s.Set(0x5678, day), ShouldBeNil)
+		So(s.Set(0x1234, night), ShouldBeNil)
+		So(s.Stats().QueueDepth, ShouldEqual, 2)
+		So(s.Stats().Coalesced, ShouldEqual, 1)
+		defer runScheduler(s)()
+
+		sent := waitForSent(sender, 6)
+		So(sent, ShouldHaveLength, 6)
+		for _, p := range sent {
+			if p.packet.Address == 0x1234 {
+				So(p.packet.Setting, ShouldResemble, night)
+			}
+		}
+	}))
+
+	Convey("Full queue drops commands", t, WithScheduler(SchedulerOptions{MaxQueue: 1}, func(sender *radiatortest.Sender, s *TransmitScheduler) {
+		So(s.Set(0x1234, day), ShouldBeNil)
+		So(s.Set(0x5678, day), ShouldNotBeNil)
+		// Replacing a pending command is still allowed.
+		So(s.Set(0x1234, night), ShouldBeNil)
+		So(s.Stats().Dropped, ShouldEqual, 1)
+	}))
+
+	Convey("Invalid setting", t, WithScheduler(options, func(sender *radiatortest.Sender, s *TransmitScheduler) {
+		So(s.Set(0x1234, radiator.Setting{Mode: radiator.Day}), ShouldNotBeNil)
+		So(s.Stats().QueueDepth, ShouldEqual, 0)
+	}))
+
+	Convey("Waiting for the channel isn't airtime", t, func() {
+		sender := busySender{&radiatortest.Sender{Airtime: time.Millisecond}, 50 * time.Millisecond}
+		s := NewTransmitScheduler(sender, SchedulerOptions{Repeats: 1}, zap.NewNop().Sugar())
+		So(s.Set(0x1234, day), ShouldBeNil)
+		defer runScheduler(s)()
+
+		So(waitForSent(sender.Sender, 1), ShouldHaveLength, 1)
+		time.Sleep(10 * time.Millisecond)
+		stats := s.Stats()
+		So(stats.Sent, ShouldEqual, 1)
+		So(stats.Airtime, ShouldBeLessThan, 25*time.Millisecond)
+	})
+
+	Convey("Failed sends use no budget", t, func() {
+		sender := &radiatortest.Sender{Err: errors.New("channel busy")}
+		s := NewTransmitScheduler(sender, options, zap.NewNop().Sugar())
+		So(s.Set(0x1234, day), ShouldBeNil)
+		defer runScheduler(s)()
+
+		deadline := time.Now().Add(time.Second)
+		for s.Stats().QueueDepth > 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		stats := s.Stats()
+		So(stats.QueueDepth, ShouldEqual, 0)
+		So(stats.Failed, ShouldEqual, 3)
+		So(stats.Sent, ShouldEqual, 0)
+		So(stats.Airtime, ShouldEqual, 0)
+	})
+
+	Convey("Direct sends count against the budget", t, WithScheduler(options, func(sender *radiatortest.Sender, s *TransmitScheduler) {
+		packet, err := radiator.NewPairingPacket(0x1234, day).Marshal()
+		So(err, ShouldBeNil)
+		So(s.Sender().Send(packet), ShouldBeNil)
+		So(sender.Sent(), ShouldHaveLength, 1)
+		stats := s.Stats()
+		So(stats.Sent, ShouldEqual, 1)
+		So(stats.Airtime, ShouldBeGreaterThanOrEqualTo, time.Millisecond)
+	}))
+
+	Convey("Duty cycle budget", t, func() {
+		sender := &radiatortest.Sender{Airtime: 20 * time.Millisecond}
+		// 56ms of airtime every 400ms.
+		s := NewTransmitScheduler(sender, SchedulerOptions{
+			Repeats:        3,
+			RepeatInterval: time.Millisecond,
+			DutyCycle:      0.14,
+			BudgetWindow:   400 * time.Millisecond,
+		}, zap.NewNop().Sugar())
+		So(s.Set(0x1234, day), ShouldBeNil)
+		defer runScheduler(s)()
+
+		// The third repeat would exceed the budget so is dropped.
+		So(waitForSent(sender, 2), ShouldHaveLength, 2)
+		time.Sleep(20 * time.Millisecond)
+		stats := s.Stats()
+		So(stats.Sent, ShouldEqual, 2)
+		So(stats.Dropped, ShouldEqual, 1)
+		So(stats.QueueDepth, ShouldEqual, 0)
+		So(stats.Airtime, ShouldBeGreaterThanOrEqualTo, 40*time.Millisecond)
+
+		// New commands wait for the budget rather than being dropped.
+		start := time.Now()
+		So(s.Set(0x5678, day), ShouldBeNil)
+		sent := waitForSent(sender, 3)
+		So(sent, ShouldHaveLength, 3)
+		So(sent[2].packet.Address, ShouldEqual, 0x5678)
+		So(sent[2].at.Sub(start), ShouldBeGreaterThan, 200*time.Millisecond)
+	})
+}
@@ -4,14 +4,12 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"sort"
+	"sync"
 	"time"
 
-	"github.com/felixge/pidctrl"
-	"github.com/golang/protobuf/proto"
-	"github.com/hatstand/shinywaffle/calendar"
-	"github.com/hatstand/shinywaffle/wirelesstag"
+	"github.com/hatstand/shinywaffle/pairing"
+	"github.com/hatstand/shinywaffle/radiator"
 	"go.uber.org/zap"
 )
 
@@ -19,69 +17,212 @@ var client = flag.String("client", "", "OAuth client id")
 var secret = flag.String("secret", "", "OAuth client secret")
 
 const (
-	kP = 1
-	kI = .5
-	kD = .0
+	defaultRefreshInterval = 15 * time.Minute
+	defaultOverrideHold    = 2 * time.Hour
+
+	// Readings older than this aren't trusted to control a room.
+	staleReadingAge = 30 * time.Minute
+	// Integration starts afresh rather than spanning longer gaps between
+	// updates, e.g. while the sensors can't be read.
+	maxUpdateGap = 10 * time.Minute
 )
 
 type Room struct {
-	Pid      *pidctrl.PIDController
+	Pid *PID
+	// Turns the PID output into a duty cycle.
+	pwm      *TimeProportioner
 	config   *Zone
 	LastTemp float64
+	// When LastTemp was measured.
+	readAt time.Time
+	// When the PID controller was last updated, or zero if integration is
+	// to start afresh.
+	lastUpdate time.Time
+	// Whether the room was scheduled to be heated at the last update.
+	scheduled bool
+	// State the room was last put in.
+	state HeatingState
+	// Learnt from the room's history to predict when to start heating.
+	model  ThermalModel
+	sample thermalSample
+	// When the room is predicted to have to start heating for its next
+	// scheduled period, or zero if unknown.
+	predictedStart time.Time
+	// Start of the scheduled period the room is being heated ahead of, or
+	// zero if it isn't.
+	preheatingFor time.Time
+	// Setting chosen with a wall remote, held instead of the schedule.
+	override *override
+	// Tuning of the PID gains, which switches the radiators instead of the
+	// PID controller while running.
+	autotune *Autotuner
+	// Whether tuning switched the radiators at the last tick.
+	tuning bool
+	// Saved by Tick for GetZoneStatus, guarded by the controller's lock.
+	status roomStatus
+}
+
+// offTemperature is the setpoint of the radiators' own thermostats while the
+// controller has them off, which only stops the room freezing.
+const offTemperature = 10
+
+// radiatorSetting returns the setting sent to room's radiators to put them in
+// state. While on, their own thermostats are set to the room's target so that
+// a missed command to switch off can't overheat the room.
+func radiatorSetting(room *Room, state HeatingState) radiator.Setting {
+	target := float32(room.config.GetTargetTemperature())
+	if target < radiator.MinTemperature {
+		target = radiator.MinTemperature
+	} else if target > radiator.MaxTemperature {
+		target = radiator.MaxTemperature
+	}
+	setting := radiator.Setting{
+		Mode:    radiator.Defrost,
+		Day:     target,
+		Night:   target,
+		Defrost: offTemperature,
+	}
+	if state == HeatingState_ON {
+		setting.Mode = radiator.Day
+	}
+	return setting
 }
 
 type RadiatorController interface {
-	TurnOn([]byte)
-	TurnOff([]byte)
+	// Set commands the radiator at the given address into any of its modes
+	// with explicit setpoints.
+	Set(addr radiator.Address, setting radiator.Setting) error
+}
+
+// commandedState is the setting last sent to a radiator.
+type commandedState struct {
+	setting radiator.Setting
+	sent    time.Time
 }
 
 type Controller struct {
-	Config          map[string]*Room
+	Config map[string]*Room
+	// Where the config was loaded from, so that it can be updated.
+	configPath string
+	config     *Config
+	// Pairs new radiators, or nil if there is no radio.
+	pairer          *pairing.Pairer
 	controller      RadiatorController
-	lastUpdated     time.Time
-	calendarService *calendar.CalendarScheduleService
-	logger          *zap.SugaredLogger
+	refreshInterval time.Duration
+	overrideHold    time.Duration
+	// Whether overrides end at the next schedule change.
+	overrideUntilScheduleChange bool
+	// Guards commanded and the rooms' overrides, which are updated by
+	// both the control loop and received packets.
+	lock sync.Mutex
+	// Last setting sent to each radiator, keyed by address.
+	commanded map[radiator.Address]commandedState
+	// Every configured radiator.
+	registry *radiator.Registry
+	schedule ScheduleSource
+	sensors  SensorSource
+	weather  WeatherSource
+	now      func() time.Time
+	logger   *zap.SugaredLogger
+	// Last fetched outside temperature, and when.
+	outside   float64
+	outsideAt time.Time
+	// Whether rooms are heated ahead of their scheduled periods, and at most
+	// how far ahead.
+	optimalStart bool
+	maxPreheat   time.Duration
 }
 
 func NewController(
 	path string,
 	controller RadiatorController,
-	calendarService *calendar.CalendarScheduleService,
+	schedule ScheduleSource,
+	weather WeatherSource,
 	logger *zap.SugaredLogger,
 ) (*Controller, error) {
-	configText, err := ioutil.ReadFile(path)
+	config, err := LoadConfig(path)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to read config file: %s %v", path, err)
+		return nil, err
+	}
+	c, err := NewControllerWithSources(config, controller, Sources{
+		Sensors:  WirelessTags{},
+		Schedule: schedule,
+		Weather:  weather,
+		Now:      time.Now,
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+	c.configPath = path
+	return c, nil
+}
+
+// NewControllerWithSources returns a controller for config which isn't saved
+// anywhere, with its readings, schedules and time from sources.
+func NewControllerWithSources(
+	config *Config,
+	controller RadiatorController,
+	sources Sources,
+	logger *zap.SugaredLogger,
+) (*Controller, error) {
+	if err := ValidateConfig(config); err != nil {
+		return nil, err
 	}
-	var config Config
-	if err := proto.UnmarshalText(string(configText), &config); err != nil {
-		return nil, fmt.Errorf("Failed to parse config file: %v", err)
+	registry, err := NewRegistry(config)
+	if err != nil {
+		return nil, err
 	}
 
 	m := make(map[string]*Room)
 	for _, room := range config.Zone {
 		logger.Infof("Configuring controller for: %s", room.GetName())
-		ctrl := pidctrl.NewPIDController(kP, kI, kD)
-		ctrl.SetOutputLimits(0, 100)
 		m[room.GetName()] = &Room{
-			Pid:    ctrl,
+			Pid: newZonePID(room.GetPid()),
+			pwm: NewTimeProportioner(
+				time.Duration(config.GetCyclePeriodMinutes())*time.Minute,
+				time.Duration(config.GetMinOnMinutes())*time.Minute,
+				time.Duration(config.GetMinOffMinutes())*time.Minute,
+			),
 			config: room,
 		}
 	}
+	refreshInterval := defaultRefreshInterval
+	if minutes := config.GetRefreshIntervalMinutes(); minutes > 0 {
+		refreshInterval = time.Duration(minutes) * time.Minute
+	}
+	overrideHold := defaultOverrideHold
+	if minutes := config.GetOverrideHoldMinutes(); minutes > 0 {
+		overrideHold = time.Duration(minutes) * time.Minute
+	}
+	maxPreheat := defaultMaxPreheat
+	if minutes := config.GetMaxPreheatMinutes(); minutes > 0 {
+		maxPreheat = time.Duration(minutes) * time.Minute
+	}
 	return &Controller{
-		Config:          m,
-		controller:      controller,
-		calendarService: calendarService,
-		logger:          logger,
+		Config:                      m,
+		config:                      config,
+		controller:                  controller,
+		refreshInterval:             refreshInterval,
+		overrideHold:                overrideHold,
+		overrideUntilScheduleChange: config.GetOverrideUntilScheduleChange(),
+		commanded:                   make(map[radiator.Address]commandedState),
+		registry:                    registry,
+		schedule:                    sources.Schedule,
+		sensors:                     sources.Sensors,
+		weather:                     sources.Weather,
+		now:                         sources.Now,
+		logger:                      logger,
+		optimalStart:                !config.GetDisableOptimalStart(),
+		maxPreheat:                  maxPreheat,
 	}, nil
 }
 
 func (c *Controller) checkSchedule(room *Room) (int32, error) {
-	on, err := c.calendarService.GetSchedule(room.config.CalendarId)
+	on, err := c.schedule.GetSchedule(room.config.CalendarId)
 	if err != nil {
 		return -1, fmt.Errorf("Failed to fetch schedule for room %s: %v", room.config.Name, err)
 	}
-	now := time.Now()
+	now := c.now()
 	for _, period := range on {
 		start, err := time.Parse(time.RFC3339, period.Start)
 		if err != nil {
@@ -102,58 +243,183 @@ func (c *Controller) checkSchedule(room *Room) (int32, error) {
 }
 
 func (c *Controller) GetNextState(room *Room) HeatingState {
+	now := c.now()
 	scheduledTemp, err := c.checkSchedule(room)
 	if err != nil {
 		c.logger.Infof("Failed to get schedule for room %s: %v", room.config.Name, err)
-		return HeatingState_OFF
+		scheduledTemp = -1
 	}
-	room.Pid.Set(float64(scheduledTemp))
-	value := room.Pid.UpdateDuration(room.LastTemp, time.Since(c.lastUpdated))
-	c.logger.Infof("Room: %s Temperature: %.1f Target: %d PID: %f\n", room.config.GetName(), room.LastTemp, scheduledTemp, value)
-	if value > 0.0 {
-		return HeatingState_ON
-	} else {
-		return HeatingState_OFF
+	room.predictedStart = time.Time{}
+	if scheduledTemp >= 0 || err != nil {
+		room.preheatingFor = time.Time{}
+	} else if start, scheduled, ok := c.predictStart(room, now); ok {
+		room.predictedStart = start
+		// Once started, preheating carries on even though the prediction
+		// moves later as the room warms.
+		if !now.Before(start) && !scheduled.Equal(room.preheatingFor) {
+			c.logger.Infof("Preheating %s for %v", room.config.GetName(), scheduled)
+			room.preheatingFor = scheduled
+		}
+	}
+	if !room.preheatingFor.IsZero() {
+		if now.After(room.preheatingFor) {
+			// The period it was for has been cancelled.
+			room.preheatingFor = time.Time{}
+		} else {
+			scheduledTemp = room.config.GetTargetTemperature()
+		}
+	}
+	return c.nextState(room, float64(scheduledTemp), now)
+}
+
+// nextState updates room's PID controller with its latest reading towards
+// target, which is negative if the room isn't scheduled to be heated, and
+// returns the state to put its radiators in.
+func (c *Controller) nextState(room *Room, target float64, now time.Time) HeatingState {
+	name := room.config.GetName()
+	if target < 0 {
+		// There is nothing to integrate towards, so start afresh when the
+		// room is next scheduled.
+		room.Pid.Reset()
+		room.lastUpdate = time.Time{}
+		room.scheduled = false
+		room.state = room.pwm.Update(0, now)
+		return room.state
+	}
+	if age := now.Sub(room.readAt); age > staleReadingAge {
+		// Integration is paused until there is a fresh reading.
+		c.logger.Warnf("Room: %s last reading is %v old", name, age.Round(time.Minute))
+		room.lastUpdate = time.Time{}
+		room.state = room.pwm.Update(0, now)
+		return room.state
+	}
+	if !room.preheatingFor.IsZero() && room.LastTemp < target {
+		// Heat flat out, as predicting when to start assumed, until the PID
+		// controller can take over at the target.
+		room.Pid.Reset()
+		room.lastUpdate = time.Time{}
+		room.scheduled = false
+		room.state = room.pwm.Update(100, now)
+		c.logger.Infof("Room: %s Temperature: %.1f Preheating to: %.1f State: %v\n", name, room.LastTemp, target, room.state)
+		return room.state
+	}
+
+	if !room.scheduled {
+		room.Pid.Set(target)
+	} else if target != room.Pid.Get() {
+		room.Pid.SetBumpless(target)
+	}
+	room.scheduled = true
+	var dt time.Duration
+	if !room.lastUpdate.IsZero() && now.Sub(room.lastUpdate) <= maxUpdateGap {
+		dt = now.Sub(room.lastUpdate)
 	}
+	room.lastUpdate = now
+	value := room.Pid.UpdateDuration(room.LastTemp, dt)
+	room.state = room.pwm.Update(value, now)
+	c.logger.Infof("Room: %s Temperature: %.1f Target: %.1f PID: %f State: %v\n", name, room.LastTemp, target, value, room.state)
+	return room.state
 }
 
-func (c *Controller) tick() {
-	tags, err := wirelesstag.GetTags()
+// Tick reads every zone's sensor and commands its radiators. It is run
+// every minute by ControlRadiators.
+func (c *Controller) Tick() {
+	readings, err := c.sensors.Readings()
 	if err != nil {
-		c.logger.Infof("Failed to fetch tag data: %v", err)
+		c.logger.Infof("Failed to read sensors: %v", err)
 		return
 	}
-	c.lastUpdated = time.Now()
-	for _, t := range tags {
-		room := c.Config[t.Name]
-		room.LastTemp = t.Temperature
-		if room != nil {
-			nextState := c.GetNextState(room)
-			switch nextState {
-			case HeatingState_OFF, HeatingState_UNKNOWN:
-				for _, r := range room.config.Radiator {
-					c.logger.Infof("Turning OFF %s %v", room.config.Name, r.GetAddress())
-					c.controller.TurnOff(r.GetAddress())
-				}
-			case HeatingState_ON:
-				for _, r := range room.config.Radiator {
-					c.logger.Info("Turning ON %s %v", room.config.Name, r.GetAddress())
-					c.controller.TurnOn(r.GetAddress())
-				}
+	now := c.now()
+	outside, haveOutside := c.outsideTemperature(now)
+	for _, r := range readings {
+		room := c.Config[r.Zone]
+		if room == nil {
+			c.logger.Warnf("No config for room: %s", r.Zone)
+			continue
+		}
+		room.LastTemp = r.Temperature
+		room.readAt = r.At
+		if room.readAt.IsZero() {
+			room.readAt = now
+		}
+		c.learn(room, outside, haveOutside, now)
+		nextState, tuning := c.autotuneState(room, now)
+		if !tuning {
+			nextState = c.GetNextState(room)
+		}
+		room.state = nextState
+		c.saveStatus(room, outside, haveOutside)
+		if !c.commandRoom(room, nextState, now) {
+			room.sample.spoilt = true
+		}
+	}
+}
+
+// commandRoom commands every radiator in room into state unless the room is
+// held by an override, and reports whether it did. While held, the rest of
+// the zone follows the radiator changed with its remote.
+func (c *Controller) commandRoom(room *Room, state HeatingState, now time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.holdOverride(room, now) {
+		o := room.override
+		c.logger.Infof("Holding %s in override until %v", room.config.GetName(), o.end)
+		for _, r := range c.registry.Zone(room.config.GetName()) {
+			if r.Address != o.address {
+				c.sendSetting(room, r.Address, o.setting, o.setting.Mode.String(), now)
 			}
-		} else {
-			c.logger.Warnf("No config for room: %s", t.Name)
 		}
+		return false
 	}
+	for _, r := range c.registry.Zone(room.config.GetName()) {
+		c.setRadiator(room, r.Address, state, now)
+	}
+	return true
+}
+
+// setRadiator commands the radiator at addr into state.
+func (c *Controller) setRadiator(room *Room, addr radiator.Address, state HeatingState, now time.Time) {
+	name := "OFF"
+	if state == HeatingState_ON {
+		name = "ON"
+	}
+	c.sendSetting(room, addr, radiatorSetting(room, state), name, now)
+}
+
+// sendSetting sends setting, described by name, to the radiator at addr if
+// it was last sent something else, or if it hasn't been sent anything for
+// the refresh interval in case it missed the earlier command.
+func (c *Controller) sendSetting(room *Room, addr radiator.Address, setting radiator.Setting, name string, now time.Time) {
+	last, ok := c.commanded[addr]
+	if ok && last.setting == setting && now.Sub(last.sent) < c.refreshInterval {
+		return
+	}
+	if ok && last.setting == setting {
+		c.logger.Infof("Refreshing %s %s %v", name, room.config.GetName(), addr)
+	} else {
+		c.logger.Infof("Turning %s %s %v", name, room.config.GetName(), addr)
+	}
+	if err := c.controller.Set(addr, setting); err != nil {
+		c.logger.Warnf("Failed to turn %s %s %v: %v", name, room.config.GetName(), addr, err)
+		// Try again on the next tick.
+		delete(c.commanded, addr)
+		return
+	}
+	c.commanded[addr] = commandedState{setting, now}
+}
+
+// Registry returns the configured radiators.
+func (c *Controller) Registry() *radiator.Registry {
+	return c.registry
 }
 
 func (c *Controller) ControlRadiators(ctx context.Context) {
 	ch := time.Tick(1 * time.Minute)
-	c.tick()
+	c.Tick()
 	for {
 		select {
 		case <-ch:
-			c.tick()
+			c.Tick()
 		case <-ctx.Done():
 			return
 		}
@@ -175,23 +441,48 @@ func (s *Controller) GetZones(ctx context.Context, req *GetZonesRequest) (*GetZo
 	return &ret, nil
 }
 
+// radiatorStatus reports when each of room's radiators was last heard.
+func (c *Controller) radiatorStatus(room *Room) []*RadiatorStatus {
+	var ret []*RadiatorStatus
+	for _, info := range c.registry.Zone(room.config.GetName()) {
+		status := &RadiatorStatus{
+			Address: info.Address.Bytes(),
+			Name:    info.Name,
+		}
+		if !info.LastSeen.IsZero() {
+			status.LastSeenTime = info.LastSeen.Unix()
+			status.LastRssi = int32(info.LastRSSI)
+		}
+		ret = append(ret, status)
+	}
+	return ret
+}
+
 func (s *Controller) GetZoneStatus(ctx context.Context, req *GetZoneStatusRequest) (*GetZoneStatusReply, error) {
 	for _, r := range s.Config {
 		if r.config.GetName() == req.GetName() {
+			s.lock.Lock()
+			status := r.status
+			s.lock.Unlock()
+			reply := &GetZoneStatusReply{
+				Name:               r.config.GetName(),
+				CurrentTemperature: float32(status.temperature),
+				State:              status.state,
+				Override:           s.overrideStatus(r),
+				Radiator:           s.radiatorStatus(r),
+			}
+			if !status.predictedStart.IsZero() {
+				reply.PredictedStartTime = status.predictedStart.Unix()
+			}
+			if status.haveHeatUpRate {
+				reply.HeatUpRate = float32(status.heatUpRate)
+			}
 			target, err := s.checkSchedule(r)
 			if err != nil {
-				return &GetZoneStatusReply{
-					Name:               r.config.GetName(),
-					CurrentTemperature: float32(r.LastTemp),
-					State:              s.GetNextState(r),
-				}, fmt.Errorf("Failed to get current target temp for request %+v: %v", req, err)
+				return reply, fmt.Errorf("Failed to get current target temp for request %+v: %v", req, err)
 			}
-			return &GetZoneStatusReply{
-				Name:               r.config.GetName(),
-				TargetTemperature:  float32(target),
-				CurrentTemperature: float32(r.LastTemp),
-				State:              s.GetNextState(r),
-			}, nil
+			reply.TargetTemperature = float32(target)
+			return reply, nil
 		}
 	}
 	return &GetZoneStatusReply{}, nil
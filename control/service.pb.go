@@ -42,17 +42,125 @@ func (x HeatingState) String() string {
 }
 func (HeatingState) EnumDescriptor() ([]byte, []int) { return fileDescriptor1, []int{0} }
 
+type AutotuneState int32
+
+const (
+	AutotuneState_AUTOTUNE_NONE    AutotuneState = 0
+	AutotuneState_AUTOTUNE_RUNNING AutotuneState = 1
+	AutotuneState_AUTOTUNE_DONE    AutotuneState = 2
+	AutotuneState_AUTOTUNE_FAILED  AutotuneState = 3
+)
+
+var AutotuneState_name = map[int32]string{
+	0: "AUTOTUNE_NONE",
+	1: "AUTOTUNE_RUNNING",
+	2: "AUTOTUNE_DONE",
+	3: "AUTOTUNE_FAILED",
+}
+var AutotuneState_value = map[string]int32{
+	"AUTOTUNE_NONE":    0,
+	"AUTOTUNE_RUNNING": 1,
+	"AUTOTUNE_DONE":    2,
+	"AUTOTUNE_FAILED":  3,
+}
+
+func (x AutotuneState) String() string {
+	return proto.EnumName(AutotuneState_name, int32(x))
+}
+func (AutotuneState) EnumDescriptor() ([]byte, []int) { return fileDescriptor1, []int{1} }
+
+// Tuning of a zone's PID controller.
+type PidConfig struct {
+	// Gains, with the integral and derivative gains per second.
+	Proportional float64 `protobuf:"fixed64,1,opt,name=proportional" json:"proportional,omitempty"`
+	Integral     float64 `protobuf:"fixed64,2,opt,name=integral" json:"integral,omitempty"`
+	Derivative   float64 `protobuf:"fixed64,3,opt,name=derivative" json:"derivative,omitempty"`
+	// Limits on the output, which is the percentage of each cycle to heat for.
+	// Defaults to 0-100.
+	OutputMin float64 `protobuf:"fixed64,4,opt,name=output_min,json=outputMin" json:"output_min,omitempty"`
+	OutputMax float64 `protobuf:"fixed64,5,opt,name=output_max,json=outputMax" json:"output_max,omitempty"`
+	// Limits on the integral term to prevent windup. Defaults to the output
+	// limits.
+	IntegralMin float64 `protobuf:"fixed64,6,opt,name=integral_min,json=integralMin" json:"integral_min,omitempty"`
+	IntegralMax float64 `protobuf:"fixed64,7,opt,name=integral_max,json=integralMax" json:"integral_max,omitempty"`
+	// Whether to also stop integrating while the output is saturated.
+	ConditionalIntegration bool `protobuf:"varint,8,opt,name=conditional_integration,json=conditionalIntegration" json:"conditional_integration,omitempty"`
+}
+
+func (m *PidConfig) Reset()                    { *m = PidConfig{} }
+func (m *PidConfig) String() string            { return proto.CompactTextString(m) }
+func (*PidConfig) ProtoMessage()               {}
+func (*PidConfig) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{0} }
+
+func (m *PidConfig) GetProportional() float64 {
+	if m != nil {
+		return m.Proportional
+	}
+	return 0
+}
+
+func (m *PidConfig) GetIntegral() float64 {
+	if m != nil {
+		return m.Integral
+	}
+	return 0
+}
+
+func (m *PidConfig) GetDerivative() float64 {
+	if m != nil {
+		return m.Derivative
+	}
+	return 0
+}
+
+func (m *PidConfig) GetOutputMin() float64 {
+	if m != nil {
+		return m.OutputMin
+	}
+	return 0
+}
+
+func (m *PidConfig) GetOutputMax() float64 {
+	if m != nil {
+		return m.OutputMax
+	}
+	return 0
+}
+
+func (m *PidConfig) GetIntegralMin() float64 {
+	if m != nil {
+		return m.IntegralMin
+	}
+	return 0
+}
+
+func (m *PidConfig) GetIntegralMax() float64 {
+	if m != nil {
+		return m.IntegralMax
+	}
+	return 0
+}
+
+func (m *PidConfig) GetConditionalIntegration() bool {
+	if m != nil {
+		return m.ConditionalIntegration
+	}
+	return false
+}
+
 type Zone struct {
 	Name              string      `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
 	Radiator          []*Radiator `protobuf:"bytes,2,rep,name=radiator" json:"radiator,omitempty"`
 	CalendarId        string      `protobuf:"bytes,4,opt,name=calendar_id,json=calendarId" json:"calendar_id,omitempty"`
 	TargetTemperature int32       `protobuf:"varint,5,opt,name=target_temperature,json=targetTemperature" json:"target_temperature,omitempty"`
+	// Defaults to gains of 1, 0.5 and 0.
+	Pid *PidConfig `protobuf:"bytes,6,opt,name=pid" json:"pid,omitempty"`
 }
 
 func (m *Zone) Reset()                    { *m = Zone{} }
 func (m *Zone) String() string            { return proto.CompactTextString(m) }
 func (*Zone) ProtoMessage()               {}
-func (*Zone) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{0} }
+func (*Zone) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{1} }
 
 func (m *Zone) GetName() string {
 	if m != nil {
@@ -82,13 +190,20 @@ func (m *Zone) GetTargetTemperature() int32 {
 	return 0
 }
 
+func (m *Zone) GetPid() *PidConfig {
+	if m != nil {
+		return m.Pid
+	}
+	return nil
+}
+
 type GetZonesRequest struct {
 }
 
 func (m *GetZonesRequest) Reset()                    { *m = GetZonesRequest{} }
 func (m *GetZonesRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetZonesRequest) ProtoMessage()               {}
-func (*GetZonesRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{1} }
+func (*GetZonesRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{2} }
 
 type GetZonesReply struct {
 	Zone []*Zone `protobuf:"bytes,1,rep,name=zone" json:"zone,omitempty"`
@@ -97,7 +212,7 @@ type GetZonesReply struct {
 func (m *GetZonesReply) Reset()                    { *m = GetZonesReply{} }
 func (m *GetZonesReply) String() string            { return proto.CompactTextString(m) }
 func (*GetZonesReply) ProtoMessage()               {}
-func (*GetZonesReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{2} }
+func (*GetZonesReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{3} }
 
 func (m *GetZonesReply) GetZone() []*Zone {
 	if m != nil {
@@ -113,7 +228,7 @@ type GetZoneStatusRequest struct {
 func (m *GetZoneStatusRequest) Reset()                    { *m = GetZoneStatusRequest{} }
 func (m *GetZoneStatusRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetZoneStatusRequest) ProtoMessage()               {}
-func (*GetZoneStatusRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{3} }
+func (*GetZoneStatusRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{4} }
 
 func (m *GetZoneStatusRequest) GetName() string {
 	if m != nil {
@@ -122,17 +237,95 @@ func (m *GetZoneStatusRequest) GetName() string {
 	return ""
 }
 
+// A setting chosen by someone with a radiator's wall remote, which the
+// controller holds instead of following the schedule.
+type Override struct {
+	// Radiator the setting was sent to.
+	Address            []byte  `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Mode               string  `protobuf:"bytes,2,opt,name=mode" json:"mode,omitempty"`
+	DayTemperature     float32 `protobuf:"fixed32,3,opt,name=day_temperature,json=dayTemperature" json:"day_temperature,omitempty"`
+	NightTemperature   float32 `protobuf:"fixed32,4,opt,name=night_temperature,json=nightTemperature" json:"night_temperature,omitempty"`
+	DefrostTemperature float32 `protobuf:"fixed32,5,opt,name=defrost_temperature,json=defrostTemperature" json:"defrost_temperature,omitempty"`
+	// Unix times the override was detected and when it expires.
+	StartTime int64 `protobuf:"varint,6,opt,name=start_time,json=startTime" json:"start_time,omitempty"`
+	EndTime   int64 `protobuf:"varint,7,opt,name=end_time,json=endTime" json:"end_time,omitempty"`
+}
+
+func (m *Override) Reset()                    { *m = Override{} }
+func (m *Override) String() string            { return proto.CompactTextString(m) }
+func (*Override) ProtoMessage()               {}
+func (*Override) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{5} }
+
+func (m *Override) GetAddress() []byte {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *Override) GetMode() string {
+	if m != nil {
+		return m.Mode
+	}
+	return ""
+}
+
+func (m *Override) GetDayTemperature() float32 {
+	if m != nil {
+		return m.DayTemperature
+	}
+	return 0
+}
+
+func (m *Override) GetNightTemperature() float32 {
+	if m != nil {
+		return m.NightTemperature
+	}
+	return 0
+}
+
+func (m *Override) GetDefrostTemperature() float32 {
+	if m != nil {
+		return m.DefrostTemperature
+	}
+	return 0
+}
+
+func (m *Override) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *Override) GetEndTime() int64 {
+	if m != nil {
+		return m.EndTime
+	}
+	return 0
+}
+
 type GetZoneStatusReply struct {
 	Name               string       `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
 	TargetTemperature  float32      `protobuf:"fixed32,2,opt,name=target_temperature,json=targetTemperature" json:"target_temperature,omitempty"`
 	CurrentTemperature float32      `protobuf:"fixed32,3,opt,name=current_temperature,json=currentTemperature" json:"current_temperature,omitempty"`
 	State              HeatingState `protobuf:"varint,4,opt,name=state,enum=control.HeatingState" json:"state,omitempty"`
+	// Set if the zone is held in a manually chosen setting.
+	Override *Override `protobuf:"bytes,6,opt,name=override" json:"override,omitempty"`
+	// When heating is predicted to have to start to reach the target
+	// temperature by the start of the next scheduled period, in seconds since
+	// the epoch. Unset until the zone's model has been learnt.
+	PredictedStartTime int64 `protobuf:"varint,7,opt,name=predicted_start_time,json=predictedStartTime" json:"predicted_start_time,omitempty"`
+	// Degrees per hour the zone is predicted to heat up by with its radiators
+	// on, at the current indoor and outdoor temperatures.
+	HeatUpRate float32           `protobuf:"fixed32,8,opt,name=heat_up_rate,json=heatUpRate" json:"heat_up_rate,omitempty"`
+	Radiator   []*RadiatorStatus `protobuf:"bytes,9,rep,name=radiator" json:"radiator,omitempty"`
 }
 
 func (m *GetZoneStatusReply) Reset()                    { *m = GetZoneStatusReply{} }
 func (m *GetZoneStatusReply) String() string            { return proto.CompactTextString(m) }
 func (*GetZoneStatusReply) ProtoMessage()               {}
-func (*GetZoneStatusReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{4} }
+func (*GetZoneStatusReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{6} }
 
 func (m *GetZoneStatusReply) GetName() string {
 	if m != nil {
@@ -162,13 +355,83 @@ func (m *GetZoneStatusReply) GetState() HeatingState {
 	return HeatingState_UNKNOWN
 }
 
+func (m *GetZoneStatusReply) GetOverride() *Override {
+	if m != nil {
+		return m.Override
+	}
+	return nil
+}
+
+func (m *GetZoneStatusReply) GetPredictedStartTime() int64 {
+	if m != nil {
+		return m.PredictedStartTime
+	}
+	return 0
+}
+
+func (m *GetZoneStatusReply) GetHeatUpRate() float32 {
+	if m != nil {
+		return m.HeatUpRate
+	}
+	return 0
+}
+
+func (m *GetZoneStatusReply) GetRadiator() []*RadiatorStatus {
+	if m != nil {
+		return m.Radiator
+	}
+	return nil
+}
+
+type RadiatorStatus struct {
+	Address []byte `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Name    string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	// Unix time a packet for the radiator was last received, and its signal
+	// strength in dBm. Unset if it has not been heard since startup.
+	LastSeenTime int64 `protobuf:"varint,3,opt,name=last_seen_time,json=lastSeenTime" json:"last_seen_time,omitempty"`
+	LastRssi     int32 `protobuf:"varint,4,opt,name=last_rssi,json=lastRssi" json:"last_rssi,omitempty"`
+}
+
+func (m *RadiatorStatus) Reset()                    { *m = RadiatorStatus{} }
+func (m *RadiatorStatus) String() string            { return proto.CompactTextString(m) }
+func (*RadiatorStatus) ProtoMessage()               {}
+func (*RadiatorStatus) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{7} }
+
+func (m *RadiatorStatus) GetAddress() []byte {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *RadiatorStatus) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *RadiatorStatus) GetLastSeenTime() int64 {
+	if m != nil {
+		return m.LastSeenTime
+	}
+	return 0
+}
+
+func (m *RadiatorStatus) GetLastRssi() int32 {
+	if m != nil {
+		return m.LastRssi
+	}
+	return 0
+}
+
 type SetZoneScheduleRequest struct {
 }
 
 func (m *SetZoneScheduleRequest) Reset()                    { *m = SetZoneScheduleRequest{} }
 func (m *SetZoneScheduleRequest) String() string            { return proto.CompactTextString(m) }
 func (*SetZoneScheduleRequest) ProtoMessage()               {}
-func (*SetZoneScheduleRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{5} }
+func (*SetZoneScheduleRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{8} }
 
 type SetZoneScheduleReply struct {
 }
@@ -176,16 +439,341 @@ type SetZoneScheduleReply struct {
 func (m *SetZoneScheduleReply) Reset()                    { *m = SetZoneScheduleReply{} }
 func (m *SetZoneScheduleReply) String() string            { return proto.CompactTextString(m) }
 func (*SetZoneScheduleReply) ProtoMessage()               {}
-func (*SetZoneScheduleReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{6} }
+func (*SetZoneScheduleReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{9} }
+
+type ClearOverrideRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *ClearOverrideRequest) Reset()                    { *m = ClearOverrideRequest{} }
+func (m *ClearOverrideRequest) String() string            { return proto.CompactTextString(m) }
+func (*ClearOverrideRequest) ProtoMessage()               {}
+func (*ClearOverrideRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{10} }
+
+func (m *ClearOverrideRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type ClearOverrideReply struct {
+}
+
+func (m *ClearOverrideReply) Reset()                    { *m = ClearOverrideReply{} }
+func (m *ClearOverrideReply) String() string            { return proto.CompactTextString(m) }
+func (*ClearOverrideReply) ProtoMessage()               {}
+func (*ClearOverrideReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{11} }
+
+type PairRadiatorRequest struct {
+	// Zone to add the radiator to.
+	Zone string `protobuf:"bytes,1,opt,name=zone" json:"zone,omitempty"`
+	// Address to pair the radiator as. A free address is allocated if unset.
+	Address []byte `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	// Initial setting of the radiator. Defaults to OFF with day, night and
+	// defrost temperatures of 20, 15 and 10.
+	Mode               string  `protobuf:"bytes,3,opt,name=mode" json:"mode,omitempty"`
+	DayTemperature     float32 `protobuf:"fixed32,4,opt,name=day_temperature,json=dayTemperature" json:"day_temperature,omitempty"`
+	NightTemperature   float32 `protobuf:"fixed32,5,opt,name=night_temperature,json=nightTemperature" json:"night_temperature,omitempty"`
+	DefrostTemperature float32 `protobuf:"fixed32,6,opt,name=defrost_temperature,json=defrostTemperature" json:"defrost_temperature,omitempty"`
+	// Only add the radiator to the config if a wall remote is heard using
+	// the new address while pairing.
+	RequireConfirmation bool `protobuf:"varint,7,opt,name=require_confirmation,json=requireConfirmation" json:"require_confirmation,omitempty"`
+	// Friendly name for the radiator.
+	Name string `protobuf:"bytes,8,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *PairRadiatorRequest) Reset()                    { *m = PairRadiatorRequest{} }
+func (m *PairRadiatorRequest) String() string            { return proto.CompactTextString(m) }
+func (*PairRadiatorRequest) ProtoMessage()               {}
+func (*PairRadiatorRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{12} }
+
+func (m *PairRadiatorRequest) GetZone() string {
+	if m != nil {
+		return m.Zone
+	}
+	return ""
+}
+
+func (m *PairRadiatorRequest) GetAddress() []byte {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *PairRadiatorRequest) GetMode() string {
+	if m != nil {
+		return m.Mode
+	}
+	return ""
+}
+
+func (m *PairRadiatorRequest) GetDayTemperature() float32 {
+	if m != nil {
+		return m.DayTemperature
+	}
+	return 0
+}
+
+func (m *PairRadiatorRequest) GetNightTemperature() float32 {
+	if m != nil {
+		return m.NightTemperature
+	}
+	return 0
+}
+
+func (m *PairRadiatorRequest) GetDefrostTemperature() float32 {
+	if m != nil {
+		return m.DefrostTemperature
+	}
+	return 0
+}
+
+func (m *PairRadiatorRequest) GetRequireConfirmation() bool {
+	if m != nil {
+		return m.RequireConfirmation
+	}
+	return false
+}
+
+func (m *PairRadiatorRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type PairRadiatorReply struct {
+	Address []byte `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// Whether traffic for the new address was heard while pairing.
+	Confirmed   bool  `protobuf:"varint,2,opt,name=confirmed" json:"confirmed,omitempty"`
+	PacketsSent int32 `protobuf:"varint,3,opt,name=packets_sent,json=packetsSent" json:"packets_sent,omitempty"`
+}
+
+func (m *PairRadiatorReply) Reset()                    { *m = PairRadiatorReply{} }
+func (m *PairRadiatorReply) String() string            { return proto.CompactTextString(m) }
+func (*PairRadiatorReply) ProtoMessage()               {}
+func (*PairRadiatorReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{13} }
+
+func (m *PairRadiatorReply) GetAddress() []byte {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *PairRadiatorReply) GetConfirmed() bool {
+	if m != nil {
+		return m.Confirmed
+	}
+	return false
+}
+
+func (m *PairRadiatorReply) GetPacketsSent() int32 {
+	if m != nil {
+		return m.PacketsSent
+	}
+	return 0
+}
+
+type StartAutotuneRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// Temperature to oscillate around. Defaults to the zone's target
+	// temperature.
+	Setpoint float32 `protobuf:"fixed32,2,opt,name=setpoint" json:"setpoint,omitempty"`
+	// Tuning fails if it takes longer than this. Defaults to 6.
+	MaxHours int32 `protobuf:"varint,3,opt,name=max_hours,json=maxHours" json:"max_hours,omitempty"`
+}
+
+func (m *StartAutotuneRequest) Reset()                    { *m = StartAutotuneRequest{} }
+func (m *StartAutotuneRequest) String() string            { return proto.CompactTextString(m) }
+func (*StartAutotuneRequest) ProtoMessage()               {}
+func (*StartAutotuneRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{14} }
+
+func (m *StartAutotuneRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *StartAutotuneRequest) GetSetpoint() float32 {
+	if m != nil {
+		return m.Setpoint
+	}
+	return 0
+}
+
+func (m *StartAutotuneRequest) GetMaxHours() int32 {
+	if m != nil {
+		return m.MaxHours
+	}
+	return 0
+}
+
+type StartAutotuneReply struct {
+}
+
+func (m *StartAutotuneReply) Reset()                    { *m = StartAutotuneReply{} }
+func (m *StartAutotuneReply) String() string            { return proto.CompactTextString(m) }
+func (*StartAutotuneReply) ProtoMessage()               {}
+func (*StartAutotuneReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{15} }
+
+type GetAutotuneRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *GetAutotuneRequest) Reset()                    { *m = GetAutotuneRequest{} }
+func (m *GetAutotuneRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetAutotuneRequest) ProtoMessage()               {}
+func (*GetAutotuneRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{16} }
+
+func (m *GetAutotuneRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type GetAutotuneReply struct {
+	State AutotuneState `protobuf:"varint,1,opt,name=state,enum=control.AutotuneState" json:"state,omitempty"`
+	// Unix time tuning started.
+	StartTime int64   `protobuf:"varint,2,opt,name=start_time,json=startTime" json:"start_time,omitempty"`
+	Setpoint  float32 `protobuf:"fixed32,3,opt,name=setpoint" json:"setpoint,omitempty"`
+	// Oscillations measured so far.
+	Cycles int32 `protobuf:"varint,4,opt,name=cycles" json:"cycles,omitempty"`
+	// Why tuning failed.
+	Error string `protobuf:"bytes,5,opt,name=error" json:"error,omitempty"`
+	// Measured from the oscillations once done.
+	UltimateGain          float64 `protobuf:"fixed64,6,opt,name=ultimate_gain,json=ultimateGain" json:"ultimate_gain,omitempty"`
+	UltimatePeriodSeconds float64 `protobuf:"fixed64,7,opt,name=ultimate_period_seconds,json=ultimatePeriodSeconds" json:"ultimate_period_seconds,omitempty"`
+	// Proposed gains.
+	ZieglerNichols *PidConfig `protobuf:"bytes,8,opt,name=ziegler_nichols,json=zieglerNichols" json:"ziegler_nichols,omitempty"`
+	TyreusLuyben   *PidConfig `protobuf:"bytes,9,opt,name=tyreus_luyben,json=tyreusLuyben" json:"tyreus_luyben,omitempty"`
+}
+
+func (m *GetAutotuneReply) Reset()                    { *m = GetAutotuneReply{} }
+func (m *GetAutotuneReply) String() string            { return proto.CompactTextString(m) }
+func (*GetAutotuneReply) ProtoMessage()               {}
+func (*GetAutotuneReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{17} }
+
+func (m *GetAutotuneReply) GetState() AutotuneState {
+	if m != nil {
+		return m.State
+	}
+	return AutotuneState_AUTOTUNE_NONE
+}
+
+func (m *GetAutotuneReply) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *GetAutotuneReply) GetSetpoint() float32 {
+	if m != nil {
+		return m.Setpoint
+	}
+	return 0
+}
+
+func (m *GetAutotuneReply) GetCycles() int32 {
+	if m != nil {
+		return m.Cycles
+	}
+	return 0
+}
+
+func (m *GetAutotuneReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *GetAutotuneReply) GetUltimateGain() float64 {
+	if m != nil {
+		return m.UltimateGain
+	}
+	return 0
+}
+
+func (m *GetAutotuneReply) GetUltimatePeriodSeconds() float64 {
+	if m != nil {
+		return m.UltimatePeriodSeconds
+	}
+	return 0
+}
+
+func (m *GetAutotuneReply) GetZieglerNichols() *PidConfig {
+	if m != nil {
+		return m.ZieglerNichols
+	}
+	return nil
+}
+
+func (m *GetAutotuneReply) GetTyreusLuyben() *PidConfig {
+	if m != nil {
+		return m.TyreusLuyben
+	}
+	return nil
+}
+
+type CancelAutotuneRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *CancelAutotuneRequest) Reset()                    { *m = CancelAutotuneRequest{} }
+func (m *CancelAutotuneRequest) String() string            { return proto.CompactTextString(m) }
+func (*CancelAutotuneRequest) ProtoMessage()               {}
+func (*CancelAutotuneRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{18} }
+
+func (m *CancelAutotuneRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type CancelAutotuneReply struct {
+}
+
+func (m *CancelAutotuneReply) Reset()                    { *m = CancelAutotuneReply{} }
+func (m *CancelAutotuneReply) String() string            { return proto.CompactTextString(m) }
+func (*CancelAutotuneReply) ProtoMessage()               {}
+func (*CancelAutotuneReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{19} }
 
 type Config struct {
 	Zone []*Zone `protobuf:"bytes,1,rep,name=zone" json:"zone,omitempty"`
+	// Radiators are only sent commands when their desired state changes, and
+	// otherwise resent it this often in case they missed it. Defaults to 15.
+	RefreshIntervalMinutes int32 `protobuf:"varint,2,opt,name=refresh_interval_minutes,json=refreshIntervalMinutes" json:"refresh_interval_minutes,omitempty"`
+	// How long a setting chosen with a wall remote is held before returning to
+	// the schedule. Defaults to 120.
+	OverrideHoldMinutes int32 `protobuf:"varint,3,opt,name=override_hold_minutes,json=overrideHoldMinutes" json:"override_hold_minutes,omitempty"`
+	// Whether overrides also end when the zone's schedule next changes.
+	OverrideUntilScheduleChange bool `protobuf:"varint,4,opt,name=override_until_schedule_change,json=overrideUntilScheduleChange" json:"override_until_schedule_change,omitempty"`
+	// Radiators are switched on for the percentage of each cycle given by the
+	// PID output. Defaults to 15.
+	CyclePeriodMinutes int32 `protobuf:"varint,5,opt,name=cycle_period_minutes,json=cyclePeriodMinutes" json:"cycle_period_minutes,omitempty"`
+	// Radiators are never switched on or off for less than these. Default to 3.
+	MinOnMinutes  int32 `protobuf:"varint,6,opt,name=min_on_minutes,json=minOnMinutes" json:"min_on_minutes,omitempty"`
+	MinOffMinutes int32 `protobuf:"varint,7,opt,name=min_off_minutes,json=minOffMinutes" json:"min_off_minutes,omitempty"`
+	// Zones are heated ahead of their scheduled periods so that they reach
+	// their target temperature as each period starts, as predicted by a model
+	// of each room learnt from its history. Preheating starts at most this
+	// early. Defaults to 180.
+	MaxPreheatMinutes int32 `protobuf:"varint,8,opt,name=max_preheat_minutes,json=maxPreheatMinutes" json:"max_preheat_minutes,omitempty"`
+	// Only heat zones once their scheduled periods have started.
+	DisableOptimalStart bool `protobuf:"varint,9,opt,name=disable_optimal_start,json=disableOptimalStart" json:"disable_optimal_start,omitempty"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
 func (m *Config) String() string            { return proto.CompactTextString(m) }
 func (*Config) ProtoMessage()               {}
-func (*Config) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{7} }
+func (*Config) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{20} }
 
 func (m *Config) GetZone() []*Zone {
 	if m != nil {
@@ -194,16 +782,86 @@ func (m *Config) GetZone() []*Zone {
 	return nil
 }
 
+func (m *Config) GetRefreshIntervalMinutes() int32 {
+	if m != nil {
+		return m.RefreshIntervalMinutes
+	}
+	return 0
+}
+
+func (m *Config) GetOverrideHoldMinutes() int32 {
+	if m != nil {
+		return m.OverrideHoldMinutes
+	}
+	return 0
+}
+
+func (m *Config) GetOverrideUntilScheduleChange() bool {
+	if m != nil {
+		return m.OverrideUntilScheduleChange
+	}
+	return false
+}
+
+func (m *Config) GetCyclePeriodMinutes() int32 {
+	if m != nil {
+		return m.CyclePeriodMinutes
+	}
+	return 0
+}
+
+func (m *Config) GetMinOnMinutes() int32 {
+	if m != nil {
+		return m.MinOnMinutes
+	}
+	return 0
+}
+
+func (m *Config) GetMinOffMinutes() int32 {
+	if m != nil {
+		return m.MinOffMinutes
+	}
+	return 0
+}
+
+func (m *Config) GetMaxPreheatMinutes() int32 {
+	if m != nil {
+		return m.MaxPreheatMinutes
+	}
+	return 0
+}
+
+func (m *Config) GetDisableOptimalStart() bool {
+	if m != nil {
+		return m.DisableOptimalStart
+	}
+	return false
+}
+
 func init() {
+	proto.RegisterType((*PidConfig)(nil), "control.PidConfig")
 	proto.RegisterType((*Zone)(nil), "control.Zone")
 	proto.RegisterType((*GetZonesRequest)(nil), "control.GetZonesRequest")
 	proto.RegisterType((*GetZonesReply)(nil), "control.GetZonesReply")
 	proto.RegisterType((*GetZoneStatusRequest)(nil), "control.GetZoneStatusRequest")
+	proto.RegisterType((*Override)(nil), "control.Override")
 	proto.RegisterType((*GetZoneStatusReply)(nil), "control.GetZoneStatusReply")
+	proto.RegisterType((*RadiatorStatus)(nil), "control.RadiatorStatus")
 	proto.RegisterType((*SetZoneScheduleRequest)(nil), "control.SetZoneScheduleRequest")
 	proto.RegisterType((*SetZoneScheduleReply)(nil), "control.SetZoneScheduleReply")
+	proto.RegisterType((*ClearOverrideRequest)(nil), "control.ClearOverrideRequest")
+	proto.RegisterType((*ClearOverrideReply)(nil), "control.ClearOverrideReply")
+	proto.RegisterType((*PairRadiatorRequest)(nil), "control.PairRadiatorRequest")
+	proto.RegisterType((*PairRadiatorReply)(nil), "control.PairRadiatorReply")
+	proto.RegisterType((*StartAutotuneRequest)(nil), "control.StartAutotuneRequest")
+	proto.RegisterType((*StartAutotuneReply)(nil), "control.StartAutotuneReply")
+	proto.RegisterType((*GetAutotuneRequest)(nil), "control.GetAutotuneRequest")
+	proto.RegisterType((*GetAutotuneReply)(nil), "control.GetAutotuneReply")
+	proto.RegisterType((*CancelAutotuneRequest)(nil), "control.CancelAutotuneRequest")
+	proto.RegisterType((*CancelAutotuneReply)(nil), "control.CancelAutotuneReply")
 	proto.RegisterType((*Config)(nil), "control.Config")
 	proto.RegisterEnum("control.HeatingState", HeatingState_name, HeatingState_value)
+	proto.RegisterEnum("control.AutotuneState", AutotuneState_name, AutotuneState_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -220,6 +878,19 @@ type HeatingControlServiceClient interface {
 	GetZones(ctx context.Context, in *GetZonesRequest, opts ...grpc.CallOption) (*GetZonesReply, error)
 	GetZoneStatus(ctx context.Context, in *GetZoneStatusRequest, opts ...grpc.CallOption) (*GetZoneStatusReply, error)
 	SetZoneSchedule(ctx context.Context, in *SetZoneScheduleRequest, opts ...grpc.CallOption) (*SetZoneScheduleReply, error)
+	// Returns the zone to following its schedule.
+	ClearOverride(ctx context.Context, in *ClearOverrideRequest, opts ...grpc.CallOption) (*ClearOverrideReply, error)
+	// Pairs a radiator in pairing mode and adds it to a zone. Blocks until
+	// pairing finishes.
+	PairRadiator(ctx context.Context, in *PairRadiatorRequest, opts ...grpc.CallOption) (*PairRadiatorReply, error)
+	// Starts relay feedback tuning of a zone's PID gains. The zone's radiators
+	// are switched fully on and off around the setpoint until the temperature
+	// oscillates steadily, which takes a few hours.
+	StartAutotune(ctx context.Context, in *StartAutotuneRequest, opts ...grpc.CallOption) (*StartAutotuneReply, error)
+	// Returns the progress of tuning and the proposed gains once done.
+	GetAutotune(ctx context.Context, in *GetAutotuneRequest, opts ...grpc.CallOption) (*GetAutotuneReply, error)
+	// Stops tuning and returns the zone to its PID controller.
+	CancelAutotune(ctx context.Context, in *CancelAutotuneRequest, opts ...grpc.CallOption) (*CancelAutotuneReply, error)
 }
 
 type heatingControlServiceClient struct {
@@ -257,12 +928,70 @@ func (c *heatingControlServiceClient) SetZoneSchedule(ctx context.Context, in *S
 	return out, nil
 }
 
+func (c *heatingControlServiceClient) ClearOverride(ctx context.Context, in *ClearOverrideRequest, opts ...grpc.CallOption) (*ClearOverrideReply, error) {
+	out := new(ClearOverrideReply)
+	err := grpc.Invoke(ctx, "/control.HeatingControlService/ClearOverride", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *heatingControlServiceClient) PairRadiator(ctx context.Context, in *PairRadiatorRequest, opts ...grpc.CallOption) (*PairRadiatorReply, error) {
+	out := new(PairRadiatorReply)
+	err := grpc.Invoke(ctx, "/control.HeatingControlService/PairRadiator", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *heatingControlServiceClient) StartAutotune(ctx context.Context, in *StartAutotuneRequest, opts ...grpc.CallOption) (*StartAutotuneReply, error) {
+	out := new(StartAutotuneReply)
+	err := grpc.Invoke(ctx, "/control.HeatingControlService/StartAutotune", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *heatingControlServiceClient) GetAutotune(ctx context.Context, in *GetAutotuneRequest, opts ...grpc.CallOption) (*GetAutotuneReply, error) {
+	out := new(GetAutotuneReply)
+	err := grpc.Invoke(ctx, "/control.HeatingControlService/GetAutotune", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *heatingControlServiceClient) CancelAutotune(ctx context.Context, in *CancelAutotuneRequest, opts ...grpc.CallOption) (*CancelAutotuneReply, error) {
+	out := new(CancelAutotuneReply)
+	err := grpc.Invoke(ctx, "/control.HeatingControlService/CancelAutotune", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for HeatingControlService service
 
 type HeatingControlServiceServer interface {
 	GetZones(context.Context, *GetZonesRequest) (*GetZonesReply, error)
 	GetZoneStatus(context.Context, *GetZoneStatusRequest) (*GetZoneStatusReply, error)
 	SetZoneSchedule(context.Context, *SetZoneScheduleRequest) (*SetZoneScheduleReply, error)
+	// Returns the zone to following its schedule.
+	ClearOverride(context.Context, *ClearOverrideRequest) (*ClearOverrideReply, error)
+	// Pairs a radiator in pairing mode and adds it to a zone. Blocks until
+	// pairing finishes.
+	PairRadiator(context.Context, *PairRadiatorRequest) (*PairRadiatorReply, error)
+	// Starts relay feedback tuning of a zone's PID gains. The zone's radiators
+	// are switched fully on and off around the setpoint until the temperature
+	// oscillates steadily, which takes a few hours.
+	StartAutotune(context.Context, *StartAutotuneRequest) (*StartAutotuneReply, error)
+	// Returns the progress of tuning and the proposed gains once done.
+	GetAutotune(context.Context, *GetAutotuneRequest) (*GetAutotuneReply, error)
+	// Stops tuning and returns the zone to its PID controller.
+	CancelAutotune(context.Context, *CancelAutotuneRequest) (*CancelAutotuneReply, error)
 }
 
 func RegisterHeatingControlServiceServer(s *grpc.Server, srv HeatingControlServiceServer) {
@@ -323,6 +1052,96 @@ func _HeatingControlService_SetZoneSchedule_Handler(srv interface{}, ctx context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _HeatingControlService_ClearOverride_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearOverrideRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HeatingControlServiceServer).ClearOverride(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/control.HeatingControlService/ClearOverride",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HeatingControlServiceServer).ClearOverride(ctx, req.(*ClearOverrideRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HeatingControlService_PairRadiator_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PairRadiatorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HeatingControlServiceServer).PairRadiator(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/control.HeatingControlService/PairRadiator",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HeatingControlServiceServer).PairRadiator(ctx, req.(*PairRadiatorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HeatingControlService_StartAutotune_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartAutotuneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HeatingControlServiceServer).StartAutotune(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/control.HeatingControlService/StartAutotune",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HeatingControlServiceServer).StartAutotune(ctx, req.(*StartAutotuneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HeatingControlService_GetAutotune_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAutotuneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HeatingControlServiceServer).GetAutotune(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/control.HeatingControlService/GetAutotune",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HeatingControlServiceServer).GetAutotune(ctx, req.(*GetAutotuneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HeatingControlService_CancelAutotune_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelAutotuneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HeatingControlServiceServer).CancelAutotune(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/control.HeatingControlService/CancelAutotune",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HeatingControlServiceServer).CancelAutotune(ctx, req.(*CancelAutotuneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _HeatingControlService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "control.HeatingControlService",
 	HandlerType: (*HeatingControlServiceServer)(nil),
@@ -339,6 +1158,26 @@ var _HeatingControlService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SetZoneSchedule",
 			Handler:    _HeatingControlService_SetZoneSchedule_Handler,
 		},
+		{
+			MethodName: "ClearOverride",
+			Handler:    _HeatingControlService_ClearOverride_Handler,
+		},
+		{
+			MethodName: "PairRadiator",
+			Handler:    _HeatingControlService_PairRadiator_Handler,
+		},
+		{
+			MethodName: "StartAutotune",
+			Handler:    _HeatingControlService_StartAutotune_Handler,
+		},
+		{
+			MethodName: "GetAutotune",
+			Handler:    _HeatingControlService_GetAutotune_Handler,
+		},
+		{
+			MethodName: "CancelAutotune",
+			Handler:    _HeatingControlService_CancelAutotune_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "service.proto",
@@ -347,36 +1186,101 @@ var _HeatingControlService_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("service.proto", fileDescriptor1) }
 
 var fileDescriptor1 = []byte{
-	// 491 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x53, 0x5d, 0x6e, 0xd3, 0x40,
-	0x10, 0xae, 0x1d, 0xe7, 0xa7, 0x13, 0x42, 0xec, 0xa1, 0x0d, 0x96, 0xa1, 0x6a, 0xf0, 0x53, 0xd4,
-	0xd2, 0x5a, 0x84, 0x23, 0x54, 0x2a, 0x50, 0xa4, 0x44, 0x72, 0x40, 0x48, 0xbc, 0x54, 0x8b, 0xbd,
-	0x18, 0x4b, 0xee, 0xae, 0x59, 0xaf, 0x2b, 0xb5, 0x88, 0x17, 0xae, 0xc0, 0x01, 0x78, 0xe0, 0x18,
-	0x1c, 0x83, 0x2b, 0x70, 0x10, 0xb4, 0xfe, 0x6b, 0x1b, 0x1c, 0x89, 0x37, 0x6b, 0xbe, 0x6f, 0xbe,
-	0xf9, 0xe6, 0x9b, 0x35, 0x8c, 0x32, 0x2a, 0x2e, 0xe3, 0x80, 0x1e, 0xa7, 0x82, 0x4b, 0x8e, 0xfd,
-	0x80, 0x33, 0x29, 0x78, 0xe2, 0x8c, 0xaa, 0x8f, 0xb2, 0xee, 0x3c, 0x8e, 0x38, 0x8f, 0x12, 0xea,
-	0x91, 0x34, 0xf6, 0x08, 0x63, 0x5c, 0x12, 0x19, 0x73, 0x96, 0x95, 0xa8, 0xfb, 0x43, 0x03, 0xe3,
-	0x3d, 0x67, 0x14, 0x11, 0x0c, 0x46, 0x2e, 0xa8, 0xad, 0x4d, 0xb5, 0xd9, 0xb6, 0x5f, 0x7c, 0xe3,
-	0x11, 0x0c, 0x04, 0x09, 0x63, 0x22, 0xb9, 0xb0, 0xf5, 0x69, 0x67, 0x36, 0x9c, 0x5b, 0xc7, 0xb5,
-	0xb8, 0x5f, 0x01, 0x7e, 0x43, 0xc1, 0x7d, 0x18, 0x06, 0x24, 0xa1, 0x2c, 0x24, 0xe2, 0x3c, 0x0e,
-	0x6d, 0xa3, 0x50, 0x82, 0xba, 0xf4, 0x2a, 0xc4, 0x23, 0x40, 0x49, 0x44, 0x44, 0xe5, 0xb9, 0xa4,
-	0x17, 0x29, 0x15, 0x44, 0xe6, 0x82, 0xda, 0xdd, 0xa9, 0x36, 0xeb, 0xfa, 0x56, 0x89, 0xbc, 0xb9,
-	0x01, 0xce, 0x8c, 0x41, 0xc7, 0x34, 0x5c, 0x0b, 0xc6, 0x2f, 0xa8, 0x54, 0x1e, 0x33, 0x9f, 0x7e,
-	0xce, 0x69, 0x26, 0xdd, 0x39, 0x8c, 0x6e, 0x4a, 0x69, 0x72, 0x85, 0x4f, 0xc0, 0xb8, 0xe6, 0x4c,
-	0x99, 0x57, 0x26, 0x47, 0x8d, 0x49, 0x45, 0xf1, 0x0b, 0xc8, 0x3d, 0x80, 0x9d, 0xaa, 0x67, 0x25,
-	0x89, 0xcc, 0x6b, 0xad, 0xb6, 0xbd, 0xdd, 0x5f, 0x1a, 0xe0, 0x1a, 0x59, 0x4d, 0x69, 0x8f, 0xa8,
-	0x6d, 0x25, 0x7d, 0xaa, 0xcd, 0xf4, 0x96, 0x95, 0xd0, 0x83, 0x07, 0x41, 0x2e, 0x04, 0x65, 0x77,
-	0xf9, 0x9d, 0x82, 0x8f, 0x15, 0x74, 0xbb, 0xe1, 0x10, 0xba, 0x99, 0x24, 0x92, 0x16, 0x69, 0xde,
-	0x9f, 0xef, 0x36, 0xab, 0xbd, 0xa4, 0x44, 0xc6, 0x2c, 0x52, 0xfe, 0xa8, 0x5f, 0x72, 0xce, 0x8c,
-	0x41, 0xd7, 0xec, 0xb9, 0x36, 0x4c, 0x56, 0x95, 0xf9, 0xe0, 0x13, 0x0d, 0xf3, 0x84, 0xd6, 0xb9,
-	0x4d, 0x60, 0xe7, 0x1f, 0x24, 0x4d, 0xae, 0xdc, 0x43, 0xe8, 0x9d, 0x70, 0xf6, 0x31, 0x8e, 0xfe,
-	0x23, 0xc8, 0x83, 0xa7, 0x70, 0xef, 0xf6, 0x6c, 0x1c, 0x42, 0xff, 0xed, 0xe2, 0xf5, 0x62, 0xf9,
-	0x6e, 0x61, 0x6e, 0x61, 0x0f, 0xf4, 0xe5, 0xc2, 0xd4, 0xb0, 0x0f, 0x9d, 0xe5, 0xe9, 0xa9, 0xa9,
-	0xcf, 0x7f, 0xea, 0xb0, 0x5b, 0xd1, 0x4f, 0x4a, 0xad, 0x55, 0xf9, 0x6a, 0x71, 0x09, 0x83, 0xfa,
-	0x88, 0x68, 0x37, 0x83, 0xd6, 0x4e, 0xed, 0x4c, 0x5a, 0x10, 0x65, 0xd9, 0xfa, 0xf6, 0xfb, 0xcf,
-	0x77, 0x7d, 0x88, 0xdb, 0xde, 0xe5, 0x33, 0xef, 0xba, 0x10, 0x09, 0x9b, 0x57, 0x51, 0x1e, 0x0d,
-	0xf7, 0xd6, 0x7b, 0xef, 0x5c, 0xde, 0x79, 0xb4, 0x09, 0x56, 0xfa, 0x0f, 0x0b, 0x7d, 0x0b, 0xc7,
-	0xb5, 0xbe, 0xf7, 0x45, 0xdd, 0xfb, 0x2b, 0xae, 0x60, 0xbc, 0x96, 0x21, 0xee, 0x37, 0x42, 0xed,
-	0xb9, 0x3b, 0x7b, 0x9b, 0x09, 0x6a, 0xd6, 0xd6, 0x87, 0x5e, 0xf1, 0x33, 0x3e, 0xff, 0x1b, 0x00,
-	0x00, 0xff, 0xff, 0x4e, 0xb1, 0xb3, 0xd6, 0xd3, 0x03, 0x00, 0x00,
+	// 1527 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x57, 0x5d, 0x6e, 0xdb, 0xc6,
+	0x16, 0x8e, 0xa8, 0x3f, 0xea, 0x48, 0xb2, 0xa5, 0x91, 0x6c, 0x2b, 0xb2, 0x9d, 0x38, 0xba, 0xc1,
+	0xbd, 0x46, 0xfe, 0x7c, 0xe3, 0x00, 0x37, 0x17, 0xe8, 0x53, 0xe0, 0x24, 0x8e, 0xd3, 0x44, 0x32,
+	0x28, 0x1b, 0x05, 0xfa, 0xc2, 0x4e, 0xc4, 0xb1, 0x34, 0x28, 0x35, 0x64, 0x86, 0x43, 0xc3, 0x4e,
+	0xd1, 0x87, 0x76, 0x0b, 0x5d, 0x43, 0x81, 0xac, 0xa3, 0x40, 0x57, 0xd0, 0x2d, 0x74, 0x07, 0x7d,
+	0x2a, 0xd0, 0x87, 0x62, 0x66, 0x48, 0x8a, 0x94, 0x25, 0xc3, 0x7d, 0x13, 0xcf, 0xf7, 0xcd, 0x99,
+	0x99, 0xef, 0xfc, 0x8d, 0xa0, 0x1e, 0x10, 0x7e, 0x4e, 0x47, 0xe4, 0x89, 0xcf, 0x3d, 0xe1, 0xa1,
+	0xf2, 0xc8, 0x63, 0x82, 0x7b, 0x6e, 0xb7, 0x1e, 0xfd, 0xd0, 0xf6, 0xee, 0xd6, 0xd8, 0xf3, 0xc6,
+	0x2e, 0xd9, 0xc3, 0x3e, 0xdd, 0xc3, 0x8c, 0x79, 0x02, 0x0b, 0xea, 0xb1, 0x40, 0xa3, 0xbd, 0xcf,
+	0x06, 0x54, 0x8e, 0xa9, 0x73, 0xe0, 0xb1, 0x33, 0x3a, 0x46, 0x3d, 0xa8, 0xf9, 0xdc, 0xf3, 0x3d,
+	0x2e, 0x29, 0xd8, 0xed, 0xe4, 0x76, 0x72, 0xbb, 0x39, 0x2b, 0x63, 0x43, 0x5d, 0x30, 0x29, 0x13,
+	0x64, 0xcc, 0xb1, 0xdb, 0x31, 0x14, 0x9e, 0x7c, 0xa3, 0x3b, 0x00, 0x0e, 0xe1, 0xf4, 0x1c, 0x0b,
+	0x7a, 0x4e, 0x3a, 0x79, 0x85, 0xa6, 0x2c, 0x68, 0x1b, 0xc0, 0x0b, 0x85, 0x1f, 0x0a, 0x7b, 0x4a,
+	0x59, 0xa7, 0xa0, 0xf0, 0x8a, 0xb6, 0xbc, 0xa7, 0x2c, 0x0d, 0xe3, 0x8b, 0x4e, 0x31, 0x03, 0xe3,
+	0x0b, 0x74, 0x0f, 0x6a, 0xf1, 0x4e, 0x6a, 0x7d, 0x49, 0x11, 0xaa, 0xb1, 0x4d, 0x7a, 0xc8, 0x50,
+	0xf0, 0x45, 0xa7, 0x3c, 0x47, 0xc1, 0x17, 0xe8, 0x39, 0x6c, 0x8c, 0x3c, 0xe6, 0x50, 0x7d, 0x1d,
+	0x3b, 0x82, 0xe4, 0x57, 0xc7, 0xdc, 0xc9, 0xed, 0x9a, 0xd6, 0x7a, 0x0a, 0x3e, 0x9a, 0xa1, 0xbd,
+	0x5f, 0x73, 0x50, 0xf8, 0xda, 0x63, 0x04, 0x21, 0x28, 0x30, 0x3c, 0x25, 0x4a, 0x9d, 0x8a, 0xa5,
+	0x7e, 0xa3, 0xc7, 0x60, 0x72, 0xec, 0x50, 0x2c, 0x3c, 0xde, 0x31, 0x76, 0xf2, 0xbb, 0xd5, 0xfd,
+	0xe6, 0x93, 0x38, 0x0e, 0x56, 0x04, 0x58, 0x09, 0x05, 0xdd, 0x85, 0xea, 0x08, 0xbb, 0x84, 0x39,
+	0x98, 0xdb, 0xd4, 0x51, 0x4a, 0x54, 0x2c, 0x88, 0x4d, 0x47, 0x0e, 0x7a, 0x0c, 0x48, 0x60, 0x3e,
+	0x26, 0xc2, 0x16, 0x64, 0xea, 0x13, 0x8e, 0x45, 0xc8, 0x89, 0x92, 0xa4, 0x68, 0x35, 0x35, 0x72,
+	0x32, 0x03, 0xd0, 0x7d, 0xc8, 0xfb, 0xd4, 0x51, 0x8a, 0x54, 0xf7, 0x51, 0xb2, 0x73, 0x12, 0x59,
+	0x4b, 0xc2, 0x6f, 0x0b, 0x66, 0xbe, 0x51, 0xe8, 0x35, 0x61, 0xf5, 0x90, 0x08, 0x79, 0x93, 0xc0,
+	0x22, 0x1f, 0x43, 0x12, 0x88, 0xde, 0x3e, 0xd4, 0x67, 0x26, 0xdf, 0xbd, 0x44, 0xf7, 0xa0, 0xf0,
+	0xc9, 0x63, 0xf2, 0x8a, 0xf2, 0x2a, 0xf5, 0xc4, 0xa1, 0xa4, 0x58, 0x0a, 0xea, 0x3d, 0x80, 0x76,
+	0xb4, 0x66, 0x28, 0xb0, 0x08, 0x63, 0x5f, 0x8b, 0xd4, 0xe9, 0xfd, 0x99, 0x03, 0x73, 0x70, 0x4e,
+	0x38, 0xa7, 0x0e, 0x41, 0x1d, 0x28, 0x63, 0xc7, 0xe1, 0x24, 0x08, 0x14, 0xa7, 0x66, 0xc5, 0x9f,
+	0x72, 0xe9, 0xd4, 0x73, 0x88, 0x4a, 0xab, 0x8a, 0xa5, 0x7e, 0xa3, 0xff, 0xc0, 0xaa, 0x83, 0x2f,
+	0x33, 0x2a, 0xc8, 0xbc, 0x32, 0xac, 0x15, 0x07, 0x5f, 0xa6, 0x25, 0x78, 0x08, 0x4d, 0x46, 0xc7,
+	0x93, 0xac, 0x60, 0x05, 0x45, 0x6d, 0x28, 0x20, 0x4d, 0xde, 0x83, 0x96, 0x43, 0xce, 0xb8, 0x17,
+	0x5c, 0xd5, 0xd7, 0xb0, 0x50, 0x04, 0xa5, 0x17, 0x6c, 0x03, 0x04, 0x02, 0x73, 0x61, 0x0b, 0x3a,
+	0x25, 0x4a, 0xe7, 0xbc, 0x55, 0x51, 0x96, 0x13, 0x3a, 0x25, 0xe8, 0x36, 0x98, 0x84, 0x39, 0x1a,
+	0x2c, 0x2b, 0xb0, 0x4c, 0x98, 0x23, 0xa1, 0xde, 0x1f, 0x06, 0xa0, 0x39, 0xa1, 0xa4, 0xc2, 0x8b,
+	0x93, 0x68, 0x51, 0xd0, 0x0d, 0x75, 0xa8, 0x05, 0x41, 0xdf, 0x83, 0xd6, 0x28, 0xe4, 0x9c, 0x30,
+	0xb1, 0x40, 0x1e, 0x14, 0x41, 0x59, 0x89, 0x8a, 0x81, 0xc0, 0x42, 0xcb, 0xb2, 0xb2, 0xbf, 0x96,
+	0x84, 0xf5, 0x0d, 0xc1, 0x82, 0xb2, 0xb1, 0x3c, 0x1f, 0xb1, 0x34, 0x47, 0x66, 0xb4, 0x17, 0x85,
+	0x2c, 0xca, 0xab, 0x59, 0x46, 0xc7, 0xb1, 0xb4, 0x12, 0x0a, 0xfa, 0x2f, 0xb4, 0x7d, 0x4e, 0x1c,
+	0x3a, 0x12, 0xc4, 0xb1, 0x53, 0x52, 0x69, 0x35, 0x50, 0x82, 0x0d, 0x13, 0xcd, 0x76, 0xa0, 0x36,
+	0x21, 0x58, 0xd8, 0xa1, 0x6f, 0x73, 0x79, 0x28, 0x53, 0x9d, 0x1b, 0xa4, 0xed, 0xd4, 0xb7, 0xe4,
+	0x11, 0x9e, 0xa5, 0x8a, 0xaa, 0xa2, 0x32, 0x71, 0xe3, 0x4a, 0x51, 0x45, 0x9a, 0x26, 0xc4, 0xb7,
+	0x05, 0xb3, 0xd8, 0x28, 0xf5, 0x7e, 0xc8, 0xc1, 0x4a, 0x96, 0x72, 0x7d, 0xde, 0xa9, 0x58, 0x18,
+	0xa9, 0x58, 0xdc, 0x87, 0x15, 0x17, 0x07, 0xc2, 0x0e, 0x08, 0x61, 0xfa, 0x26, 0x79, 0x75, 0x93,
+	0x9a, 0xb4, 0x0e, 0x09, 0x61, 0xea, 0x0e, 0x9b, 0x50, 0x51, 0x2c, 0x1e, 0x04, 0x54, 0xa9, 0x5a,
+	0xb4, 0x4c, 0x69, 0xb0, 0x82, 0x80, 0xf6, 0x3a, 0xb0, 0x3e, 0x8c, 0x02, 0x3f, 0x9a, 0x10, 0x27,
+	0x74, 0x49, 0x5c, 0x6f, 0xeb, 0xd0, 0xbe, 0x82, 0xf8, 0xee, 0xa5, 0xac, 0xa9, 0x03, 0x97, 0x60,
+	0x9e, 0xe8, 0x7b, 0x4d, 0x4d, 0xb5, 0x01, 0xcd, 0x71, 0xa5, 0x87, 0x9f, 0x0d, 0x68, 0x1d, 0x63,
+	0xca, 0x93, 0x9e, 0x33, 0xf3, 0x10, 0x15, 0xb4, 0xf2, 0x20, 0x7f, 0xa7, 0x05, 0x31, 0x16, 0x17,
+	0x62, 0xfe, 0xfa, 0x42, 0x2c, 0xdc, 0xbc, 0x10, 0x8b, 0xff, 0xac, 0x10, 0x4b, 0x4b, 0x0b, 0xf1,
+	0x29, 0xb4, 0x39, 0xf9, 0x18, 0x52, 0x4e, 0xec, 0x91, 0x6c, 0x6d, 0x7c, 0xaa, 0x7b, 0x77, 0x59,
+	0xf5, 0xee, 0x56, 0x84, 0x1d, 0xa4, 0xa0, 0x44, 0x3d, 0x33, 0xa5, 0x1e, 0x83, 0x66, 0x56, 0x26,
+	0x59, 0x93, 0xcb, 0x33, 0x64, 0x0b, 0x2a, 0xd1, 0x6e, 0xc4, 0x51, 0x62, 0x99, 0xd6, 0xcc, 0x20,
+	0xa7, 0x8e, 0x8f, 0x47, 0xdf, 0x12, 0x11, 0xd8, 0x01, 0x61, 0x42, 0xc9, 0x56, 0xb4, 0xaa, 0x91,
+	0x6d, 0x48, 0x98, 0xe8, 0x8d, 0xa0, 0xad, 0x32, 0xff, 0x45, 0x28, 0x3c, 0x11, 0xb2, 0xeb, 0x22,
+	0x2b, 0x27, 0x6c, 0x40, 0x84, 0xef, 0x51, 0x26, 0xa2, 0xe2, 0x4f, 0xbe, 0x65, 0xc2, 0x4d, 0xf1,
+	0x85, 0x3d, 0xf1, 0x42, 0x1e, 0x44, 0xfb, 0x98, 0x53, 0x7c, 0xf1, 0x46, 0x7e, 0xcb, 0x94, 0x98,
+	0xdb, 0x44, 0xa6, 0xc4, 0xae, 0xea, 0x3f, 0x37, 0xd8, 0xb8, 0xf7, 0x97, 0x01, 0x8d, 0x0c, 0x55,
+	0x8a, 0xf2, 0x28, 0x6e, 0x1a, 0x39, 0xd5, 0x34, 0xd6, 0x93, 0x0a, 0x8c, 0x69, 0x99, 0xae, 0x91,
+	0xed, 0x93, 0xc6, 0x7c, 0x9f, 0x4c, 0x5f, 0x2d, 0x3f, 0x77, 0xb5, 0x75, 0x28, 0x8d, 0x2e, 0x47,
+	0x2e, 0x09, 0xa2, 0x42, 0x8a, 0xbe, 0x50, 0x1b, 0x8a, 0x84, 0x73, 0x8f, 0xab, 0x1c, 0xaa, 0x58,
+	0xfa, 0x03, 0xfd, 0x0b, 0xea, 0xa1, 0x2b, 0xe8, 0x14, 0x0b, 0x62, 0x8f, 0x71, 0xf2, 0x1a, 0xa8,
+	0xc5, 0xc6, 0x43, 0x4c, 0x19, 0xfa, 0x1f, 0x6c, 0x24, 0x24, 0x9f, 0x70, 0xea, 0x39, 0x76, 0x40,
+	0xe4, 0x78, 0x0f, 0xa2, 0x97, 0xc1, 0x5a, 0x0c, 0x1f, 0x2b, 0x74, 0xa8, 0x41, 0xf4, 0x05, 0xac,
+	0x7e, 0xa2, 0x64, 0xec, 0x12, 0x6e, 0x33, 0x3a, 0x9a, 0x78, 0x6e, 0xd0, 0x31, 0x97, 0x8e, 0xd6,
+	0x95, 0x88, 0xda, 0xd7, 0x4c, 0xf4, 0x1c, 0xea, 0xe2, 0x92, 0x93, 0x30, 0xb0, 0xdd, 0xf0, 0xf2,
+	0x03, 0x61, 0x9d, 0xca, 0xd2, 0xa5, 0x35, 0x4d, 0x7c, 0xa7, 0x78, 0xbd, 0x87, 0xb0, 0x76, 0x80,
+	0xd9, 0x88, 0xb8, 0x37, 0x89, 0xd5, 0x1a, 0xb4, 0xe6, 0xc9, 0x32, 0xd8, 0xbf, 0xe4, 0xa1, 0xa4,
+	0x9d, 0xdf, 0x60, 0x86, 0xa3, 0xff, 0x43, 0x87, 0x93, 0x33, 0x4e, 0x82, 0x89, 0x7a, 0x07, 0xf1,
+	0x73, 0xfd, 0xb2, 0x0a, 0x05, 0xd1, 0x2d, 0xa1, 0x68, 0xad, 0x47, 0xf8, 0x51, 0x04, 0xbf, 0xd7,
+	0x28, 0xda, 0x87, 0xb5, 0xb8, 0xf5, 0xdb, 0x13, 0xcf, 0x75, 0x92, 0x65, 0x3a, 0x27, 0x5b, 0x31,
+	0xf8, 0xc6, 0x73, 0x9d, 0x78, 0xcd, 0x01, 0xdc, 0x49, 0xd6, 0x84, 0x4c, 0x50, 0xd7, 0x0e, 0xa2,
+	0xee, 0x67, 0x8f, 0x26, 0x98, 0x8d, 0x75, 0x43, 0x31, 0xad, 0xcd, 0x98, 0x75, 0x2a, 0x49, 0x71,
+	0x87, 0x3c, 0x50, 0x14, 0x39, 0x67, 0x54, 0x5e, 0xc4, 0xf1, 0x8c, 0xf7, 0xd5, 0x4f, 0x23, 0xa4,
+	0x30, 0x1d, 0xcc, 0x78, 0xdb, 0xfb, 0xb0, 0x32, 0xa5, 0xcc, 0xf6, 0x58, 0xc2, 0x2d, 0x29, 0x6e,
+	0x6d, 0x4a, 0xd9, 0x80, 0xc5, 0xac, 0x7f, 0xc3, 0xaa, 0x62, 0x9d, 0x9d, 0x25, 0xb4, 0xb2, 0xa2,
+	0xd5, 0x25, 0xed, 0xec, 0x2c, 0xe6, 0x3d, 0x81, 0x96, 0x2c, 0x40, 0x9f, 0x13, 0x35, 0xbc, 0x62,
+	0xae, 0xa9, 0x5f, 0x66, 0x53, 0x7c, 0x71, 0xac, 0x91, 0x94, 0x50, 0x0e, 0x0d, 0xf0, 0x07, 0x97,
+	0xd8, 0x9e, 0x2f, 0x73, 0xcd, 0xd5, 0xd3, 0x51, 0x65, 0x85, 0x69, 0xb5, 0x22, 0x70, 0xa0, 0x31,
+	0x55, 0xbe, 0x0f, 0x1e, 0x41, 0x2d, 0x3d, 0x91, 0x51, 0x15, 0xca, 0xa7, 0xfd, 0x2f, 0xfb, 0x83,
+	0xaf, 0xfa, 0x8d, 0x5b, 0xa8, 0x04, 0xc6, 0xa0, 0xdf, 0xc8, 0xa1, 0x32, 0xe4, 0x07, 0xaf, 0x5f,
+	0x37, 0x8c, 0x07, 0xdf, 0x40, 0x3d, 0x53, 0x8a, 0xa8, 0x09, 0xf5, 0x17, 0xa7, 0x27, 0x83, 0x93,
+	0xd3, 0xfe, 0x2b, 0xbb, 0x3f, 0xe8, 0xbf, 0x6a, 0xdc, 0x42, 0x6d, 0x68, 0x24, 0x26, 0xeb, 0xb4,
+	0xdf, 0x3f, 0xea, 0x1f, 0x36, 0x72, 0x19, 0xe2, 0x4b, 0x49, 0x34, 0x50, 0x0b, 0x56, 0x13, 0xd3,
+	0xeb, 0x17, 0x47, 0xef, 0x5e, 0xbd, 0x6c, 0xe4, 0xf7, 0x3f, 0x17, 0x61, 0x2d, 0x3a, 0xd0, 0x81,
+	0x4e, 0xa2, 0xa1, 0xfe, 0xeb, 0x81, 0x06, 0x60, 0xc6, 0x0f, 0x47, 0xd4, 0x49, 0x32, 0x6c, 0xee,
+	0x79, 0xd9, 0x5d, 0x5f, 0x80, 0xc8, 0x64, 0x6d, 0xfe, 0xf8, 0xdb, 0xef, 0x3f, 0x19, 0x55, 0x54,
+	0xd9, 0x3b, 0x7f, 0xba, 0xf7, 0x49, 0x39, 0x71, 0x92, 0x97, 0x68, 0x34, 0xb5, 0xb7, 0xe7, 0xd7,
+	0x66, 0x5e, 0x9b, 0xdd, 0xcd, 0x65, 0xb0, 0xf4, 0xbf, 0xa1, 0xfc, 0x37, 0xd1, 0x6a, 0xec, 0x7f,
+	0xef, 0x3b, 0x59, 0x3b, 0xdf, 0xa3, 0x21, 0xac, 0xce, 0xcd, 0x5f, 0x74, 0x37, 0x71, 0xb4, 0x78,
+	0x66, 0x77, 0xb7, 0x97, 0x13, 0xe4, 0x5e, 0xb7, 0xd0, 0x7b, 0xa8, 0x67, 0x06, 0x72, 0xea, 0xe8,
+	0x8b, 0x86, 0x7a, 0x77, 0x73, 0x19, 0xac, 0xdd, 0xbd, 0x85, 0x5a, 0x7a, 0x42, 0xa1, 0xad, 0x59,
+	0xff, 0xb8, 0x3a, 0xdf, 0xbb, 0xdd, 0x25, 0x68, 0x72, 0xb4, 0xcc, 0x60, 0x48, 0x1d, 0x6d, 0xd1,
+	0x54, 0xea, 0x6e, 0x2e, 0x83, 0xb5, 0xbb, 0x43, 0xa8, 0xa6, 0xc6, 0x04, 0xca, 0xc4, 0x60, 0xde,
+	0xd5, 0xed, 0xc5, 0xa0, 0x76, 0x74, 0x0c, 0x2b, 0xd9, 0x26, 0x86, 0xee, 0xcc, 0x44, 0x59, 0xd4,
+	0x0a, 0xbb, 0x5b, 0x4b, 0x71, 0xe5, 0xf1, 0x43, 0x49, 0xfd, 0xad, 0x7d, 0xf6, 0xf7, 0x00, 0xee,
+	0x2d, 0x47, 0x4d, 0x1d, 0x0f, 0x00, 0x00,
 }
@@ -0,0 +1,322 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle/radiator"
+	"go.uber.org/zap"
+	"google.golang.org/api/calendar/v3"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type setCall struct {
+	addr    radiator.Address
+	setting radiator.Setting
+}
+
+// recordingController records the settings sent to radiators.
+type recordingController struct {
+	calls []setCall
+	err   error
+}
+
+func (r *recordingController) Set(addr radiator.Address, setting radiator.Setting) error {
+	r.calls = append(r.calls, setCall{addr, setting})
+	return r.err
+}
+
+func newTestController(radiators RadiatorController) *Controller {
+	return &Controller{
+		Config:          make(map[string]*Room),
+		controller:      radiators,
+		refreshInterval: 15 * time.Minute,
+		overrideHold:    2 * time.Hour,
+		commanded:       make(map[radiator.Address]commandedState),
+		registry:        radiator.NewRegistry(),
+		now:             time.Now,
+		logger:          zap.NewNop().Sugar(),
+	}
+}
+
+func TestSetRadiator(t *testing.T) {
+	room := &Room{config: &Zone{Name: "Study", TargetTemperature: 20}}
+	addr := radiator.Address(0x1234)
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	on := radiator.Setting{Mode: radiator.Day, Day: 20, Night: 20, Defrost: 10}
+	off := radiator.Setting{Mode: radiator.Defrost, Day: 20, Night: 20, Defrost: 10}
+
+	Convey("Only sends changes", t, func() {
+		radiators := &recordingController{}
+		c := newTestController(radiators)
+
+		c.setRadiator(room, addr, HeatingState_ON, start)
+		So(radiators.calls, ShouldResemble, []setCall{{addr, on}})
+
+		c.setRadiator(room, addr, HeatingState_ON, start.Add(time.Minute))
+		So(radiators.calls, ShouldHaveLength, 1)
+
+		c.setRadiator(room, addr, HeatingState_OFF, start.Add(2*time.Minute))
+		So(radiators.calls, ShouldHaveLength, 2)
+		So(radiators.calls[1].setting, ShouldResemble, off)
+
+		// Unknown is treated as off.
+		c.setRadiator(room, addr, HeatingState_UNKNOWN, start.Add(3*time.Minute))
+		So(radiators.calls, ShouldHaveLength, 2)
+
+		// Other radiators are tracked separately.
+		c.setRadiator(room, 0x5678, HeatingState_OFF, start.Add(3*time.Minute))
+		So(radiators.calls, ShouldHaveLength, 3)
+	})
+
+	Convey("Radiators hold at most the room's target", t, func() {
+		So(radiatorSetting(room, HeatingState_ON), ShouldResemble, on)
+		So(radiatorSetting(&Room{config: &Zone{}}, HeatingState_ON).Validate(), ShouldBeNil)
+		hot := radiatorSetting(&Room{config: &Zone{TargetTemperature: 40}}, HeatingState_ON)
+		So(hot.Day, ShouldEqual, radiator.MaxTemperature)
+	})
+
+	Convey("Refreshes unchanged state", t, func() {
+		radiators := &recordingController{}
+		c := newTestController(radiators)
+
+		c.setRadiator(room, addr, HeatingState_ON, start)
+		c.setRadiator(room, addr, HeatingState_ON, start.Add(14*time.Minute))
+		So(radiators.calls, ShouldHaveLength, 1)
+		c.setRadiator(room, addr, HeatingState_ON, start.Add(15*time.Minute))
+		So(radiators.calls, ShouldHaveLength, 2)
+		// The refresh interval restarts from the refresh.
+		c.setRadiator(room, addr, HeatingState_ON, start.Add(20*time.Minute))
+		So(radiators.calls, ShouldHaveLength, 2)
+	})
+
+	Convey("Retries failures", t, func() {
+		radiators := &recordingController{err: errors.New("radio broken")}
+		c := newTestController(radiators)
+
+		c.setRadiator(room, addr, HeatingState_ON, start)
+		radiators.err = nil
+		c.setRadiator(room, addr, HeatingState_ON, start.Add(time.Minute))
+		So(radiators.calls, ShouldHaveLength, 2)
+		c.setRadiator(room, addr, HeatingState_ON, start.Add(2*time.Minute))
+		So(radiators.calls, ShouldHaveLength, 2)
+	})
+}
+
+// weekCase is a week of simulated control of a lagged room.
+type weekCase struct {
+	name string
+	// Target at a time, negative if unscheduled.
+	target func(time.Time) float64
+	// Whether the sensor fails to report at a time.
+	stale func(time.Time) bool
+	// Limit on the mean absolute error while scheduled, ignoring the first
+	// couple of hours of each period spent warming up.
+	maxError float64
+}
+
+type weekResult struct {
+	meanError float64
+	// Minutes heated while unscheduled or stale, past the minimum on time
+	// which may keep the radiators on for a while afterwards.
+	wrongOn int
+	// Integral seen at the first update of each scheduled period.
+	startIntegrals []float64
+}
+
+func daytime(target float64) func(time.Time) float64 {
+	return func(t time.Time) float64 {
+		if t.Hour() >= 7 && t.Hour() < 22 {
+			return target
+		}
+		return -1
+	}
+}
+
+func neverStale(time.Time) bool { return false }
+
+// simulateWeek updates room every minute for a week.
+func simulateWeek(c *Controller, room *Room, wc weekCase, start time.Time) weekResult {
+	r := &laggedRoom{radiator: 15, room: 15, outside: 5}
+	var result weekResult
+	var errSum float64
+	var samples int
+	var scheduledSince, offSince time.Time
+	for now := start; now.Before(start.Add(7 * 24 * time.Hour)); now = now.Add(time.Minute) {
+		target := wc.target(now)
+		stale := wc.stale(now)
+		if !stale {
+			room.LastTemp = r.room
+			room.readAt = now
+		}
+		if target >= 0 && scheduledSince.IsZero() {
+			scheduledSince = now
+			result.startIntegrals = append(result.startIntegrals, room.Pid.Integral())
+		} else if target < 0 {
+			scheduledSince = time.Time{}
+		}
+		state := c.nextState(room, target, now)
+		// Readings are trusted for a while after the sensor stops reporting.
+		if target < 0 || now.Sub(room.readAt) > staleReadingAge {
+			if offSince.IsZero() {
+				offSince = now
+			}
+			if state == HeatingState_ON && now.Sub(offSince) >= defaultMinOnTime {
+				result.wrongOn++
+			}
+		} else {
+			offSince = time.Time{}
+		}
+		if target >= 0 && !stale && now.Sub(scheduledSince) > 2*time.Hour {
+			errSum += math.Abs(target - r.room)
+			samples++
+		}
+		r.step(state)
+	}
+	if samples > 0 {
+		result.meanError = errSum / float64(samples)
+	}
+	return result
+}
+
+func TestWeekSimulation(t *testing.T) {
+	// A Monday.
+	start := time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+	cases := []weekCase{
+		{
+			name:     "Default gains",
+			target:   daytime(20),
+			stale:    neverStale,
+			maxError: 0.5,
+		},
+		{
+			name: "Setpoint changes",
+			target: func(t time.Time) float64 {
+				target := daytime(20)(t)
+				if target > 0 && t.Hour() >= 18 {
+					return 22
+				}
+				return target
+			},
+			stale:    neverStale,
+			maxError: 0.6,
+		},
+		{
+			name:   "Sensor stale for a day",
+			target: daytime(20),
+			stale: func(t time.Time) bool {
+				return t.Weekday() == time.Wednesday
+			},
+			maxError: 0.5,
+		},
+	}
+	for _, wc := range cases {
+		Convey(wc.name, t, func() {
+			c := newTestController(&recordingController{})
+			room := &Room{
+				Pid:    newZonePID(nil),
+				pwm:    NewTimeProportioner(0, 0, 0),
+				config: &Zone{Name: "Study"},
+			}
+			result := simulateWeek(c, room, wc, start)
+			So(result.meanError, ShouldBeLessThan, wc.maxError)
+			So(result.wrongOn, ShouldEqual, 0)
+			// Nothing is carried over from the day before.
+			So(result.startIntegrals, ShouldHaveLength, 7)
+			for _, integral := range result.startIntegrals {
+				So(integral, ShouldEqual, 0)
+			}
+		})
+	}
+
+	Convey("Integrates over the time between a room's own updates", t, func() {
+		c := newTestController(&recordingController{})
+		room := &Room{
+			Pid:    NewPID(0, 0.01, 0),
+			pwm:    NewTimeProportioner(0, 0, 0),
+			config: &Zone{Name: "Study"},
+		}
+		room.LastTemp = 19
+		room.readAt = start
+		c.nextState(room, 20, start)
+		So(room.Pid.Integral(), ShouldEqual, 0)
+		// Other rooms being updated in between makes no difference.
+		c.nextState(room, 20, start.Add(5*time.Minute))
+		So(room.Pid.Integral(), ShouldAlmostEqual, 3)
+		// Nor does a long gap, which isn't integrated over.
+		room.readAt = start.Add(time.Hour)
+		c.nextState(room, 20, start.Add(time.Hour))
+		So(room.Pid.Integral(), ShouldAlmostEqual, 3)
+	})
+
+	Convey("Stale readings pause integration", t, func() {
+		c := newTestController(&recordingController{})
+		room := &Room{
+			Pid:    NewPID(0, 0.01, 0),
+			pwm:    NewTimeProportioner(0, 0, 0),
+			config: &Zone{Name: "Study"},
+		}
+		room.LastTemp = 19
+		room.readAt = start
+		c.nextState(room, 20, start)
+		c.nextState(room, 20, start.Add(5*time.Minute))
+		So(c.nextState(room, 20, start.Add(31*time.Minute)), ShouldEqual, HeatingState_OFF)
+		// The integral is kept for when the sensor comes back.
+		So(room.Pid.Integral(), ShouldAlmostEqual, 3)
+		room.readAt = start.Add(40 * time.Minute)
+		c.nextState(room, 20, start.Add(40*time.Minute))
+		So(room.Pid.Integral(), ShouldAlmostEqual, 3)
+	})
+}
+
+func TestRadiatorStatus(t *testing.T) {
+	Convey("Reports when each radiator was last heard", t, func() {
+		c := newTestController(&recordingController{})
+		room := &Room{config: &Zone{Name: "Study"}}
+		So(c.registry.Add(radiator.Info{Address: 0x1234, Name: "Window", Zone: "Study"}), ShouldBeNil)
+		So(c.registry.Add(radiator.Info{Address: 0x5678, Name: "Door", Zone: "Study"}), ShouldBeNil)
+		So(c.registry.Add(radiator.Info{Address: 0x9abc, Zone: "Kitchen"}), ShouldBeNil)
+		seen := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+		So(c.registry.Seen(0x1234, seen, -70), ShouldBeTrue)
+
+		So(c.radiatorStatus(room), ShouldResemble, []*RadiatorStatus{
+			{Address: []byte{0x12, 0x34}, Name: "Window", LastSeenTime: seen.Unix(), LastRssi: -70},
+			{Address: []byte{0x56, 0x78}, Name: "Door"},
+		})
+	})
+}
+
+type failingSchedule struct{}
+
+func (failingSchedule) GetSchedule(calendarId string) ([]*calendar.TimePeriod, error) {
+	return nil, errors.New("offline")
+}
+
+func TestGetZoneStatus(t *testing.T) {
+	Convey("Reports the last tick without the schedule", t, func() {
+		start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+		c := newTestController(&recordingController{})
+		c.schedule = failingSchedule{}
+		room := &Room{config: &Zone{Name: "Study"}, LastTemp: 18, state: HeatingState_ON}
+		c.Config["Study"] = room
+		c.saveStatus(room, 0, false)
+		room.override = &override{
+			address: 0x1234,
+			setting: radiator.Setting{Mode: radiator.Night, Day: 21, Night: 19, Defrost: 7},
+			start:   start,
+			end:     start.Add(time.Hour),
+		}
+		// Changes since the last tick aren't reported.
+		room.LastTemp = 19
+
+		reply, err := c.GetZoneStatus(context.Background(), &GetZoneStatusRequest{Name: "Study"})
+		So(err, ShouldNotBeNil)
+		So(reply.State, ShouldEqual, HeatingState_ON)
+		So(reply.CurrentTemperature, ShouldEqual, 18)
+		So(reply.GetOverride().GetMode(), ShouldEqual, "NIGHT")
+	})
+}
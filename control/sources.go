@@ -0,0 +1,78 @@
+package control
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hatstand/shinywaffle/weather"
+	"github.com/hatstand/shinywaffle/wirelesstag"
+	"google.golang.org/api/calendar/v3"
+)
+
+// ScheduleSource provides the periods each zone's calendar is busy, which
+// are when the zone is heated.
+type ScheduleSource interface {
+	GetSchedule(calendarId string) ([]*calendar.TimePeriod, error)
+}
+
+// Reading is a zone's temperature as last reported by its sensor.
+type Reading struct {
+	Zone        string
+	Temperature float64
+	// When the sensor reported, or zero if unknown.
+	At time.Time
+}
+
+// SensorSource provides the latest reading of every zone's sensor.
+type SensorSource interface {
+	Readings() ([]Reading, error)
+}
+
+// WirelessTags reads zones' temperatures from wireless tags named after
+// them.
+type WirelessTags struct{}
+
+func (WirelessTags) Readings() ([]Reading, error) {
+	tags, err := wirelesstag.GetTags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tag data: %w", err)
+	}
+	var readings []Reading
+	for _, t := range tags {
+		readings = append(readings, Reading{
+			Zone:        t.Name,
+			Temperature: t.Temperature,
+			At:          t.LastCommunication(),
+		})
+	}
+	return readings, nil
+}
+
+// WeatherSource provides the outside temperature.
+type WeatherSource interface {
+	OutsideTemperature() (float64, error)
+}
+
+// OpenWeatherMap reads the outside temperature from OpenWeatherMap.
+type OpenWeatherMap struct {
+	Location string
+}
+
+func (w OpenWeatherMap) OutsideTemperature() (float64, error) {
+	obs, err := weather.FetchCurrentWeather(w.Location)
+	if err != nil {
+		return 0, err
+	}
+	return float64(obs.CurrentTemp), nil
+}
+
+// Sources are where a Controller gets its readings, schedules and the time
+// from. They are replaced to simulate a controller.
+type Sources struct {
+	Sensors  SensorSource
+	Schedule ScheduleSource
+	// Optional, for learning rooms' models to preheat them.
+	Weather WeatherSource
+	// Returns the current time.
+	Now func() time.Time
+}
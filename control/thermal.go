@@ -0,0 +1,240 @@
+package control
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	defaultMaxPreheat = 3 * time.Hour
+
+	// Weight of each sample of a room's model relative to the next, so that
+	// the model follows changes through the seasons.
+	thermalForgetting = 0.998
+	// Samples needed before a room's model is used.
+	minThermalSamples = 24
+	// Readings are combined into samples spanning at least this long, which
+	// smooths out the sensors' resolution.
+	minSampleSpan = 20 * time.Minute
+	// Longer spans between readings aren't sampled.
+	maxSampleSpan = 2 * time.Hour
+
+	// How often the outside temperature is fetched, and how long it is used
+	// for if fetching fails.
+	outsideInterval = 15 * time.Minute
+	maxOutsideAge   = time.Hour
+)
+
+// ThermalModel is a model of a room learnt from its history. The room's
+// temperature T changes by
+//
+//	dT/dt = heat·duty - loss·(T - outside)
+//
+// degrees an hour, where duty is the fraction of the time its radiators are
+// on. With them on all the time the room heats up by heat - loss·(T -
+// outside) degrees an hour. heat and loss are fitted by least squares.
+type ThermalModel struct {
+	// Sums of products of the duty d, the negated temperature difference l
+	// and the rate r.
+	dd, dl, ll, dr, lr float64
+	samples            int
+}
+
+// Add adds a sample of the room heating by rate degrees an hour with its
+// radiators on for duty of the time, while delta degrees warmer than
+// outside.
+func (m *ThermalModel) Add(rate, duty, delta float64) {
+	l := -delta
+	f := thermalForgetting
+	m.dd = f*m.dd + duty*duty
+	m.dl = f*m.dl + duty*l
+	m.ll = f*m.ll + l*l
+	m.dr = f*m.dr + duty*rate
+	m.lr = f*m.lr + l*rate
+	m.samples++
+}
+
+// Samples returns the number of samples added.
+func (m *ThermalModel) Samples() int {
+	return m.samples
+}
+
+// Parameters returns the rate in degrees an hour that the radiators heat
+// the room by and the rate it loses heat by per degree warmer than outside,
+// once there is enough varied history to tell them apart.
+func (m *ThermalModel) Parameters() (heat, loss float64, ok bool) {
+	if m.samples < minThermalSamples {
+		return 0, 0, false
+	}
+	det := m.dd*m.ll - m.dl*m.dl
+	if det <= 1e-6*m.dd*m.ll {
+		return 0, 0, false
+	}
+	heat = (m.dr*m.ll - m.dl*m.lr) / det
+	loss = (m.dd*m.lr - m.dl*m.dr) / det
+	if heat <= 0 || loss < 0 {
+		return 0, 0, false
+	}
+	return heat, loss, true
+}
+
+// HeatUpRate returns the degrees an hour the room heats up by with its
+// radiators on.
+func (m *ThermalModel) HeatUpRate(inside, outside float64) (float64, bool) {
+	heat, loss, ok := m.Parameters()
+	if !ok {
+		return 0, false
+	}
+	return heat - loss*(inside-outside), true
+}
+
+// TimeToHeat returns how long the room takes to heat from one temperature to
+// another with its radiators on, or false if the model is unknown or the
+// room never gets that warm.
+func (m *ThermalModel) TimeToHeat(from, to, outside float64) (time.Duration, bool) {
+	heat, loss, ok := m.Parameters()
+	if !ok {
+		return 0, false
+	}
+	if from >= to {
+		return 0, true
+	}
+	var hours float64
+	if loss == 0 {
+		hours = (to - from) / heat
+	} else {
+		// The room approaches the temperature at which its losses match
+		// the radiators exponentially.
+		settles := outside + heat/loss
+		if to >= settles {
+			return 0, false
+		}
+		hours = math.Log((settles-from)/(settles-to)) / loss
+	}
+	return time.Duration(hours * float64(time.Hour)), true
+}
+
+// thermalSample is a room's heating since a reading, to sample its model
+// with once a later reading arrives.
+type thermalSample struct {
+	readAt time.Time
+	temp   float64
+	// How long the radiators have been on since readAt.
+	on time.Duration
+	// When the controller last ran for the room.
+	tick time.Time
+	// Whether anything other than the controller, e.g. an override, might
+	// have switched the radiators.
+	spoilt bool
+}
+
+// learn adds the room's heating since it was last sampled to its model once
+// there is a reading long enough after. The radiators are assumed to have
+// been in the room's state since the last tick.
+func (c *Controller) learn(room *Room, outside float64, haveOutside bool, now time.Time) {
+	s := &room.sample
+	if !s.tick.IsZero() && room.state == HeatingState_ON {
+		s.on += now.Sub(s.tick)
+	}
+	s.tick = now
+	span := room.readAt.Sub(s.readAt)
+	if !s.readAt.IsZero() && span < minSampleSpan {
+		return
+	}
+	if !s.readAt.IsZero() && span <= maxSampleSpan && !s.spoilt && haveOutside {
+		duty := math.Min(s.on.Seconds()/span.Seconds(), 1)
+		rate := (room.LastTemp - s.temp) / span.Hours()
+		room.model.Add(rate, duty, (room.LastTemp+s.temp)/2-outside)
+	}
+	*s = thermalSample{readAt: room.readAt, temp: room.LastTemp, tick: now}
+}
+
+// roomStatus is what GetZoneStatus reports of a room as of the last tick,
+// saved by Tick so that status requests neither fetch the weather nor race
+// with the room being updated.
+type roomStatus struct {
+	temperature    float64
+	state          HeatingState
+	predictedStart time.Time
+	// Degrees an hour the room heats up by with its radiators on.
+	heatUpRate     float64
+	haveHeatUpRate bool
+}
+
+// saveStatus saves room's state and prediction as of this tick for
+// GetZoneStatus.
+func (c *Controller) saveStatus(room *Room, outside float64, haveOutside bool) {
+	status := roomStatus{
+		temperature:    room.LastTemp,
+		state:          room.state,
+		predictedStart: room.predictedStart,
+	}
+	if haveOutside {
+		status.heatUpRate, status.haveHeatUpRate = room.model.HeatUpRate(room.LastTemp, outside)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	room.status = status
+}
+
+// outsideTemperature returns the outside temperature, fetched at most every
+// outsideInterval. It is only called from Tick.
+func (c *Controller) outsideTemperature(now time.Time) (float64, bool) {
+	if c.weather == nil {
+		return 0, false
+	}
+	if c.outsideAt.IsZero() || now.Sub(c.outsideAt) >= outsideInterval {
+		if temp, err := c.weather.OutsideTemperature(); err != nil {
+			c.logger.Warnf("Failed to fetch outside temperature: %v", err)
+		} else {
+			c.outside, c.outsideAt = temp, now
+		}
+	}
+	if c.outsideAt.IsZero() || now.Sub(c.outsideAt) > maxOutsideAge {
+		return 0, false
+	}
+	return c.outside, true
+}
+
+// nextScheduledStart returns when room's next scheduled period after now
+// starts.
+func (c *Controller) nextScheduledStart(room *Room, now time.Time) (time.Time, bool) {
+	periods, err := c.schedule.GetSchedule(room.config.CalendarId)
+	if err != nil {
+		c.logger.Warnf("Failed to fetch schedule for room %s: %v", room.config.GetName(), err)
+		return time.Time{}, false
+	}
+	var next time.Time
+	for _, period := range periods {
+		t, err := time.Parse(time.RFC3339, period.Start)
+		if err != nil {
+			continue
+		}
+		if t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// predictStart returns when room has to start heating to reach its target
+// temperature as its next scheduled period starts, and when that is. Rooms
+// which can't be heated that far are heated for the maximum preheat time.
+func (c *Controller) predictStart(room *Room, now time.Time) (start, scheduled time.Time, ok bool) {
+	if _, _, known := room.model.Parameters(); !known || !c.optimalStart {
+		return time.Time{}, time.Time{}, false
+	}
+	outside, ok := c.outsideTemperature(now)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	scheduled, ok = c.nextScheduledStart(room, now)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	d, ok := room.model.TimeToHeat(room.LastTemp, float64(room.config.GetTargetTemperature()), outside)
+	if !ok || d > c.maxPreheat {
+		d = c.maxPreheat
+	}
+	return scheduled.Add(-d), scheduled, true
+}
@@ -0,0 +1,215 @@
+package control
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fixedSchedule is heated for the same periods on every calendar.
+type fixedSchedule []*calendar.TimePeriod
+
+func (s fixedSchedule) GetSchedule(calendarId string) ([]*calendar.TimePeriod, error) {
+	return s, nil
+}
+
+type fixedWeather float64
+
+func (w fixedWeather) OutsideTemperature() (float64, error) {
+	return float64(w), nil
+}
+
+// fixedReadings are the latest readings of every sensor.
+type fixedReadings []Reading
+
+func (r fixedReadings) Readings() ([]Reading, error) {
+	return r, nil
+}
+
+// learnt returns a model of a room which heats by heat and loses loss per
+// degree warmer than outside, each an hour.
+func learnt(heat, loss float64) ThermalModel {
+	var m ThermalModel
+	for i := 0; i < minThermalSamples; i++ {
+		duty := float64(i%4) / 3
+		delta := float64(5 + i%7)
+		m.Add(heat*duty-loss*delta, duty, delta)
+	}
+	return m
+}
+
+func TestThermalModel(t *testing.T) {
+	Convey("Fits the heating and loss rates", t, func() {
+		m := learnt(2, 0.05)
+		heat, loss, ok := m.Parameters()
+		So(ok, ShouldBeTrue)
+		So(heat, ShouldAlmostEqual, 2)
+		So(loss, ShouldAlmostEqual, 0.05)
+		rate, ok := m.HeatUpRate(20, 0)
+		So(ok, ShouldBeTrue)
+		So(rate, ShouldAlmostEqual, 1)
+	})
+
+	Convey("Needs enough varied history", t, func() {
+		var m ThermalModel
+		for i := 0; i < minThermalSamples-1; i++ {
+			m.Add(1, 1, 10)
+		}
+		_, _, ok := m.Parameters()
+		So(ok, ShouldBeFalse)
+		// Never off, so losses can't be told from heating.
+		m.Add(1, 1, 10)
+		_, _, ok = m.Parameters()
+		So(ok, ShouldBeFalse)
+		_, ok = m.TimeToHeat(15, 20, 5)
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("Predicts how long heating takes", t, func() {
+		m := learnt(2, 0.05)
+		// Settles at 40 degrees above outside.
+		d, ok := m.TimeToHeat(10, 20, 0)
+		So(ok, ShouldBeTrue)
+		So(d.Hours(), ShouldAlmostEqual, math.Log(30.0/20)/0.05)
+		d, ok = m.TimeToHeat(21, 20, 0)
+		So(ok, ShouldBeTrue)
+		So(d, ShouldEqual, 0)
+		_, ok = m.TimeToHeat(10, 20, -25)
+		So(ok, ShouldBeFalse)
+
+		m = learnt(2, 0)
+		d, ok = m.TimeToHeat(10, 20, 0)
+		So(ok, ShouldBeTrue)
+		So(d, ShouldEqual, 5*time.Hour)
+	})
+}
+
+func TestOptimalStart(t *testing.T) {
+	// Scheduled from 07:00.
+	day := time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+	scheduled := day.Add(7 * time.Hour)
+	schedule := fixedSchedule{{
+		Start: scheduled.Format(time.RFC3339),
+		End:   day.Add(22 * time.Hour).Format(time.RFC3339),
+	}}
+
+	newRoom := func() (*Controller, *Room, *time.Time) {
+		now := day
+		c := newTestController(&recordingController{})
+		c.schedule = schedule
+		c.weather = fixedWeather(0)
+		c.now = func() time.Time { return now }
+		c.optimalStart = true
+		c.maxPreheat = defaultMaxPreheat
+		room := &Room{
+			Pid:      newZonePID(nil),
+			pwm:      NewTimeProportioner(0, 0, 0),
+			config:   &Zone{Name: "Study", TargetTemperature: 20},
+			LastTemp: 17,
+			model:    learnt(2, 0.05),
+		}
+		c.Config["Study"] = room
+		return c, room, &now
+	}
+
+	Convey("Heats ahead of the schedule", t, func() {
+		c, room, now := newRoom()
+		// 17 to 20 degrees takes log(23/20)/0.05 hours.
+		want := scheduled.Add(-time.Duration(math.Log(23.0/20) / 0.05 * float64(time.Hour)))
+
+		*now = day.Add(2 * time.Hour)
+		c.sensors = fixedReadings{{Zone: "Study", Temperature: 17, At: *now}}
+		c.Tick()
+		So(room.state, ShouldEqual, HeatingState_OFF)
+		So(room.predictedStart.Sub(want), ShouldBeBetween, -time.Second, time.Second)
+		// Status is as of the last tick, without fetching the weather.
+		c.weather = nil
+		reply, err := c.GetZoneStatus(context.Background(), &GetZoneStatusRequest{Name: "Study"})
+		So(err, ShouldBeNil)
+		So(reply.State, ShouldEqual, HeatingState_OFF)
+		So(reply.CurrentTemperature, ShouldEqual, 17)
+		So(reply.PredictedStartTime, ShouldEqual, room.predictedStart.Unix())
+		So(reply.HeatUpRate, ShouldAlmostEqual, 2-0.05*17, 0.001)
+		c.weather = fixedWeather(0)
+
+		*now = want.Add(time.Minute)
+		room.readAt = *now
+		So(c.GetNextState(room), ShouldEqual, HeatingState_ON)
+		So(room.preheatingFor, ShouldEqual, scheduled)
+
+		// Carries on as the room warms and the prediction moves later.
+		room.LastTemp = 18
+		*now = want.Add(20 * time.Minute)
+		room.readAt = *now
+		So(room.predictedStart.After(*now), ShouldBeFalse)
+		So(c.GetNextState(room), ShouldEqual, HeatingState_ON)
+		So(room.predictedStart.After(*now), ShouldBeTrue)
+		So(room.Pid.Integral(), ShouldEqual, 0)
+
+		// Once warm, the PID controller holds the target.
+		room.LastTemp = 20.5
+		*now = scheduled.Add(-10 * time.Minute)
+		room.readAt = *now
+		c.GetNextState(room)
+		So(room.scheduled, ShouldBeTrue)
+		So(room.Pid.Get(), ShouldEqual, 20)
+
+		*now = scheduled.Add(time.Minute)
+		room.readAt = *now
+		c.GetNextState(room)
+		So(room.preheatingFor.IsZero(), ShouldBeTrue)
+		So(room.predictedStart.IsZero(), ShouldBeTrue)
+	})
+
+	Convey("Preheats for at most the maximum", t, func() {
+		c, room, now := newRoom()
+		c.weather = fixedWeather(-25)
+		*now = day
+		room.readAt = *now
+		c.GetNextState(room)
+		So(room.predictedStart, ShouldEqual, scheduled.Add(-defaultMaxPreheat))
+	})
+
+	Convey("Disabled", t, func() {
+		c, room, now := newRoom()
+		c.optimalStart = false
+		*now = scheduled.Add(-time.Minute)
+		room.readAt = *now
+		So(c.GetNextState(room), ShouldEqual, HeatingState_OFF)
+		So(room.predictedStart.IsZero(), ShouldBeTrue)
+	})
+
+	Convey("Learns from readings", t, func() {
+		c, room, now := newRoom()
+		room.model = ThermalModel{}
+		start := *now
+		// Heating at 1 degree an hour while 10 degrees warmer than outside,
+		// with the radiators on half the time.
+		for i := 0; i <= 60; i++ {
+			*now = start.Add(time.Duration(i) * time.Minute)
+			if i%10 == 0 {
+				room.readAt = *now
+				room.LastTemp = 10 + float64(i)/60
+			}
+			c.learn(room, 0, true, *now)
+			room.state = HeatingState_OFF
+			if i%2 == 0 {
+				room.state = HeatingState_ON
+			}
+		}
+		So(room.model.Samples(), ShouldEqual, 3)
+		So(room.model.dr/room.model.dd, ShouldAlmostEqual, 2, 0.1)
+
+		// Nothing is learnt while held by an override.
+		room.sample.spoilt = true
+		*now = now.Add(30 * time.Minute)
+		room.readAt = *now
+		c.learn(room, 0, true, *now)
+		So(room.model.Samples(), ShouldEqual, 3)
+	})
+}
@@ -1,26 +1,132 @@
 package main
 
 import (
+	"context"
 	"encoding/hex"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"log"
+	"os"
+	"os/signal"
 
 	"github.com/hatstand/shinywaffle"
+	"github.com/hatstand/shinywaffle/capture"
+	"github.com/hatstand/shinywaffle/control"
+	"github.com/hatstand/shinywaffle/radiator"
+	"github.com/hatstand/shinywaffle/sniffer"
 )
 
-func dumpPacket(packet []byte) {
-	fmt.Printf("%s\n", hex.EncodeToString(packet))
+var sniff = flag.Bool("sniff", false, "Decode radiator packets and print them as JSON events")
+var config = flag.String("config", "", "Path to controller config proto, used to name the zone of sniffed radiators")
+var record = flag.String("record", "", "Path to append received frames to as a capture")
+var replay = flag.String("replay", "", "Path to a capture to read frames from instead of the radio")
+var speed = flag.Float64("speed", 1, "How much faster than recorded to replay a capture, or 0 for as fast as possible")
+
+func dumpPacket(f shinywaffle.Frame) {
+	fmt.Printf("%s rssi: %ddBm lqi: %d crc: %v\n", hex.EncodeToString(f.Payload), f.RSSI, f.LQI, f.CRCOK)
+}
+
+// loadRegistry returns the radiators in the controller config, if any.
+func loadRegistry(path string) (*radiator.Registry, error) {
+	if path == "" {
+		return radiator.NewRegistry(), nil
+	}
+	c, err := control.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return control.NewRegistry(c)
+}
+
+// openCapture opens path for appending records to, creating it if necessary.
+func openCapture(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture: %w", err)
+	}
+	return f, nil
+}
+
+// replayFrames returns the received frames from the capture at path.
+func replayFrames(ctx context.Context, path string) (<-chan shinywaffle.Frame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture: %w", err)
+	}
+	defer f.Close()
+	records, err := capture.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return capture.Replay(ctx, records, *speed), nil
 }
 
 func main() {
-	packetCh := make(chan []byte, 10)
-	cc1101 := shinywaffle.NewCC1101(packetCh)
-	defer cc1101.Close()
-	cc1101.SetSyncWord(0xd391)
-
-	for {
-		select {
-		case p := <-packetCh:
-			dumpPacket(p)
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry, err := loadRegistry(*config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var frames <-chan shinywaffle.Frame
+	if *replay != "" {
+		frames, err = replayFrames(ctx, *replay)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		cc1101, err := shinywaffle.NewCC1101()
+		if err != nil {
+			log.Fatalf("Failed to initialise CC1101: %v", err)
+		}
+		defer cc1101.Close()
+		cc1101.SetSyncWord(0xd391)
+
+		frames, err = cc1101.Listen(ctx)
+		if err != nil {
+			log.Fatalf("Failed to listen: %v", err)
 		}
 	}
+
+	if *record != "" {
+		f, err := openCapture(*record)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		frames = capture.RecordFrames(frames, capture.NewWriter(f), func(err error) {
+			log.Printf("Failed to record frame: %v", err)
+		})
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt)
+	go func() {
+		<-ch
+		cancel()
+	}()
+
+	if !*sniff {
+		for f := range frames {
+			dumpPacket(f)
+		}
+		return
+	}
+
+	events := sniffer.New(registry).Run(ctx, frames, func(f shinywaffle.Frame, err error) {
+		log.Printf("Failed to decode %s: %v", hex.EncodeToString(f.Payload), err)
+	})
+	enc := json.NewEncoder(os.Stdout)
+	for e := range events {
+		if err := enc.Encode(e); err != nil {
+			log.Printf("Failed to encode event: %v", err)
+		}
+	}
+	for range frames {
+	}
 }
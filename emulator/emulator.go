@@ -0,0 +1,738 @@
+// Package emulator is an in-memory model of a CC1101 behind its SPI interface
+// so that the driver and everything built on it can be tested without a radio.
+//
+// It models the configuration, status and PATABLE registers, command strobes,
+// the TX and RX FIFOs, enough of the main radio control state machine to send
+// and receive packets, and the GDO0 and GDO2 outputs for the signals the
+// driver configures. Register addresses are deliberately duplicated from the
+// datasheet rather than imported from the driver so that mistakes in the
+// driver's constants are caught.
+package emulator
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/hatstand/shinywaffle/hal"
+)
+
+// Header byte flags.
+const (
+	readFlag  = 0x80
+	burstFlag = 0x40
+)
+
+// Register addresses.
+const (
+	iocfg2   = 0x00
+	iocfg0   = 0x02
+	pktlen   = 0x06
+	pktctrl1 = 0x07
+	pktctrl0 = 0x08
+	mdmcfg4  = 0x10
+	mdmcfg3  = 0x11
+	mdmcfg1  = 0x13
+	mcsm1    = 0x17
+	fstest   = 0x29
+	test0    = 0x2e
+
+	// Command strobes.
+	sres    = 0x30
+	sfstxon = 0x31
+	sxoff   = 0x32
+	scal    = 0x33
+	srx     = 0x34
+	stx     = 0x35
+	sidle   = 0x36
+	swor    = 0x38
+	spwd    = 0x39
+	sfrx    = 0x3a
+	sftx    = 0x3b
+	sworrst = 0x3c
+	snop    = 0x3d
+
+	// Status registers, accessed with the burst bit set.
+	partnum   = 0x30
+	version   = 0x31
+	lqi       = 0x33
+	rssi      = 0x34
+	marcstate = 0x35
+	pktstatus = 0x38
+	txbytes   = 0x3a
+	rxbytes   = 0x3b
+
+	patable = 0x3e
+	fifo    = 0x3f
+)
+
+// MARCSTATE values.
+const (
+	StateSleep           = 0x00
+	StateIdle            = 0x01
+	StateCalibrate       = 0x08
+	StateRX              = 0x0d
+	StateRXFIFOOverflow  = 0x11
+	StateFSTXOn          = 0x12
+	StateTX              = 0x13
+	StateTXFIFOUnderflow = 0x16
+)
+
+const (
+	fifoSize = 64
+	// Largest count of FIFO bytes reported in the chip status byte.
+	maxFIFOBytesAvailable = 0x0f
+	// Set in RXBYTES and TXBYTES on overflow and underflow.
+	overflowFlag = 0x80
+
+	chipPartnum = 0x00
+	chipVersion = 0x14
+
+	rssiOffset = 74
+	crcOK      = 0x80
+
+	// GDOx_CFG signals.
+	gdoSyncWord    = 0x06
+	gdoPacketCRCOK = 0x07
+	gdoInvert      = 0x40
+
+	// PKTCTRL1 and PKTCTRL0 fields.
+	appendStatus   = 0x04
+	lengthConfig   = 0x03
+	variableLength = 0x01
+)
+
+// Reset values of the configuration registers from the datasheet.
+var resetRegisters = [test0 + 1]byte{
+	0x29, 0x2e, 0x3f, 0x07, 0xd3, 0x91, 0xff, 0x04,
+	0x45, 0x00, 0x00, 0x0f, 0x00, 0x1e, 0xc4, 0xec,
+	0x8c, 0x22, 0x02, 0x22, 0xf8, 0x47, 0x07, 0x30,
+	0x04, 0x36, 0x6c, 0x03, 0x40, 0x91, 0x87, 0x6b,
+	0xf8, 0x56, 0x10, 0xa9, 0x0a, 0x20, 0x0d, 0x41,
+	0x00, 0x59, 0x7f, 0x3f, 0x88, 0x31, 0x0b,
+}
+
+// Packet is a packet sent or received over the air.
+type Packet struct {
+	Payload []byte
+	// Signal strength in dBm the packet is received with.
+	RSSI int
+	// Link quality indicator the packet is received with.
+	LQI byte
+	// Whether the packet is received with a bad CRC.
+	CorruptCRC bool
+}
+
+// Channel is the state of the air around the radio, used for clear channel
+// assessment.
+type Channel struct {
+	// Signal strength in dBm reported while in RX.
+	RSSI int
+	// Whether the RSSI is above the carrier sense threshold.
+	CarrierSense bool
+	// Whether the radio is in the middle of receiving a packet.
+	Receiving bool
+}
+
+// Radio is an emulated CC1101. It implements hal.SPI and its GDO pins
+// implement hal.Pin.
+type Radio struct {
+	lock      sync.Mutex
+	registers [test0 + 1]byte
+	patable   [8]byte
+	state     byte
+	rxFIFO    []byte
+	txFIFO    []byte
+	rssi      byte
+	lqi       byte
+	sent      []Packet
+	airtime   time.Duration
+	// Incremented whenever a transmission is started or aborted.
+	txGeneration int
+	gdo0         *Pin
+	gdo2         *Pin
+	closed       bool
+	// Whether the radio is polling with Wake-on-Radio while in SLEEP.
+	wakeOnRadio bool
+	channel     Channel
+}
+
+// New returns a radio in the state it is in after power on.
+func New() *Radio {
+	r := &Radio{}
+	r.gdo0 = &Pin{radio: r}
+	r.gdo2 = &Pin{radio: r}
+	r.reset()
+	return r
+}
+
+func (r *Radio) reset() {
+	r.registers = resetRegisters
+	r.patable = [8]byte{0xc6}
+	r.state = StateIdle
+	r.rxFIFO = nil
+	r.txFIFO = nil
+	r.txGeneration++
+	r.updatePins()
+}
+
+// GDO0 returns the pin connected to GDO0.
+func (r *Radio) GDO0() *Pin {
+	return r.gdo0
+}
+
+// GDO2 returns the pin connected to GDO2.
+func (r *Radio) GDO2() *Pin {
+	return r.gdo2
+}
+
+// SetAirtime overrides how long each transmission takes. By default it is
+// calculated from the data rate and packet length.
+func (r *Radio) SetAirtime(d time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.airtime = d
+}
+
+// State returns the current MARCSTATE.
+func (r *Radio) State() byte {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.state
+}
+
+// Register returns the value of a configuration register.
+func (r *Radio) Register(address byte) byte {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.registers[address]
+}
+
+// PATable returns the contents of the PATABLE.
+func (r *Radio) PATable() [8]byte {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.patable
+}
+
+// WakeOnRadio reports whether the radio is sleeping between Wake-on-Radio polls.
+func (r *Radio) WakeOnRadio() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.wakeOnRadio
+}
+
+// Sent returns every packet transmitted so far.
+func (r *Radio) Sent() []Packet {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Packet(nil), r.sent...)
+}
+
+// Closed reports whether Close has been called.
+func (r *Radio) Closed() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.closed
+}
+
+// SetChannel changes the state of the channel seen by the radio.
+func (r *Radio) SetChannel(c Channel) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.channel = c
+	r.rssi = encodeRSSI(c.RSSI)
+}
+
+// Receive delivers a packet over the air. It returns false if the radio is
+// not in RX or polling with Wake-on-Radio, or the packet is filtered out.
+// Packets are assumed to have a long enough preamble to be caught by
+// Wake-on-Radio.
+func (r *Radio) Receive(p Packet) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.state == StateSleep && r.wakeOnRadio {
+		// Woken by EVENT0, the radio stops polling once it has a packet.
+		r.state = StateRX
+		r.wakeOnRadio = false
+	}
+	if r.state != StateRX {
+		return false
+	}
+	if r.registers[pktctrl0]&lengthConfig == variableLength && len(p.Payload) > int(r.registers[pktlen]) {
+		return false
+	}
+
+	r.setSignal(gdoSyncWord, true)
+	r.setSignal(gdoSyncWord, false)
+
+	data := []byte{byte(len(p.Payload))}
+	if r.registers[pktctrl0]&lengthConfig != variableLength {
+		data = nil
+	}
+	data = append(data, p.Payload...)
+	r.rssi = encodeRSSI(p.RSSI)
+	r.lqi = p.LQI & 0x7f
+	if !p.CorruptCRC {
+		r.lqi |= crcOK
+	}
+	if r.registers[pktctrl1]&appendStatus != 0 {
+		data = append(data, r.rssi, r.lqi)
+	}
+	if len(r.rxFIFO)+len(data) > fifoSize {
+		r.rxFIFO = append(r.rxFIFO, data[:fifoSize-len(r.rxFIFO)]...)
+		r.state = StateRXFIFOOverflow
+		return true
+	}
+	r.rxFIFO = append(r.rxFIFO, data...)
+	if !p.CorruptCRC {
+		r.setSignal(gdoPacketCRCOK, true)
+	}
+	// RXOFF_MODE
+	switch (r.registers[mcsm1] >> 2) & 0x03 {
+	case 0:
+		r.state = StateIdle
+	case 1:
+		r.state = StateFSTXOn
+	case 2:
+		r.startTX()
+	case 3:
+		r.state = StateRX
+	}
+	return true
+}
+
+func encodeRSSI(dBm int) byte {
+	return byte(int8(math.Max(-128, math.Min(127, float64((dBm+rssiOffset)*2)))))
+}
+
+// TransferAndReceiveData implements hal.SPI. A transfer is equivalent to one
+// period of CSn being held low.
+func (r *Radio) TransferAndReceiveData(data []byte) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		return fmt.Errorf("SPI bus closed")
+	}
+	if r.state == StateSleep && len(data) > 0 {
+		// Pulling CSn low wakes the chip.
+		r.state = StateIdle
+		r.wakeOnRadio = false
+	}
+
+	for i := 0; i < len(data); {
+		header := data[i]
+		read := header&readFlag != 0
+		burst := header&burstFlag != 0
+		address := header & 0x3f
+		data[i] = r.status(read)
+		i++
+
+		switch {
+		case address >= sres && address <= snop && !burst:
+			r.strobe(address)
+		case address >= partnum && address <= snop && burst:
+			if i < len(data) {
+				data[i] = r.statusRegister(address)
+				i++
+			}
+		case address == patable:
+			for n := 0; i < len(data) && n < len(r.patable); n++ {
+				if read {
+					data[i] = r.patable[n]
+				} else {
+					r.patable[n] = data[i]
+				}
+				i++
+				if !burst {
+					break
+				}
+			}
+		case address == fifo:
+			for i < len(data) {
+				if read {
+					data[i] = r.readRXFIFO()
+				} else {
+					b := data[i]
+					data[i] = r.status(false)
+					r.writeTXFIFO(b)
+				}
+				i++
+				if !burst {
+					break
+				}
+			}
+		case address <= test0:
+			for a := address; i < len(data) && a <= test0; a++ {
+				if read {
+					data[i] = r.registers[a]
+				} else {
+					b := data[i]
+					data[i] = r.status(false)
+					r.registers[a] = b
+				}
+				i++
+				if !burst {
+					break
+				}
+			}
+			r.updatePins()
+		default:
+			// Unused address 0x2f.
+			if i < len(data) {
+				data[i] = 0
+				i++
+			}
+		}
+	}
+	return nil
+}
+
+// Close implements hal.SPI.
+func (r *Radio) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.closed = true
+	return nil
+}
+
+// status returns the chip status byte sent in response to every header byte.
+func (r *Radio) status(read bool) byte {
+	var state byte
+	switch r.state {
+	case StateIdle:
+		state = 0
+	case StateRX:
+		state = 1
+	case StateTX:
+		state = 2
+	case StateFSTXOn:
+		state = 3
+	case StateCalibrate:
+		state = 4
+	case StateRXFIFOOverflow:
+		state = 6
+	case StateTXFIFOUnderflow:
+		state = 7
+	}
+	var available int
+	if read {
+		available = len(r.rxFIFO)
+	} else {
+		available = fifoSize - len(r.txFIFO)
+	}
+	if available > maxFIFOBytesAvailable {
+		available = maxFIFOBytesAvailable
+	}
+	return state<<4 | byte(available)
+}
+
+func (r *Radio) statusRegister(address byte) byte {
+	switch address {
+	case partnum:
+		return chipPartnum
+	case version:
+		return chipVersion
+	case lqi:
+		return r.lqi
+	case rssi:
+		return r.rssi
+	case marcstate:
+		return r.state
+	case pktstatus:
+		var s byte
+		if r.lqi&crcOK != 0 {
+			s |= 0x80
+		}
+		if r.state == StateRX && r.channel.CarrierSense {
+			s |= 0x40
+		}
+		if r.clearChannel() {
+			s |= 0x10
+		}
+		return s
+	case txbytes:
+		b := byte(len(r.txFIFO))
+		if r.state == StateTXFIFOUnderflow {
+			b |= overflowFlag
+		}
+		return b
+	case rxbytes:
+		b := byte(len(r.rxFIFO))
+		if r.state == StateRXFIFOOverflow {
+			b |= overflowFlag
+		}
+		return b
+	default:
+		return 0
+	}
+}
+
+func (r *Radio) strobe(s byte) {
+	switch s {
+	case sres:
+		r.reset()
+	case sfstxon:
+		if r.state == StateIdle {
+			r.state = StateFSTXOn
+		}
+	case sxoff, scal, snop, sworrst:
+	case srx:
+		if r.state == StateIdle || r.state == StateFSTXOn {
+			r.state = StateRX
+		}
+	case stx:
+		if r.state == StateRX && !r.clearChannel() {
+			// Stays in RX if the channel is busy.
+			return
+		}
+		if r.state == StateIdle || r.state == StateRX || r.state == StateFSTXOn {
+			r.startTX()
+		}
+	case sidle:
+		switch r.state {
+		case StateRXFIFOOverflow, StateTXFIFOUnderflow:
+			// Only leaves these states once the FIFO is flushed.
+		case StateTX:
+			r.abortTX()
+			r.state = StateIdle
+		default:
+			r.state = StateIdle
+		}
+	case swor, spwd:
+		if r.state == StateIdle {
+			r.sleep()
+			r.wakeOnRadio = s == swor
+		}
+	case sfrx:
+		if r.state == StateIdle || r.state == StateRXFIFOOverflow {
+			r.rxFIFO = nil
+			r.state = StateIdle
+			r.setSignal(gdoPacketCRCOK, false)
+		}
+	case sftx:
+		if r.state == StateIdle || r.state == StateTXFIFOUnderflow {
+			r.txFIFO = nil
+			r.state = StateIdle
+		}
+	}
+}
+
+// clearChannel reports whether the channel is clear according to CCA_MODE.
+func (r *Radio) clearChannel() bool {
+	if r.state != StateRX {
+		return false
+	}
+	switch (r.registers[mcsm1] >> 4) & 0x03 {
+	case 1:
+		return !r.channel.CarrierSense
+	case 2:
+		return !r.channel.Receiving
+	case 3:
+		return !r.channel.CarrierSense && !r.channel.Receiving
+	default:
+		return true
+	}
+}
+
+// sleep enters SLEEP, losing the values of the test registers and all but
+// the first byte of the PATABLE.
+func (r *Radio) sleep() {
+	r.state = StateSleep
+	copy(r.registers[fstest:], resetRegisters[fstest:])
+	for i := 1; i < len(r.patable); i++ {
+		r.patable[i] = 0
+	}
+}
+
+func (r *Radio) readRXFIFO() byte {
+	if len(r.rxFIFO) == 0 {
+		return 0
+	}
+	b := r.rxFIFO[0]
+	r.rxFIFO = r.rxFIFO[1:]
+	// GDO0 0x07 de-asserts when the first byte is read out of the FIFO.
+	r.setSignal(gdoPacketCRCOK, false)
+	return b
+}
+
+func (r *Radio) writeTXFIFO(b byte) {
+	if len(r.txFIFO) >= fifoSize {
+		r.state = StateTXFIFOUnderflow
+		return
+	}
+	r.txFIFO = append(r.txFIFO, b)
+}
+
+// startTX sends the packet in the TX FIFO, taking the airtime to do so.
+func (r *Radio) startTX() {
+	var payload []byte
+	if r.registers[pktctrl0]&lengthConfig == variableLength {
+		if len(r.txFIFO) == 0 || len(r.txFIFO) < 1+int(r.txFIFO[0]) {
+			r.state = StateTXFIFOUnderflow
+			return
+		}
+		n := int(r.txFIFO[0])
+		payload = append([]byte(nil), r.txFIFO[1:1+n]...)
+		r.txFIFO = r.txFIFO[1+n:]
+	} else {
+		n := int(r.registers[pktlen])
+		if len(r.txFIFO) < n {
+			r.state = StateTXFIFOUnderflow
+			return
+		}
+		payload = append([]byte(nil), r.txFIFO[:n]...)
+		r.txFIFO = r.txFIFO[n:]
+	}
+
+	r.state = StateTX
+	r.txGeneration++
+	generation := r.txGeneration
+	airtime := r.airtime
+	if airtime == 0 {
+		airtime = r.packetAirtime(len(payload))
+	}
+	go func() {
+		// Sync word is sent after the preamble, roughly a quarter of the way through.
+		time.Sleep(airtime / 4)
+		r.lock.Lock()
+		if r.txGeneration != generation {
+			r.lock.Unlock()
+			return
+		}
+		r.setSignal(gdoSyncWord, true)
+		r.lock.Unlock()
+
+		time.Sleep(airtime - airtime/4)
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		if r.txGeneration != generation {
+			return
+		}
+		r.sent = append(r.sent, Packet{Payload: payload})
+		r.setSignal(gdoSyncWord, false)
+		r.finishTX()
+	}()
+}
+
+// finishTX moves to the state given by TXOFF_MODE.
+func (r *Radio) finishTX() {
+	switch r.registers[mcsm1] & 0x03 {
+	case 0:
+		r.state = StateIdle
+	case 1:
+		r.state = StateFSTXOn
+	case 2:
+		r.startTX()
+	case 3:
+		r.state = StateRX
+	}
+}
+
+func (r *Radio) abortTX() {
+	r.txGeneration++
+	r.setSignal(gdoSyncWord, false)
+}
+
+// packetAirtime returns how long it takes to send a payload of n bytes.
+func (r *Radio) packetAirtime(n int) time.Duration {
+	e := r.registers[mdmcfg4] & 0x0f
+	m := r.registers[mdmcfg3]
+	baud := float64(256+int(m)) * math.Pow(2, float64(e)) / (1 << 28) * 26e6
+	preamble := []int{2, 3, 4, 6, 8, 12, 16, 24}[(r.registers[mdmcfg1]>>4)&0x07]
+	// Preamble, sync word, length byte, payload and CRC.
+	bits := float64(8 * (preamble + 2 + 1 + n + 2))
+	return time.Duration(bits / baud * float64(time.Second))
+}
+
+// setSignal drives any GDO pins configured to output fn.
+func (r *Radio) setSignal(fn byte, level bool) {
+	for _, p := range []struct {
+		pin *Pin
+		cfg byte
+	}{
+		{r.gdo0, r.registers[iocfg0]},
+		{r.gdo2, r.registers[iocfg2]},
+	} {
+		if p.cfg&0x3f == fn {
+			p.pin.set(level != (p.cfg&gdoInvert != 0))
+		}
+	}
+}
+
+// updatePins resets pins after their configuration has changed.
+func (r *Radio) updatePins() {
+	for _, p := range []struct {
+		pin *Pin
+		cfg byte
+	}{
+		{r.gdo0, r.registers[iocfg0]},
+		{r.gdo2, r.registers[iocfg2]},
+	} {
+		if p.pin.cfg != p.cfg {
+			p.pin.cfg = p.cfg
+			p.pin.set(p.cfg&gdoInvert != 0)
+		}
+	}
+}
+
+// Pin is an emulated GDO output. It implements hal.Pin.
+type Pin struct {
+	radio   *Radio
+	cfg     byte
+	level   bool
+	edge    hal.Edge
+	handler func()
+}
+
+// set changes the level of the pin and notifies any watcher. Called with the
+// radio lock held.
+func (p *Pin) set(level bool) {
+	if level == p.level {
+		return
+	}
+	p.level = level
+	if p.handler == nil {
+		return
+	}
+	if p.edge == hal.EdgeBoth || (level && p.edge == hal.EdgeRising) || (!level && p.edge == hal.EdgeFalling) {
+		go p.handler()
+	}
+}
+
+// Level returns whether the pin is high.
+func (p *Pin) Level() bool {
+	p.radio.lock.Lock()
+	defer p.radio.lock.Unlock()
+	return p.level
+}
+
+// Watch implements hal.Pin.
+func (p *Pin) Watch(edge hal.Edge, handler func()) error {
+	p.radio.lock.Lock()
+	defer p.radio.lock.Unlock()
+	if p.handler != nil {
+		return fmt.Errorf("pin is already being watched")
+	}
+	p.edge = edge
+	p.handler = handler
+	return nil
+}
+
+// StopWatching implements hal.Pin.
+func (p *Pin) StopWatching() error {
+	p.radio.lock.Lock()
+	defer p.radio.lock.Unlock()
+	p.handler = nil
+	return nil
+}
+
+// Close implements hal.Pin.
+func (p *Pin) Close() error {
+	p.radio.lock.Lock()
+	defer p.radio.lock.Unlock()
+	p.handler = nil
+	return nil
+}
@@ -0,0 +1,190 @@
+package emulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle/hal"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegisters(t *testing.T) {
+	Convey("Reset values", t, func() {
+		r := New()
+		data := []byte{0x04 | readFlag | burstFlag, 0x00, 0x00}
+		So(r.TransferAndReceiveData(data), ShouldBeNil)
+		So(data[1:], ShouldResemble, []byte{0xd3, 0x91})
+	})
+
+	Convey("Burst write then single read", t, func() {
+		r := New()
+		So(r.TransferAndReceiveData([]byte{0x0d | burstFlag, 0x21, 0x65, 0x6a}), ShouldBeNil)
+		data := []byte{0x0e | readFlag, 0x00}
+		So(r.TransferAndReceiveData(data), ShouldBeNil)
+		So(data[1], ShouldEqual, 0x65)
+		So(r.Register(0x0f), ShouldEqual, 0x6a)
+	})
+
+	Convey("Status registers", t, func() {
+		r := New()
+		data := []byte{version | readFlag | burstFlag, 0x00}
+		So(r.TransferAndReceiveData(data), ShouldBeNil)
+		So(data[1], ShouldEqual, 0x14)
+
+		data = []byte{marcstate | readFlag | burstFlag, 0x00}
+		So(r.TransferAndReceiveData(data), ShouldBeNil)
+		So(data[1], ShouldEqual, StateIdle)
+	})
+
+	Convey("PATABLE", t, func() {
+		r := New()
+		So(r.TransferAndReceiveData([]byte{patable | burstFlag, 0x00, 0x50}), ShouldBeNil)
+		So(r.PATable(), ShouldResemble, [8]byte{0x00, 0x50})
+	})
+}
+
+func TestStrobes(t *testing.T) {
+	Convey("Chip status byte", t, func() {
+		r := New()
+		data := []byte{srx, snop}
+		So(r.TransferAndReceiveData(data), ShouldBeNil)
+		// IDLE then RX with the TX FIFO empty.
+		So(data, ShouldResemble, []byte{0x0f, 0x1f})
+		So(r.State(), ShouldEqual, StateRX)
+	})
+
+	Convey("Sleep", t, func() {
+		r := New()
+		So(r.TransferAndReceiveData([]byte{spwd}), ShouldBeNil)
+		So(r.State(), ShouldEqual, StateSleep)
+		// Any access wakes the chip.
+		So(r.TransferAndReceiveData([]byte{snop}), ShouldBeNil)
+		So(r.State(), ShouldEqual, StateIdle)
+	})
+}
+
+func TestTransmit(t *testing.T) {
+	Convey("Transmit", t, func() {
+		r := New()
+		r.SetAirtime(time.Millisecond)
+		// Variable length packets with GDO2 asserted while sending.
+		So(r.TransferAndReceiveData([]byte{iocfg2, gdoSyncWord}), ShouldBeNil)
+		So(r.TransferAndReceiveData([]byte{pktctrl0, 0x05}), ShouldBeNil)
+
+		done := make(chan struct{})
+		So(r.GDO2().Watch(hal.EdgeFalling, func() { close(done) }), ShouldBeNil)
+		So(r.TransferAndReceiveData([]byte{fifo | burstFlag, 0x02, 0x42, 0x43}), ShouldBeNil)
+		So(r.TransferAndReceiveData([]byte{stx}), ShouldBeNil)
+		So(r.State(), ShouldEqual, StateTX)
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("GDO2 did not fall")
+		}
+		So(r.Sent(), ShouldResemble, []Packet{{Payload: []byte{0x42, 0x43}}})
+		So(r.State(), ShouldEqual, StateIdle)
+	})
+
+	Convey("Clear channel assessment", t, func() {
+		r := New()
+		// CCA_MODE 3
+		So(r.TransferAndReceiveData([]byte{mcsm1, 0x30}), ShouldBeNil)
+		So(r.TransferAndReceiveData([]byte{fifo | burstFlag, 0x01, 0x42}), ShouldBeNil)
+		So(r.TransferAndReceiveData([]byte{srx}), ShouldBeNil)
+
+		r.SetChannel(Channel{RSSI: -50, CarrierSense: true})
+		So(r.statusRegister(pktstatus), ShouldEqual, 0x40)
+		So(r.TransferAndReceiveData([]byte{stx}), ShouldBeNil)
+		So(r.State(), ShouldEqual, StateRX)
+
+		r.SetChannel(Channel{RSSI: -100, Receiving: true})
+		So(r.TransferAndReceiveData([]byte{stx}), ShouldBeNil)
+		So(r.State(), ShouldEqual, StateRX)
+
+		r.SetChannel(Channel{RSSI: -100})
+		So(r.statusRegister(pktstatus), ShouldEqual, 0x10)
+		So(r.TransferAndReceiveData([]byte{stx}), ShouldBeNil)
+		So(r.State(), ShouldEqual, StateTX)
+	})
+
+	Convey("Underflow", t, func() {
+		r := New()
+		So(r.TransferAndReceiveData([]byte{pktctrl0, 0x05}), ShouldBeNil)
+		So(r.TransferAndReceiveData([]byte{fifo, 0x02}), ShouldBeNil)
+		So(r.TransferAndReceiveData([]byte{stx}), ShouldBeNil)
+		So(r.State(), ShouldEqual, StateTXFIFOUnderflow)
+		So(r.TransferAndReceiveData([]byte{sftx}), ShouldBeNil)
+		So(r.State(), ShouldEqual, StateIdle)
+	})
+}
+
+func TestReceive(t *testing.T) {
+	Convey("Receive", t, func() {
+		r := New()
+		// GDO0 asserts on CRC OK and variable length packets with status bytes appended.
+		So(r.TransferAndReceiveData([]byte{iocfg0, gdoPacketCRCOK}), ShouldBeNil)
+		So(r.TransferAndReceiveData([]byte{pktctrl0, 0x05}), ShouldBeNil)
+
+		So(r.Receive(Packet{Payload: []byte{0x42}}), ShouldBeFalse)
+
+		So(r.TransferAndReceiveData([]byte{srx}), ShouldBeNil)
+		So(r.Receive(Packet{Payload: []byte{0x42, 0x43}, RSSI: -50, LQI: 3}), ShouldBeTrue)
+		So(r.GDO0().Level(), ShouldBeTrue)
+		So(r.State(), ShouldEqual, StateIdle)
+
+		data := []byte{rxbytes | readFlag | burstFlag, 0x00}
+		So(r.TransferAndReceiveData(data), ShouldBeNil)
+		So(data[1], ShouldEqual, 5)
+
+		data = make([]byte, 6)
+		data[0] = fifo | readFlag | burstFlag
+		So(r.TransferAndReceiveData(data), ShouldBeNil)
+		So(data[1:], ShouldResemble, []byte{0x02, 0x42, 0x43, 48, crcOK | 3})
+		So(r.GDO0().Level(), ShouldBeFalse)
+	})
+
+	Convey("Overflow", t, func() {
+		r := New()
+		So(r.TransferAndReceiveData([]byte{pktctrl0, 0x05}), ShouldBeNil)
+		// Stay in RX after receiving.
+		So(r.TransferAndReceiveData([]byte{mcsm1, 0x3c}), ShouldBeNil)
+		So(r.TransferAndReceiveData([]byte{srx}), ShouldBeNil)
+		for i := 0; i < 2; i++ {
+			So(r.Receive(Packet{Payload: make([]byte, 30)}), ShouldBeTrue)
+		}
+		So(r.State(), ShouldEqual, StateRXFIFOOverflow)
+		So(r.TransferAndReceiveData([]byte{sidle}), ShouldBeNil)
+		So(r.State(), ShouldEqual, StateRXFIFOOverflow)
+		So(r.TransferAndReceiveData([]byte{sfrx}), ShouldBeNil)
+		So(r.State(), ShouldEqual, StateIdle)
+	})
+}
+
+func TestSleep(t *testing.T) {
+	Convey("Sleep loses test registers", t, func() {
+		r := New()
+		So(r.TransferAndReceiveData([]byte{test0, 0x09}), ShouldBeNil)
+		So(r.TransferAndReceiveData([]byte{patable | burstFlag, 0x00, 0x50}), ShouldBeNil)
+		So(r.TransferAndReceiveData([]byte{spwd}), ShouldBeNil)
+		So(r.Register(test0), ShouldEqual, 0x0b)
+		So(r.PATable(), ShouldResemble, [8]byte{0x00})
+	})
+
+	Convey("Wake-on-Radio", t, func() {
+		r := New()
+		So(r.TransferAndReceiveData([]byte{pktctrl0, 0x05}), ShouldBeNil)
+		So(r.TransferAndReceiveData([]byte{swor}), ShouldBeNil)
+		So(r.State(), ShouldEqual, StateSleep)
+		So(r.WakeOnRadio(), ShouldBeTrue)
+
+		So(r.Receive(Packet{Payload: []byte{0x42}}), ShouldBeTrue)
+		So(r.WakeOnRadio(), ShouldBeFalse)
+		So(r.State(), ShouldEqual, StateIdle)
+
+		// Powered down radios don't receive anything.
+		So(r.TransferAndReceiveData([]byte{spwd}), ShouldBeNil)
+		So(r.Receive(Packet{Payload: []byte{0x42}}), ShouldBeFalse)
+	})
+}
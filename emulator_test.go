@@ -0,0 +1,79 @@
+package shinywaffle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle/emulator"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// WithEmulator runs f against a driver talking to an emulated CC1101.
+func WithEmulator(t *testing.T, f func(radio *emulator.Radio, cc1101 *CC1101)) func() {
+	return func() {
+		radio := emulator.New()
+		radio.SetAirtime(5 * time.Millisecond)
+		cc1101, err := New(radio, radio.GDO0(), radio.GDO2(), DefaultProfile())
+		So(err, ShouldBeNil)
+		defer cc1101.Close()
+		f(radio, cc1101)
+	}
+}
+
+func TestEmulatedRadio(t *testing.T) {
+	Convey("Init configures the radio", t, WithEmulator(t, func(radio *emulator.Radio, cc1101 *CC1101) {
+		for _, r := range DefaultProfile().registers {
+			So(radio.Register(r.address), ShouldEqual, r.value)
+		}
+		So(radio.State(), ShouldEqual, emulator.StateIdle)
+	}))
+
+	Convey("Send", t, WithEmulator(t, func(radio *emulator.Radio, cc1101 *CC1101) {
+		So(cc1101.Send([]byte{0x42, 0x43, 0x44}), ShouldBeNil)
+		So(radio.Sent(), ShouldResemble, []emulator.Packet{{Payload: []byte{0x42, 0x43, 0x44}}})
+		So(radio.State(), ShouldEqual, emulator.StateIdle)
+	}))
+
+	Convey("Status", t, WithEmulator(t, func(radio *emulator.Radio, cc1101 *CC1101) {
+		So(cc1101.SetRx(), ShouldBeNil)
+		So(radio.Receive(emulator.Packet{Payload: []byte{0x42, 0x43}, RSSI: -60, LQI: 5}), ShouldBeTrue)
+		status, err := cc1101.Status()
+		So(err, ShouldBeNil)
+		So(status.State, ShouldEqual, MARCSTATE_IDLE)
+		// Length, payload and two status bytes.
+		So(status.RXBytes, ShouldEqual, 5)
+		So(status.RXOverflow, ShouldBeFalse)
+		So(status.RSSI, ShouldEqual, -60)
+		So(status.LQI, ShouldEqual, 5)
+		So(status.CRCOK, ShouldBeTrue)
+	}))
+
+	Convey("Listen", t, WithEmulator(t, func(radio *emulator.Radio, cc1101 *CC1101) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		frames, err := cc1101.Listen(ctx)
+		So(err, ShouldBeNil)
+		So(radio.State(), ShouldEqual, emulator.StateRX)
+
+		So(radio.Receive(emulator.Packet{Payload: []byte{0x42, 0x43}, RSSI: -60, LQI: 5}), ShouldBeTrue)
+		f := <-frames
+		So(f.Payload, ShouldResemble, []byte{0x42, 0x43})
+		So(f.RSSI, ShouldEqual, -60)
+		So(f.LQI, ShouldEqual, 5)
+		So(f.CRCOK, ShouldBeTrue)
+
+		// Sending while listening returns to RX afterwards.
+		So(cc1101.Send([]byte{0x01}), ShouldBeNil)
+		So(radio.State(), ShouldEqual, emulator.StateRX)
+		So(radio.Receive(emulator.Packet{Payload: []byte{0x44}}), ShouldBeTrue)
+		f = <-frames
+		So(f.Payload, ShouldResemble, []byte{0x44})
+
+		cancel()
+		for range frames {
+		}
+		So(radio.State(), ShouldEqual, emulator.StateIdle)
+	}))
+}
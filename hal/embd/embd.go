@@ -0,0 +1,116 @@
+//go:build embd
+
+// Package embd implements hal on top of github.com/kidoman/embd. It is only
+// built with -tags embd as embd is no longer maintained.
+package embd
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hatstand/shinywaffle/hal"
+	"github.com/kidoman/embd"
+	_ "github.com/kidoman/embd/host/rpi"
+)
+
+// embd has global SPI and GPIO drivers that have to be initialised before
+// opening anything and are closed once nothing is using them.
+var (
+	lock     sync.Mutex
+	spiUsers int
+	pinUsers int
+)
+
+func acquire(users *int, init func() error) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if *users == 0 {
+		if err := init(); err != nil {
+			return err
+		}
+	}
+	*users++
+	return nil
+}
+
+func release(users *int, close func() error) error {
+	lock.Lock()
+	defer lock.Unlock()
+	*users--
+	if *users == 0 {
+		return close()
+	}
+	return nil
+}
+
+type spi struct {
+	embd.SPIBus
+}
+
+// OpenSPI opens the device on chip select channel of the first SPI bus in
+// mode 0 with 8 bit words.
+func OpenSPI(channel byte, speedHz int) (hal.SPI, error) {
+	if err := acquire(&spiUsers, embd.InitSPI); err != nil {
+		return nil, fmt.Errorf("failed to initialise SPI: %w", err)
+	}
+	return &spi{embd.NewSPIBus(embd.SPIMode0, channel, speedHz, 8, 0)}, nil
+}
+
+func (s *spi) Close() error {
+	err := s.SPIBus.Close()
+	if cerr := release(&spiUsers, embd.CloseSPI); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type pin struct {
+	pin embd.DigitalPin
+}
+
+// OpenPin opens GPIO n (BCM numbering) as an input.
+func OpenPin(n int) (hal.Pin, error) {
+	if err := acquire(&pinUsers, embd.InitGPIO); err != nil {
+		return nil, fmt.Errorf("failed to initialise GPIO: %w", err)
+	}
+	p, err := embd.NewDigitalPin(n)
+	if err != nil {
+		release(&pinUsers, embd.CloseGPIO)
+		return nil, err
+	}
+	if err := p.SetDirection(embd.In); err != nil {
+		p.Close()
+		release(&pinUsers, embd.CloseGPIO)
+		return nil, err
+	}
+	return &pin{p}, nil
+}
+
+func (p *pin) Watch(edge hal.Edge, handler func()) error {
+	var e embd.Edge
+	switch edge {
+	case hal.EdgeRising:
+		e = embd.EdgeRising
+	case hal.EdgeFalling:
+		e = embd.EdgeFalling
+	case hal.EdgeBoth:
+		e = embd.EdgeBoth
+	default:
+		return fmt.Errorf("invalid edge: %v", edge)
+	}
+	return p.pin.Watch(e, func(embd.DigitalPin) {
+		handler()
+	})
+}
+
+func (p *pin) StopWatching() error {
+	return p.pin.StopWatching()
+}
+
+func (p *pin) Close() error {
+	err := p.pin.Close()
+	if cerr := release(&pinUsers, embd.CloseGPIO); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,45 @@
+// Package hal defines the small slice of SPI and GPIO functionality that the
+// radio drivers need, so that they can run on top of embd, periph.io or the
+// Linux character devices directly.
+package hal
+
+import "fmt"
+
+// SPI is a full duplex connection to a single device on an SPI bus.
+type SPI interface {
+	// TransferAndReceiveData clocks out data and overwrites it with the bytes
+	// clocked in at the same time.
+	TransferAndReceiveData(data []byte) error
+	Close() error
+}
+
+// Edge is a transition of a GPIO input.
+type Edge int
+
+const (
+	EdgeRising Edge = iota + 1
+	EdgeFalling
+	EdgeBoth
+)
+
+func (e Edge) String() string {
+	switch e {
+	case EdgeRising:
+		return "rising"
+	case EdgeFalling:
+		return "falling"
+	case EdgeBoth:
+		return "both"
+	default:
+		return fmt.Sprintf("Edge(%d)", int(e))
+	}
+}
+
+// Pin is a GPIO input that can notify of edges.
+type Pin interface {
+	// Watch calls handler on another goroutine whenever edge is seen until
+	// StopWatching is called. Only one watch may be active at a time.
+	Watch(edge Edge, handler func()) error
+	StopWatching() error
+	Close() error
+}
@@ -0,0 +1,131 @@
+//go:build linux
+
+package linux
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"unsafe"
+
+	"github.com/hatstand/shinywaffle/hal"
+	"golang.org/x/sys/unix"
+)
+
+// GPIO character device v1 ABI from linux/gpio.h.
+const (
+	// GPIO_GET_LINEEVENT_IOCTL
+	gpioGetLineEventIOCTL = 0xc030b404
+
+	gpioHandleRequestInput = 1 << 0
+
+	gpioEventRequestRisingEdge  = 1 << 0
+	gpioEventRequestFallingEdge = 1 << 1
+	gpioEventRequestBothEdges   = gpioEventRequestRisingEdge | gpioEventRequestFallingEdge
+
+	// Size of struct gpioevent_data.
+	gpioEventDataSize = 16
+)
+
+// gpioEventRequest is struct gpioevent_request.
+type gpioEventRequest struct {
+	lineOffset    uint32
+	handleFlags   uint32
+	eventFlags    uint32
+	consumerLabel [32]byte
+	fd            int32
+}
+
+type line struct {
+	chip   *os.File
+	offset uint32
+	lock   sync.Mutex
+	// Event file for the active watch.
+	events *os.File
+	done   chan struct{}
+}
+
+// OpenPin opens line offset of a GPIO chip such as /dev/gpiochip0 as an input.
+// On a Raspberry Pi the line offsets of gpiochip0 are the BCM GPIO numbers.
+func OpenPin(chip string, offset int) (hal.Pin, error) {
+	f, err := os.OpenFile(chip, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &line{chip: f, offset: uint32(offset)}, nil
+}
+
+func (l *line) Watch(edge hal.Edge, handler func()) error {
+	req := gpioEventRequest{
+		lineOffset:  l.offset,
+		handleFlags: gpioHandleRequestInput,
+	}
+	switch edge {
+	case hal.EdgeRising:
+		req.eventFlags = gpioEventRequestRisingEdge
+	case hal.EdgeFalling:
+		req.eventFlags = gpioEventRequestFallingEdge
+	case hal.EdgeBoth:
+		req.eventFlags = gpioEventRequestBothEdges
+	default:
+		return fmt.Errorf("invalid edge: %v", edge)
+	}
+	copy(req.consumerLabel[:], "shinywaffle")
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.events != nil {
+		return fmt.Errorf("line %d is already being watched", l.offset)
+	}
+	if err := ioctl(l.chip.Fd(), gpioGetLineEventIOCTL, unsafe.Pointer(&req)); err != nil {
+		return fmt.Errorf("failed to request events for line %d: %w", l.offset, err)
+	}
+	// Non-blocking so that reads go through the runtime poller and are
+	// interrupted by closing the file.
+	if err := unix.SetNonblock(int(req.fd), true); err != nil {
+		unix.Close(int(req.fd))
+		return err
+	}
+	l.events = os.NewFile(uintptr(req.fd), fmt.Sprintf("gpio line %d events", l.offset))
+	l.done = make(chan struct{})
+	go l.watch(l.events, handler, l.done)
+	return nil
+}
+
+func (l *line) watch(events *os.File, handler func(), done chan<- struct{}) {
+	defer close(done)
+	buf := make([]byte, gpioEventDataSize)
+	for {
+		if _, err := events.Read(buf); err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				log.Printf("Failed to read events for line %d: %v", l.offset, err)
+			}
+			return
+		}
+		// The kernel only reports the requested edges so there is no need to
+		// check the event id.
+		handler()
+	}
+}
+
+func (l *line) StopWatching() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.events == nil {
+		return nil
+	}
+	err := l.events.Close()
+	<-l.done
+	l.events = nil
+	l.done = nil
+	return err
+}
+
+func (l *line) Close() error {
+	if err := l.StopWatching(); err != nil {
+		return err
+	}
+	return l.chip.Close()
+}
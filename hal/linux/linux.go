@@ -0,0 +1,3 @@
+// Package linux implements hal directly on the Linux spidev and GPIO
+// character device interfaces, without any third party libraries.
+package linux
@@ -0,0 +1,17 @@
+//go:build linux
+
+package linux
+
+import (
+	"testing"
+	"unsafe"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestABI(t *testing.T) {
+	Convey("Struct sizes match the kernel headers", t, func() {
+		So(unsafe.Sizeof(spiIOCTransfer{}), ShouldEqual, 32)
+		So(unsafe.Sizeof(gpioEventRequest{}), ShouldEqual, 48)
+	})
+}
@@ -0,0 +1,102 @@
+//go:build linux
+
+package linux
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"github.com/hatstand/shinywaffle/hal"
+	"golang.org/x/sys/unix"
+)
+
+// ioctl requests from linux/spi/spidev.h.
+const (
+	spiIOCWrMode        = 0x40016b01
+	spiIOCWrBitsPerWord = 0x40016b03
+	spiIOCWrMaxSpeedHz  = 0x40046b04
+	// SPI_IOC_MESSAGE(1)
+	spiIOCMessage1 = 0x40206b00
+)
+
+// spiIOCTransfer is struct spi_ioc_transfer.
+type spiIOCTransfer struct {
+	txBuf          uint64
+	rxBuf          uint64
+	len            uint32
+	speedHz        uint32
+	delayUsecs     uint16
+	bitsPerWord    uint8
+	csChange       uint8
+	txNbits        uint8
+	rxNbits        uint8
+	wordDelayUsecs uint8
+	pad            uint8
+}
+
+type spidev struct {
+	f       *os.File
+	speedHz uint32
+}
+
+// OpenSPI opens a spidev device such as /dev/spidev0.0 in mode 0 with 8 bit words.
+func OpenSPI(path string, speedHz int) (hal.SPI, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	s := &spidev{f: f, speedHz: uint32(speedHz)}
+	mode := uint8(0)
+	bits := uint8(8)
+	speed := uint32(speedHz)
+	for _, setting := range []struct {
+		name    string
+		request uintptr
+		value   unsafe.Pointer
+	}{
+		{"mode", spiIOCWrMode, unsafe.Pointer(&mode)},
+		{"bits per word", spiIOCWrBitsPerWord, unsafe.Pointer(&bits)},
+		{"speed", spiIOCWrMaxSpeedHz, unsafe.Pointer(&speed)},
+	} {
+		if err := ioctl(f.Fd(), setting.request, setting.value); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to set %s of %s: %w", setting.name, path, err)
+		}
+	}
+	return s, nil
+}
+
+func (s *spidev) TransferAndReceiveData(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	read := make([]byte, len(data))
+	tr := spiIOCTransfer{
+		txBuf:       uint64(uintptr(unsafe.Pointer(&data[0]))),
+		rxBuf:       uint64(uintptr(unsafe.Pointer(&read[0]))),
+		len:         uint32(len(data)),
+		speedHz:     s.speedHz,
+		bitsPerWord: 8,
+	}
+	err := ioctl(s.f.Fd(), spiIOCMessage1, unsafe.Pointer(&tr))
+	runtime.KeepAlive(data)
+	runtime.KeepAlive(read)
+	if err != nil {
+		return fmt.Errorf("SPI transfer failed: %w", err)
+	}
+	copy(data, read)
+	return nil
+}
+
+func (s *spidev) Close() error {
+	return s.f.Close()
+}
+
+func ioctl(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package linux
+
+import (
+	"errors"
+
+	"github.com/hatstand/shinywaffle/hal"
+)
+
+var errUnsupported = errors.New("spidev and GPIO character devices are only available on Linux")
+
+// OpenSPI is only supported on Linux.
+func OpenSPI(path string, speedHz int) (hal.SPI, error) {
+	return nil, errUnsupported
+}
+
+// OpenPin is only supported on Linux.
+func OpenPin(chip string, offset int) (hal.Pin, error) {
+	return nil, errUnsupported
+}
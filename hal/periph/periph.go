@@ -0,0 +1,153 @@
+// Package periph implements hal on top of periph.io.
+package periph
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hatstand/shinywaffle/hal"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/host"
+)
+
+// How often a watch checks whether it has been stopped.
+const edgePollInterval = 100 * time.Millisecond
+
+type spiConn struct {
+	port spi.PortCloser
+	conn spi.Conn
+}
+
+// OpenSPI opens the SPI port called name, or the first one if name is empty,
+// in mode 0 with 8 bit words.
+func OpenSPI(name string, speedHz int) (hal.SPI, error) {
+	if _, err := host.Init(); err != nil {
+		return nil, fmt.Errorf("failed to initialise periph: %w", err)
+	}
+	port, err := spireg.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SPI port %q: %w", name, err)
+	}
+	s, err := NewSPI(port, speedHz)
+	if err != nil {
+		port.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewSPI connects to the device on an already open port.
+func NewSPI(port spi.PortCloser, speedHz int) (hal.SPI, error) {
+	conn, err := port.Connect(physic.Frequency(speedHz)*physic.Hertz, spi.Mode0, 8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SPI port %s: %w", port, err)
+	}
+	return &spiConn{port, conn}, nil
+}
+
+func (s *spiConn) TransferAndReceiveData(data []byte) error {
+	read := make([]byte, len(data))
+	if err := s.conn.Tx(data, read); err != nil {
+		return err
+	}
+	copy(data, read)
+	return nil
+}
+
+func (s *spiConn) Close() error {
+	return s.port.Close()
+}
+
+type pin struct {
+	pin  gpio.PinIn
+	lock sync.Mutex
+	// Closed to stop the active watch.
+	stop chan struct{}
+	done chan struct{}
+}
+
+// OpenPin opens the GPIO called name, e.g. "25" or "GPIO25", as an input.
+func OpenPin(name string) (hal.Pin, error) {
+	if _, err := host.Init(); err != nil {
+		return nil, fmt.Errorf("failed to initialise periph: %w", err)
+	}
+	p := gpioreg.ByName(name)
+	if p == nil {
+		return nil, fmt.Errorf("no GPIO called %q", name)
+	}
+	return NewPin(p)
+}
+
+// NewPin wraps an existing periph GPIO.
+func NewPin(p gpio.PinIn) (hal.Pin, error) {
+	if err := p.In(gpio.PullNoChange, gpio.NoEdge); err != nil {
+		return nil, fmt.Errorf("failed to set %s as an input: %w", p, err)
+	}
+	return &pin{pin: p}, nil
+}
+
+func (p *pin) Watch(edge hal.Edge, handler func()) error {
+	var e gpio.Edge
+	switch edge {
+	case hal.EdgeRising:
+		e = gpio.RisingEdge
+	case hal.EdgeFalling:
+		e = gpio.FallingEdge
+	case hal.EdgeBoth:
+		e = gpio.BothEdges
+	default:
+		return fmt.Errorf("invalid edge: %v", edge)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.stop != nil {
+		return fmt.Errorf("%s is already being watched", p.pin)
+	}
+	if err := p.pin.In(gpio.PullNoChange, e); err != nil {
+		return fmt.Errorf("failed to enable edge detection on %s: %w", p.pin, err)
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.watch(handler, p.stop, p.done)
+	return nil
+}
+
+func (p *pin) watch(handler func(), stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if p.pin.WaitForEdge(edgePollInterval) {
+			handler()
+		}
+	}
+}
+
+func (p *pin) StopWatching() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.stop == nil {
+		return nil
+	}
+	close(p.stop)
+	<-p.done
+	p.stop = nil
+	p.done = nil
+	return p.pin.In(gpio.PullNoChange, gpio.NoEdge)
+}
+
+func (p *pin) Close() error {
+	if err := p.StopWatching(); err != nil {
+		return err
+	}
+	return p.pin.Halt()
+}
@@ -0,0 +1,57 @@
+package periph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle/hal"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/spi/spitest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSPI(t *testing.T) {
+	Convey("Transfer", t, func() {
+		port := &spitest.Playback{
+			Playback: conntest.Playback{
+				Ops: []conntest.IO{
+					{W: []byte{0xf1, 0x00}, R: []byte{0x0f, 0x14}},
+				},
+			},
+		}
+		s, err := NewSPI(port, 50000)
+		So(err, ShouldBeNil)
+
+		data := []byte{0xf1, 0x00}
+		So(s.TransferAndReceiveData(data), ShouldBeNil)
+		So(data, ShouldResemble, []byte{0x0f, 0x14})
+		So(s.Close(), ShouldBeNil)
+	})
+}
+
+func TestPin(t *testing.T) {
+	Convey("Watch", t, func() {
+		gp := &gpiotest.Pin{N: "GPIO25", Num: 25, EdgesChan: make(chan gpio.Level)}
+		p, err := NewPin(gp)
+		So(err, ShouldBeNil)
+
+		edges := make(chan struct{}, 1)
+		So(p.Watch(hal.EdgeRising, func() {
+			edges <- struct{}{}
+		}), ShouldBeNil)
+		So(p.Watch(hal.EdgeRising, func() {}), ShouldNotBeNil)
+
+		gp.EdgesChan <- gpio.High
+		select {
+		case <-edges:
+		case <-time.After(time.Second):
+			t.Fatal("handler not called")
+		}
+
+		So(p.StopWatching(), ShouldBeNil)
+		So(p.Close(), ShouldBeNil)
+	})
+}
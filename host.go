@@ -0,0 +1,68 @@
+package shinywaffle
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/hatstand/shinywaffle/hal"
+	"github.com/hatstand/shinywaffle/hal/linux"
+	"github.com/hatstand/shinywaffle/hal/periph"
+)
+
+var halBackend = flag.String("hal", "periph", "Library used to access SPI and GPIO: periph, linux (spidev and GPIO character devices) or embd if built with -tags embd")
+var spiDevice = flag.String("spi_device", "", "SPI device the CC1101 is connected to. Defaults to the first periph.io port or /dev/spidev0.0")
+var gpioChip = flag.String("gpio_chip", "/dev/gpiochip0", "GPIO character device for -hal=linux")
+
+// SPI clock speed. The CC1101 supports up to 10MHz but the radiators are slow enough anyway.
+const spiSpeedHz = 50000
+
+// halOpener opens the SPI bus and returns how to open GPIO pins with a
+// library.
+type halOpener func() (bus hal.SPI, openPin func(n int) (hal.Pin, error), err error)
+
+// halBackends are the libraries -hal can choose, by name. Backends depending
+// on optional libraries add themselves when built with their build tag.
+var halBackends = map[string]halOpener{
+	"periph": func() (hal.SPI, func(n int) (hal.Pin, error), error) {
+		bus, err := periph.OpenSPI(*spiDevice, spiSpeedHz)
+		return bus, func(n int) (hal.Pin, error) {
+			return periph.OpenPin(strconv.Itoa(n))
+		}, err
+	},
+	"linux": func() (hal.SPI, func(n int) (hal.Pin, error), error) {
+		dev := *spiDevice
+		if dev == "" {
+			dev = "/dev/spidev0.0"
+		}
+		bus, err := linux.OpenSPI(dev, spiSpeedHz)
+		return bus, func(n int) (hal.Pin, error) {
+			return linux.OpenPin(*gpioChip, n)
+		}, err
+	},
+}
+
+// openHardware opens the SPI bus and GDO pins with the library chosen by the -hal flag.
+func openHardware() (bus hal.SPI, gdo0 hal.Pin, gdo2 hal.Pin, err error) {
+	backend, ok := halBackends[*halBackend]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("unknown -hal: %q", *halBackend)
+	}
+	bus, openPin, err := backend()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to open SPI: %w", err)
+	}
+
+	gdo0, err = openPin(*gdo0pin)
+	if err != nil {
+		bus.Close()
+		return nil, nil, nil, fmt.Errorf("failed to open GDO0 on pin %d: %w", *gdo0pin, err)
+	}
+	gdo2, err = openPin(*gdo2pin)
+	if err != nil {
+		gdo0.Close()
+		bus.Close()
+		return nil, nil, nil, fmt.Errorf("failed to open GDO2 on pin %d: %w", *gdo2pin, err)
+	}
+	return bus, gdo0, gdo2, nil
+}
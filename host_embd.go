@@ -0,0 +1,16 @@
+//go:build embd
+
+package shinywaffle
+
+import (
+	"github.com/hatstand/shinywaffle/hal"
+	"github.com/hatstand/shinywaffle/hal/embd"
+)
+
+// The embd backend is only built in with -tags embd.
+func init() {
+	halBackends["embd"] = func() (hal.SPI, func(n int) (hal.Pin, error), error) {
+		bus, err := embd.OpenSPI(0, spiSpeedHz)
+		return bus, embd.OpenPin, err
+	}
+}
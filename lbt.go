@@ -0,0 +1,177 @@
+package shinywaffle
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+var lbtEnabled = flag.Bool("lbt", false, "Listen before talk: only transmit once the channel is clear")
+
+// CCAMode is the CCA_MODE field of MCSM1, the condition the radio checks
+// before leaving RX for TX.
+type CCAMode byte
+
+const (
+	CCAAlways CCAMode = 0
+	// Clear if the RSSI is below the carrier sense threshold.
+	CCARSSIBelowThreshold CCAMode = 1
+	// Clear unless a packet is being received.
+	CCANotReceiving CCAMode = 2
+	// Clear if the RSSI is below threshold and no packet is being received.
+	CCARSSIBelowThresholdAndNotReceiving CCAMode = 3
+)
+
+const (
+	MCSM1_CCA_MODE = 0x30
+
+	// How long after entering RX before the RSSI and carrier sense are valid.
+	rssiSettleTime = time.Millisecond
+
+	defaultLBTAttempts   = 5
+	defaultLBTMinBackoff = 10 * time.Millisecond
+	defaultLBTMaxBackoff = 100 * time.Millisecond
+)
+
+// ErrChannelBusy is returned when the channel was busy for every attempt to transmit.
+var ErrChannelBusy = errors.New("channel busy")
+
+// ListenBeforeTalk configures clear channel assessment before transmitting.
+type ListenBeforeTalk struct {
+	// Condition checked by the radio itself when strobing STX from RX.
+	Mode CCAMode
+	// If non-zero the channel is also considered busy while the RSSI is
+	// above this level in dBm.
+	RSSIThreshold int
+	// Number of times to check the channel before giving up. Defaults to 5.
+	MaxAttempts int
+	// Range of the random delay between attempts. Defaults to 10-100ms.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultListenBeforeTalk avoids transmitting over other packets or a carrier.
+func DefaultListenBeforeTalk() *ListenBeforeTalk {
+	return &ListenBeforeTalk{
+		Mode: CCARSSIBelowThresholdAndNotReceiving,
+	}
+}
+
+func (l *ListenBeforeTalk) maxAttempts() int {
+	if l.MaxAttempts <= 0 {
+		return defaultLBTAttempts
+	}
+	return l.MaxAttempts
+}
+
+func (l *ListenBeforeTalk) backoff() time.Duration {
+	min, max := l.MinBackoff, l.MaxBackoff
+	if min <= 0 && max <= 0 {
+		min, max = defaultLBTMinBackoff, defaultLBTMaxBackoff
+	}
+	if max <= min {
+		return min
+	}
+	return min + time.Duration(rand.Int63n(int64(max-min)))
+}
+
+// TransmitResult describes how a packet was sent.
+type TransmitResult struct {
+	// Number of times the channel was checked, including the successful one.
+	Attempts int
+	// How long the channel was busy before the packet could be sent.
+	BusyTime time.Duration
+}
+
+// SetListenBeforeTalk enables clear channel assessment before every
+// transmission, or disables it if lbt is nil.
+func (c *CC1101) SetListenBeforeTalk(lbt *ListenBeforeTalk) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	mode := CCAAlways
+	if lbt != nil {
+		if lbt.Mode > CCARSSIBelowThresholdAndNotReceiving {
+			return fmt.Errorf("invalid CCA mode: %d", lbt.Mode)
+		}
+		mode = lbt.Mode
+	}
+	if err := c.wake(); err != nil {
+		return err
+	}
+	if err := c.setRegister(MCSM1, MCSM1_CCA_MODE, byte(mode)<<4); err != nil {
+		return err
+	}
+	c.lbt = lbt
+	return nil
+}
+
+// transmitWhenClear checks the channel and strobes STX, backing off and
+// retrying while it is busy.
+func (c *CC1101) transmitWhenClear() (TransmitResult, error) {
+	var result TransmitResult
+	var busySince time.Time
+	for {
+		result.Attempts++
+		clear, err := c.tryTransmit()
+		if err != nil {
+			return result, err
+		}
+		if clear {
+			if !busySince.IsZero() {
+				result.BusyTime = time.Since(busySince)
+			}
+			return result, nil
+		}
+		if busySince.IsZero() {
+			busySince = time.Now()
+		}
+		if result.Attempts >= c.lbt.maxAttempts() {
+			result.BusyTime = time.Since(busySince)
+			return result, fmt.Errorf("%w after %d attempts over %v", ErrChannelBusy, result.Attempts, result.BusyTime)
+		}
+		time.Sleep(c.lbt.backoff())
+	}
+}
+
+// tryTransmit strobes STX from RX, which the radio ignores if the channel is
+// busy according to the CCA mode. It reports whether the radio entered TX.
+func (c *CC1101) tryTransmit() (bool, error) {
+	state, err := c.ReadSingleByte(MARCSTATE)
+	if err != nil {
+		return false, err
+	}
+	if !isRx(state) {
+		if err := c.SetRx(); err != nil {
+			return false, err
+		}
+		time.Sleep(rssiSettleTime)
+	}
+	if c.lbt.RSSIThreshold != 0 {
+		rssi, err := c.ReadSingleByte(RSSI_STATUS)
+		if err != nil {
+			return false, err
+		}
+		if convertRSSI(int(rssi)) > c.lbt.RSSIThreshold {
+			return false, nil
+		}
+	}
+	if err := c.SetTx(); err != nil {
+		return false, err
+	}
+	state, err = c.ReadSingleByte(MARCSTATE)
+	if err != nil {
+		return false, err
+	}
+	return !isRx(state), nil
+}
+
+func isRx(marcstate byte) bool {
+	switch marcstate & 0x1f {
+	case MARCSTATE_RX, MARCSTATE_RX_END, MARCSTATE_RX_RST:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package shinywaffle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle/emulator"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestListenBeforeTalk(t *testing.T) {
+	lbt := &ListenBeforeTalk{
+		Mode:        CCARSSIBelowThresholdAndNotReceiving,
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  2 * time.Millisecond,
+	}
+
+	Convey("Sets CCA mode", t, WithEmulator(t, func(radio *emulator.Radio, cc1101 *CC1101) {
+		So(cc1101.SetListenBeforeTalk(lbt), ShouldBeNil)
+		So(radio.Register(MCSM1)&MCSM1_CCA_MODE, ShouldEqual, 0x30)
+		So(cc1101.SetListenBeforeTalk(nil), ShouldBeNil)
+		So(radio.Register(MCSM1)&MCSM1_CCA_MODE, ShouldEqual, 0)
+		So(cc1101.SetListenBeforeTalk(&ListenBeforeTalk{Mode: 4}), ShouldNotBeNil)
+	}))
+
+	Convey("Clear channel", t, WithEmulator(t, func(radio *emulator.Radio, cc1101 *CC1101) {
+		So(cc1101.SetListenBeforeTalk(lbt), ShouldBeNil)
+		result, err := cc1101.Transmit([]byte{0x42})
+		So(err, ShouldBeNil)
+		So(result.Attempts, ShouldEqual, 1)
+		So(result.BusyTime, ShouldEqual, 0)
+		So(radio.Sent(), ShouldResemble, []emulator.Packet{{Payload: []byte{0x42}}})
+		So(radio.State(), ShouldEqual, emulator.StateIdle)
+	}))
+
+	Convey("Busy channel", t, WithEmulator(t, func(radio *emulator.Radio, cc1101 *CC1101) {
+		So(cc1101.SetListenBeforeTalk(lbt), ShouldBeNil)
+		radio.SetChannel(emulator.Channel{RSSI: -40, CarrierSense: true})
+		result, err := cc1101.Transmit([]byte{0x42})
+		So(errors.Is(err, ErrChannelBusy), ShouldBeTrue)
+		So(result.Attempts, ShouldEqual, 3)
+		So(result.BusyTime, ShouldBeGreaterThanOrEqualTo, 2*time.Millisecond)
+		So(radio.Sent(), ShouldBeEmpty)
+		// The packet is discarded.
+		So(radio.State(), ShouldEqual, emulator.StateIdle)
+		status, err := cc1101.Status()
+		So(err, ShouldBeNil)
+		So(status.TXBytes, ShouldEqual, 0)
+	}))
+
+	Convey("Channel clears", t, WithEmulator(t, func(radio *emulator.Radio, cc1101 *CC1101) {
+		So(cc1101.SetListenBeforeTalk(&ListenBeforeTalk{
+			Mode:        CCANotReceiving,
+			MaxAttempts: 10,
+			MinBackoff:  5 * time.Millisecond,
+			MaxBackoff:  5 * time.Millisecond,
+		}), ShouldBeNil)
+		radio.SetChannel(emulator.Channel{RSSI: -60, Receiving: true})
+		go func() {
+			time.Sleep(12 * time.Millisecond)
+			radio.SetChannel(emulator.Channel{RSSI: -100})
+		}()
+		result, err := cc1101.Transmit([]byte{0x42})
+		So(err, ShouldBeNil)
+		So(result.Attempts, ShouldBeGreaterThan, 1)
+		So(result.BusyTime, ShouldBeGreaterThanOrEqualTo, 5*time.Millisecond)
+		So(radio.Sent(), ShouldHaveLength, 1)
+	}))
+
+	Convey("RSSI threshold", t, WithEmulator(t, func(radio *emulator.Radio, cc1101 *CC1101) {
+		So(cc1101.SetListenBeforeTalk(&ListenBeforeTalk{
+			Mode:          CCAAlways,
+			RSSIThreshold: -80,
+			MaxAttempts:   2,
+			MinBackoff:    time.Millisecond,
+			MaxBackoff:    time.Millisecond,
+		}), ShouldBeNil)
+		radio.SetChannel(emulator.Channel{RSSI: -70})
+		_, err := cc1101.Transmit([]byte{0x42})
+		So(errors.Is(err, ErrChannelBusy), ShouldBeTrue)
+
+		radio.SetChannel(emulator.Channel{RSSI: -90})
+		So(cc1101.Send([]byte{0x42}), ShouldBeNil)
+		So(radio.Sent(), ShouldHaveLength, 1)
+	}))
+
+	Convey("Listening returns to RX", t, WithEmulator(t, func(radio *emulator.Radio, cc1101 *CC1101) {
+		So(cc1101.SetListenBeforeTalk(lbt), ShouldBeNil)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		frames, err := cc1101.Listen(ctx)
+		So(err, ShouldBeNil)
+
+		radio.SetChannel(emulator.Channel{RSSI: -40, CarrierSense: true})
+		So(errors.Is(cc1101.Send([]byte{0x42}), ErrChannelBusy), ShouldBeTrue)
+		So(radio.State(), ShouldEqual, emulator.StateRX)
+
+		radio.SetChannel(emulator.Channel{RSSI: -100})
+		So(cc1101.Send([]byte{0x42}), ShouldBeNil)
+		So(radio.State(), ShouldEqual, emulator.StateRX)
+
+		cancel()
+		for range frames {
+		}
+	}))
+}
@@ -0,0 +1,77 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/hatstand/shinywaffle/hal (interfaces: Pin)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	hal "github.com/hatstand/shinywaffle/hal"
+)
+
+// MockPin is a mock of Pin interface.
+type MockPin struct {
+	ctrl     *gomock.Controller
+	recorder *MockPinMockRecorder
+}
+
+// MockPinMockRecorder is the mock recorder for MockPin.
+type MockPinMockRecorder struct {
+	mock *MockPin
+}
+
+// NewMockPin creates a new mock instance.
+func NewMockPin(ctrl *gomock.Controller) *MockPin {
+	mock := &MockPin{ctrl: ctrl}
+	mock.recorder = &MockPinMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPin) EXPECT() *MockPinMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockPin) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockPinMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockPin)(nil).Close))
+}
+
+// StopWatching mocks base method.
+func (m *MockPin) StopWatching() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StopWatching")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StopWatching indicates an expected call of StopWatching.
+func (mr *MockPinMockRecorder) StopWatching() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopWatching", reflect.TypeOf((*MockPin)(nil).StopWatching))
+}
+
+// Watch mocks base method.
+func (m *MockPin) Watch(arg0 hal.Edge, arg1 func()) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Watch", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Watch indicates an expected call of Watch.
+func (mr *MockPinMockRecorder) Watch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockPin)(nil).Watch), arg0, arg1)
+}
@@ -0,0 +1,62 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/hatstand/shinywaffle/hal (interfaces: SPI)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSPI is a mock of SPI interface.
+type MockSPI struct {
+	ctrl     *gomock.Controller
+	recorder *MockSPIMockRecorder
+}
+
+// MockSPIMockRecorder is the mock recorder for MockSPI.
+type MockSPIMockRecorder struct {
+	mock *MockSPI
+}
+
+// NewMockSPI creates a new mock instance.
+func NewMockSPI(ctrl *gomock.Controller) *MockSPI {
+	mock := &MockSPI{ctrl: ctrl}
+	mock.recorder = &MockSPIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSPI) EXPECT() *MockSPIMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockSPI) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockSPIMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockSPI)(nil).Close))
+}
+
+// TransferAndReceiveData mocks base method.
+func (m *MockSPI) TransferAndReceiveData(arg0 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferAndReceiveData", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TransferAndReceiveData indicates an expected call of TransferAndReceiveData.
+func (mr *MockSPIMockRecorder) TransferAndReceiveData(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferAndReceiveData", reflect.TypeOf((*MockSPI)(nil).TransferAndReceiveData), arg0)
+}
@@ -1,4 +1,4 @@
 //go:generate mockgen -package mocks -destination MockI2CBus.go github.com/kidoman/embd I2CBus
-//go:generate mockgen -package mocks -destination MockSPIBus.go github.com/kidoman/embd SPIBus
-//go:generate mockgen -package mocks -destination MockDigitalPin.go github.com/kidoman/embd DigitalPin
+//go:generate mockgen -package mocks -destination MockSPI.go github.com/hatstand/shinywaffle/hal SPI
+//go:generate mockgen -package mocks -destination MockPin.go github.com/hatstand/shinywaffle/hal Pin
 package mocks
@@ -1,46 +1,143 @@
 package main
 
 import (
-	"encoding/hex"
+	"bufio"
+	"context"
 	"flag"
+	"fmt"
 	"log"
-	"time"
+	"os"
+	"os/signal"
+	"strings"
 
 	"github.com/hatstand/shinywaffle"
+	"github.com/hatstand/shinywaffle/control"
+	"github.com/hatstand/shinywaffle/pairing"
+	"github.com/hatstand/shinywaffle/radiator"
+	"github.com/hatstand/shinywaffle/sniffer"
 )
 
-var address = flag.String("address", "", "Address in hexadecimal to pair as")
+var address = flag.String("address", "", "Address in hexadecimal to pair as. Allocated from the config if unset")
+var name = flag.String("name", "", "Friendly name for the radiator")
+var config = flag.String("config", "", "Path to controller config proto to add the radiator to")
+var zone = flag.String("zone", "", "Zone to add the radiator to")
+var mode = flag.String("mode", pairing.DefaultSetting.Mode.String(), "Initial mode: DAY, NIGHT, DEFROST, OFF or AUTO")
+var day = flag.Float64("day", float64(pairing.DefaultSetting.Day), "Initial day temperature")
+var night = flag.Float64("night", float64(pairing.DefaultSetting.Night), "Initial night temperature")
+var defrost = flag.Float64("defrost", float64(pairing.DefaultSetting.Defrost), "Initial defrost temperature")
+var attempts = flag.Int("attempts", pairing.DefaultOptions().Attempts, "Number of bursts of pairing packets to send")
+
+var stdin = bufio.NewReader(os.Stdin)
+
+func prompt(format string, args ...interface{}) string {
+	fmt.Printf(format, args...)
+	line, _ := stdin.ReadString('\n')
+	return strings.TrimSpace(line)
+}
 
 func main() {
 	flag.Parse()
 
-	addr, err := hex.DecodeString(*address)
-	if err != nil || len(addr) != 2 {
-		log.Fatal("Address must be exactly 2 bytes in hexadecimal")
+	m, err := radiator.ParseMode(*mode)
+	if err != nil {
+		log.Fatal(err)
+	}
+	setting := radiator.Setting{
+		Mode:    m,
+		Day:     float32(*day),
+		Night:   float32(*night),
+		Defrost: float32(*defrost),
+	}
+	if err := setting.Validate(); err != nil {
+		log.Fatalf("Invalid initial setting: %v", err)
+	}
+
+	var c *control.Config
+	if *config != "" {
+		if *zone == "" {
+			log.Fatal("-zone is required to add the radiator to the config")
+		}
+		c, err = control.LoadConfig(*config)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var addr radiator.Address
+	if *address != "" {
+		addr, err = radiator.ParseAddress(*address)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else if c != nil {
+		addr, err = pairing.AllocateAddress(control.RadiatorAddresses(c))
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		log.Fatal("Either -address or -config is required")
+	}
+	if c != nil {
+		// Checks the zone exists and the address is free before pairing.
+		// The config is only saved once pairing is confirmed.
+		if err := control.AddRadiator(c, *zone, addr, *name); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	packetCh := make(chan []byte, 10)
-	cc1101 := shinywaffle.NewCC1101(packetCh)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt)
+	go func() {
+		<-ch
+		cancel()
+	}()
+
+	cc1101, err := shinywaffle.NewCC1101()
+	if err != nil {
+		log.Fatalf("Failed to initialise CC1101: %v", err)
+	}
 	defer cc1101.Close()
-	defer close(packetCh)
 	cc1101.SetSyncWord(0xd391)
 
-	// Pairing packet
-	packet := []byte{0x57, 0x96, 0x0a}
-	// Set the address we want the radiator to have.
-	packet = append(packet, addr[0])
-	packet = append(packet, addr[1])
-	// Set some sensible defaults for initial radiator settings.
-	packet = append(packet, 0x60) // Off
-	packet = append(packet, 40)   // 20C Day time
-	packet = append(packet, 30)   // 15C Night time
-	packet = append(packet, 20)   // 10C Defrost
-
-	log.Printf("Pairing as: %s\n", *address)
-	for i := 0; i < 10; i++ {
-		cc1101.Send(packet)
-		cc1101.Send(packet)
-		cc1101.Send(packet)
-		time.Sleep(1 * time.Second)
+	options := pairing.DefaultOptions()
+	options.Attempts = *attempts
+	pairer := pairing.NewPairer(cc1101, options)
+
+	frames, err := cc1101.Listen(ctx)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+	go func() {
+		for e := range sniffer.New(nil).Run(ctx, frames, nil) {
+			pairer.HandleEvent(e)
+		}
+		for range frames {
+		}
+	}()
+
+	prompt("Put the radiator into pairing mode, usually by holding its power button until the light flashes, then press enter.")
+	fmt.Printf("Pairing as %v with %v day: %.1f night: %.1f defrost: %.1f\n",
+		addr, setting.Mode, setting.Day, setting.Night, setting.Defrost)
+	result, err := pairer.Pair(ctx, addr, setting)
+	if err != nil {
+		log.Fatalf("Failed to pair: %v", err)
+	}
+	fmt.Printf("Sent %d pairing packets\n", result.Sent)
+
+	if result.Confirmation != nil {
+		fmt.Printf("Confirmed by traffic for the new address: %v\n", result.Confirmation)
+	} else if answer := prompt("Has the radiator left pairing mode? [y/N] "); !strings.HasPrefix(strings.ToLower(answer), "y") {
+		fmt.Println("Pairing not confirmed, leaving the config unchanged")
+		return
+	}
+
+	if c == nil {
+		return
+	}
+	if err := control.SaveConfig(*config, c); err != nil {
+		log.Fatal(err)
 	}
+	fmt.Printf("Added %v to %s in %s\n", addr, *zone, *config)
 }
@@ -0,0 +1,143 @@
+// Package pairing pairs new radiators with the controller.
+//
+// A radiator in pairing mode adopts the address in the first pairing packet
+// it receives. The radiators never transmit so pairing is confirmed either by
+// the user or by hearing a wall remote send to the new address.
+package pairing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hatstand/shinywaffle/radiator"
+	"github.com/hatstand/shinywaffle/sniffer"
+)
+
+// Addresses of the radiators paired by their own wall remotes have been seen
+// starting at 0x2b00, so new addresses are allocated after those.
+const firstAddress = 0x2b00
+
+// DefaultSetting is the initial setting of a newly paired radiator.
+var DefaultSetting = radiator.Setting{
+	Mode:    radiator.Off,
+	Day:     20,
+	Night:   15,
+	Defrost: 10,
+}
+
+// AllocateAddress returns the lowest address above every address in used.
+func AllocateAddress(used []radiator.Address) (radiator.Address, error) {
+	next := uint32(firstAddress)
+	for _, a := range used {
+		if uint32(a) >= next {
+			next = uint32(a) + 1
+		}
+	}
+	// 0xffff is avoided in case radiators treat it as a broadcast.
+	if next >= 0xffff {
+		return 0, fmt.Errorf("no free radiator addresses")
+	}
+	return radiator.Address(next), nil
+}
+
+// Options configures how long pairing packets are sent for.
+type Options struct {
+	// Number of bursts of pairing packets to send.
+	Attempts int
+	// Packets per burst, as radiators occasionally miss one.
+	Repeats int
+	// Delay between bursts.
+	Interval time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Attempts: 10,
+		Repeats:  3,
+		Interval: time.Second,
+	}
+}
+
+// Result describes a pairing attempt.
+type Result struct {
+	Address radiator.Address
+	Setting radiator.Setting
+	// Packets sent.
+	Sent int
+	// A packet heard for the new address confirming pairing, if any.
+	Confirmation *sniffer.Event
+}
+
+// Pairer sends pairing packets and watches received traffic for confirmation.
+type Pairer struct {
+	sender  radiator.Sender
+	options Options
+
+	lock    sync.Mutex
+	waiting map[radiator.Address]chan *sniffer.Event
+}
+
+func NewPairer(sender radiator.Sender, options Options) *Pairer {
+	return &Pairer{
+		sender:  sender,
+		options: options,
+		waiting: make(map[radiator.Address]chan *sniffer.Event),
+	}
+}
+
+// HandleEvent passes received traffic to any pairing in progress.
+func (p *Pairer) HandleEvent(e *sniffer.Event) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if ch, ok := p.waiting[e.Address]; ok {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Pair sends pairing packets for address until confirmation is heard, every
+// attempt has been made or ctx is cancelled. The radiator should be put in
+// pairing mode first.
+func (p *Pairer) Pair(ctx context.Context, address radiator.Address, setting radiator.Setting) (*Result, error) {
+	packet, err := radiator.NewPairingPacket(address, setting).Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode pairing packet: %w", err)
+	}
+
+	confirmations := make(chan *sniffer.Event, 1)
+	p.lock.Lock()
+	if _, ok := p.waiting[address]; ok {
+		p.lock.Unlock()
+		return nil, fmt.Errorf("already pairing %v", address)
+	}
+	p.waiting[address] = confirmations
+	p.lock.Unlock()
+	defer func() {
+		p.lock.Lock()
+		delete(p.waiting, address)
+		p.lock.Unlock()
+	}()
+
+	result := &Result{Address: address, Setting: setting}
+	for i := 0; i < p.options.Attempts; i++ {
+		for j := 0; j < p.options.Repeats; j++ {
+			if err := p.sender.Send(packet); err != nil {
+				return result, fmt.Errorf("failed to send pairing packet: %w", err)
+			}
+			result.Sent++
+		}
+		select {
+		case e := <-confirmations:
+			result.Confirmation = e
+			return result, nil
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(p.options.Interval):
+		}
+	}
+	return result, nil
+}
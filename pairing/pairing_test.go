@@ -0,0 +1,78 @@
+package pairing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle/radiator"
+	"github.com/hatstand/shinywaffle/radiator/radiatortest"
+	"github.com/hatstand/shinywaffle/sniffer"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAllocateAddress(t *testing.T) {
+	Convey("First address", t, func() {
+		a, err := AllocateAddress(nil)
+		So(err, ShouldBeNil)
+		So(a, ShouldEqual, 0x2b00)
+	})
+
+	Convey("After the highest address", t, func() {
+		a, err := AllocateAddress([]radiator.Address{0x2e04, 0x2bdb, 0x0102})
+		So(err, ShouldBeNil)
+		So(a, ShouldEqual, 0x2e05)
+	})
+
+	Convey("Exhausted", t, func() {
+		_, err := AllocateAddress([]radiator.Address{0xfffe})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestPair(t *testing.T) {
+	options := Options{Attempts: 3, Repeats: 2, Interval: time.Millisecond}
+
+	Convey("Sends pairing packets", t, func() {
+		sender := &radiatortest.Sender{}
+		result, err := NewPairer(sender, options).Pair(context.Background(), 0x2b00, DefaultSetting)
+		So(err, ShouldBeNil)
+		So(result.Sent, ShouldEqual, 6)
+		So(result.Confirmation, ShouldBeNil)
+		sent, err := sender.Decoded()
+		So(err, ShouldBeNil)
+		So(sent, ShouldHaveLength, 6)
+		So(sent[0], ShouldResemble, radiator.NewPairingPacket(0x2b00, DefaultSetting))
+	})
+
+	Convey("Stops once confirmed", t, func() {
+		sender := &radiatortest.Sender{}
+		p := NewPairer(sender, Options{Attempts: 10, Repeats: 3, Interval: 10 * time.Millisecond})
+		confirmation := &sniffer.Event{Address: 0x2b00, Setting: DefaultSetting}
+		sender.OnSend = func([]byte) {
+			// Traffic for other radiators is ignored.
+			p.HandleEvent(&sniffer.Event{Address: 0x2e04})
+			if len(sender.Sent()) == 4 {
+				p.HandleEvent(confirmation)
+			}
+		}
+		result, err := p.Pair(context.Background(), 0x2b00, DefaultSetting)
+		So(err, ShouldBeNil)
+		So(result.Confirmation, ShouldEqual, confirmation)
+		So(result.Sent, ShouldEqual, 6)
+	})
+
+	Convey("Cancelled", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		result, err := NewPairer(&radiatortest.Sender{}, options).Pair(ctx, 0x2b00, DefaultSetting)
+		So(err, ShouldEqual, context.Canceled)
+		So(result.Sent, ShouldEqual, 2)
+	})
+
+	Convey("Invalid setting", t, func() {
+		_, err := NewPairer(&radiatortest.Sender{}, options).Pair(context.Background(), 0x2b00, radiator.Setting{})
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/hatstand/shinywaffle"
+	"github.com/hatstand/shinywaffle/capture"
+	"github.com/hatstand/shinywaffle/probe"
+	"github.com/hatstand/shinywaffle/radiator"
+)
+
+var address = flag.String("address", "", "Address in hexadecimal of the test radiator")
+var field = flag.String("field", "mode", "Field to sweep: ident0, ident1, ident2, mode, day, night or defrost")
+var values = flag.String("values", "", "Hexadecimal values and ranges to sweep through, e.g. 00-0f,60. Defaults depend on the field")
+var repeats = flag.Int("repeats", probe.DefaultOptions().Repeats, "Times each probe is sent")
+var window = flag.Duration("window", probe.DefaultOptions().Window, "How long to watch for traffic after each probe")
+var interactive = flag.Bool("interactive", false, "Prompt for what the radiator did after each probe")
+var reportPath = flag.String("report", "", "Path to write the report to as JSON")
+var capturePath = flag.String("capture", "", "Path to append every frame received and transmitted to as a capture")
+var yes = flag.Bool("y", false, "Don't ask for confirmation before sweeping")
+
+var stdin = bufio.NewReader(os.Stdin)
+
+func prompt(format string, args ...interface{}) string {
+	fmt.Printf(format, args...)
+	line, _ := stdin.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+func main() {
+	flag.Parse()
+
+	if *address == "" {
+		log.Fatal("-address of a test radiator is required")
+	}
+	addr, err := radiator.ParseAddress(*address)
+	if err != nil {
+		log.Fatal(err)
+	}
+	f, err := probe.ParseField(*field)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sweep := probe.DefaultValues(f)
+	if *values != "" {
+		sweep, err = probe.ParseValues(*values)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	options := probe.DefaultOptions()
+	options.Address = addr
+	options.Repeats = *repeats
+	options.Window = *window
+	fmt.Printf("Sweeping %s of %v through %d values, taking at least %v.\n",
+		f, addr, len(sweep), time.Duration(len(sweep))*options.Window)
+	fmt.Println("Malformed packets may leave the radiator in an unknown state so only use a radiator that can be reset.")
+	if !*yes {
+		if answer := prompt("Continue? [y/N] "); !strings.HasPrefix(strings.ToLower(answer), "y") {
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt)
+	go func() {
+		<-ch
+		cancel()
+	}()
+
+	cc1101, err := shinywaffle.NewCC1101()
+	if err != nil {
+		log.Fatalf("Failed to initialise CC1101: %v", err)
+	}
+	defer cc1101.Close()
+	cc1101.SetSyncWord(0xd391)
+
+	var sender radiator.Sender = cc1101
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	defer stopListening()
+	frames, err := cc1101.Listen(listenCtx)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+	if *capturePath != "" {
+		out, err := os.OpenFile(*capturePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			log.Fatalf("Failed to open capture: %v", err)
+		}
+		defer out.Close()
+		w := capture.NewWriter(out)
+		sender = capture.NewRecordingSender(cc1101, w, func(err error) {
+			log.Printf("Failed to record sent packet: %v", err)
+		})
+		frames = capture.RecordFrames(frames, w, func(err error) {
+			log.Printf("Failed to record frame: %v", err)
+		})
+	}
+
+	prober := probe.NewProber(sender, options)
+	go func() {
+		for frame := range frames {
+			log.Printf("Received %x rssi: %ddBm lqi: %d crc: %v", frame.Payload, frame.RSSI, frame.LQI, frame.CRCOK)
+			prober.HandleFrame(frame)
+		}
+	}()
+
+	observe := func(r probe.Result) string {
+		log.Printf("Sent 0x%02x: %x", r.Value, r.Packet)
+		return ""
+	}
+	if *interactive {
+		observe = func(r probe.Result) string {
+			return prompt("Sent 0x%02x: %x. What did the radiator do? ", r.Value, r.Packet)
+		}
+	}
+	report, err := prober.Sweep(ctx, f, sweep, observe)
+	if err != nil {
+		log.Printf("Sweep stopped: %v", err)
+	}
+	if report == nil {
+		os.Exit(1)
+	}
+
+	if err := report.WriteText(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+	if *reportPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*reportPath, data, 0644); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+// Package probe sweeps a field of the radiator protocol through a range of
+// values against a test radiator, to help document the parts of the protocol
+// that are not understood yet.
+//
+// The radiators never transmit so their responses can only be observed by
+// someone watching them. Each probe is followed by a window in which any
+// received traffic is collected, and the observer may note what the radiator
+// did, before the next probe is sent.
+package probe
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hatstand/shinywaffle"
+	"github.com/hatstand/shinywaffle/capture"
+	"github.com/hatstand/shinywaffle/radiator"
+)
+
+// Field is a byte of the radiator packet that can be swept.
+type Field string
+
+const (
+	Ident0  Field = "ident0"
+	Ident1  Field = "ident1"
+	Ident2  Field = "ident2"
+	Mode    Field = "mode"
+	Day     Field = "day"
+	Night   Field = "night"
+	Defrost Field = "defrost"
+)
+
+// Offsets of each field in the packet. The address is deliberately not
+// sweepable so that only the test radiator is affected.
+var offsets = map[Field]int{
+	Ident0:  0,
+	Ident1:  1,
+	Ident2:  2,
+	Mode:    5,
+	Day:     6,
+	Night:   7,
+	Defrost: 8,
+}
+
+// ParseField returns the field with the given name.
+func ParseField(name string) (Field, error) {
+	f := Field(name)
+	if _, ok := offsets[f]; !ok {
+		return "", fmt.Errorf("unknown field: %q", name)
+	}
+	return f, nil
+}
+
+// DefaultValues returns the values to sweep a field through:
+//   - every value of the mode byte,
+//   - every single bit flip of the setting ident's bytes, which is how the
+//     pairing ident differs from it,
+//   - the extremes around and outside of the valid range of temperatures.
+func DefaultValues(field Field) []byte {
+	switch field {
+	case Mode:
+		values := make([]byte, 256)
+		for i := range values {
+			values[i] = byte(i)
+		}
+		return values
+	case Ident0, Ident1, Ident2:
+		known := radiator.SettingIdent[offsets[field]]
+		values := make([]byte, 8)
+		for bit := range values {
+			values[bit] = known ^ 1<<bit
+		}
+		return values
+	default:
+		lowest, highest := byte(radiator.MinTemperature*2), byte(radiator.MaxTemperature*2)
+		return []byte{0x00, 0x01, lowest - 1, lowest, highest, highest + 1, 0x7f, 0x80, 0xfe, 0xff}
+	}
+}
+
+// ParseValues parses a comma separated list of hexadecimal byte values and
+// inclusive ranges, e.g. "00-0f,60,ff".
+func ParseValues(spec string) ([]byte, error) {
+	var values []byte
+	for _, part := range strings.Split(spec, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		lo, err := strconv.ParseUint(bounds[0], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q: %w", part, err)
+		}
+		hi := lo
+		if len(bounds) == 2 {
+			hi, err = strconv.ParseUint(bounds[1], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q: %w", part, err)
+			}
+		}
+		if hi < lo {
+			return nil, fmt.Errorf("invalid range %q", part)
+		}
+		for v := lo; v <= hi; v++ {
+			values = append(values, byte(v))
+		}
+	}
+	return values, nil
+}
+
+// Options configures a sweep.
+type Options struct {
+	// Address of the test radiator.
+	Address radiator.Address
+	// Setting of the fields not being swept. The radiator is restored to it
+	// after the sweep.
+	Base radiator.Setting
+	// Times each probe is sent, as radiators occasionally miss a packet.
+	Repeats int
+	// How long to collect received traffic for after each probe. This also
+	// keeps the transmit duty cycle low.
+	Window time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Base: radiator.Setting{
+			Mode:    radiator.Off,
+			Day:     20,
+			Night:   15,
+			Defrost: 10,
+		},
+		Repeats: 3,
+		Window:  5 * time.Second,
+	}
+}
+
+// Result is the outcome of a single probe.
+type Result struct {
+	Value  byte            `json:"value"`
+	Packet capture.Payload `json:"packet"`
+	Sent   time.Time       `json:"sent"`
+	// Why the probe could not be sent, if it wasn't.
+	Error string `json:"error,omitempty"`
+	// Traffic received during the window after the probe.
+	Observed []capture.Record `json:"observed,omitempty"`
+	// What the observer saw the radiator do.
+	Note string `json:"note,omitempty"`
+}
+
+// Report is the outcome of a sweep.
+type Report struct {
+	Address  radiator.Address `json:"address"`
+	Field    Field            `json:"field"`
+	Started  time.Time        `json:"started"`
+	Finished time.Time        `json:"finished"`
+	Results  []Result         `json:"results"`
+}
+
+// WriteText writes a table of the results to w.
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Swept %s of %v from %s to %s\n\n",
+		r.Field, r.Address, r.Started.Format(time.RFC3339), r.Finished.Format(time.RFC3339))
+	t := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(t, "VALUE\tPACKET\tOBSERVED\tNOTE")
+	for _, result := range r.Results {
+		note := result.Note
+		if result.Error != "" {
+			note = "not sent: " + result.Error
+		}
+		fmt.Fprintf(t, "0x%02x\t%s\t%d\t%s\n", result.Value, hex.EncodeToString(result.Packet), len(result.Observed), note)
+	}
+	return t.Flush()
+}
+
+// Prober sends probes and collects the traffic received after each one.
+type Prober struct {
+	sender  radiator.Sender
+	options Options
+
+	lock sync.Mutex
+	// Whether a probe's window is open.
+	collecting bool
+	observed   []capture.Record
+}
+
+func NewProber(sender radiator.Sender, options Options) *Prober {
+	if options.Repeats <= 0 {
+		options.Repeats = 1
+	}
+	return &Prober{
+		sender:  sender,
+		options: options,
+	}
+}
+
+// HandleFrame collects a received frame if a probe's window is open.
+func (p *Prober) HandleFrame(f shinywaffle.Frame) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.collecting {
+		p.observed = append(p.observed, capture.FromFrame(f))
+	}
+}
+
+// base returns the packet every probe is derived from.
+func (p *Prober) base() ([]byte, error) {
+	return radiator.NewSettingPacket(p.options.Address, p.options.Base).Marshal()
+}
+
+// Sweep sends a probe for each value of field, calling observe after each
+// window for a note of what the radiator did. observe may be nil. The test
+// radiator is restored to the base setting afterwards, even if ctx is
+// cancelled part way through the sweep.
+func (p *Prober) Sweep(ctx context.Context, field Field, values []byte, observe func(Result) string) (*Report, error) {
+	offset, ok := offsets[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown field: %q", field)
+	}
+	base, err := p.base()
+	if err != nil {
+		return nil, fmt.Errorf("invalid base setting: %w", err)
+	}
+
+	report := &Report{
+		Address: p.options.Address,
+		Field:   field,
+		Started: time.Now(),
+	}
+	defer func() {
+		report.Finished = time.Now()
+	}()
+	for _, v := range values {
+		if ctx.Err() != nil {
+			break
+		}
+		packet := append([]byte(nil), base...)
+		packet[offset] = v
+		result := p.probe(ctx, v, packet)
+		if observe != nil {
+			result.Note = observe(result)
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	if err := p.send(base); err != nil {
+		return report, fmt.Errorf("failed to restore %v: %w", p.options.Address, err)
+	}
+	return report, ctx.Err()
+}
+
+func (p *Prober) probe(ctx context.Context, value byte, packet []byte) Result {
+	result := Result{
+		Value:  value,
+		Packet: packet,
+		Sent:   time.Now(),
+	}
+	p.lock.Lock()
+	p.collecting = true
+	p.observed = nil
+	p.lock.Unlock()
+
+	if err := p.send(packet); err != nil {
+		result.Error = err.Error()
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(p.options.Window):
+	}
+
+	p.lock.Lock()
+	p.collecting = false
+	result.Observed = p.observed
+	p.lock.Unlock()
+	return result
+}
+
+func (p *Prober) send(packet []byte) error {
+	for i := 0; i < p.options.Repeats; i++ {
+		if err := p.sender.Send(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
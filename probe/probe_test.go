@@ -0,0 +1,128 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle"
+	"github.com/hatstand/shinywaffle/capture"
+	"github.com/hatstand/shinywaffle/radiator"
+	"github.com/hatstand/shinywaffle/radiator/radiatortest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func testOptions() Options {
+	options := DefaultOptions()
+	options.Address = 0x2e04
+	options.Repeats = 2
+	options.Window = 10 * time.Millisecond
+	return options
+}
+
+func TestDefaultValues(t *testing.T) {
+	Convey("Default values", t, func() {
+		So(DefaultValues(Mode), ShouldHaveLength, 256)
+		So(DefaultValues(Ident1), ShouldContain, radiator.PairingIdent[1])
+		So(DefaultValues(Ident1), ShouldNotContain, radiator.SettingIdent[1])
+		So(DefaultValues(Day), ShouldContain, byte(radiator.MaxTemperature*2+1))
+	})
+
+	Convey("Parsing values", t, func() {
+		values, err := ParseValues("00-02, 60,ff")
+		So(err, ShouldBeNil)
+		So(values, ShouldResemble, []byte{0x00, 0x01, 0x02, 0x60, 0xff})
+		for _, spec := range []string{"", "100", "05-01", "zz", "01-"} {
+			_, err := ParseValues(spec)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Parsing fields", t, func() {
+		f, err := ParseField("night")
+		So(err, ShouldBeNil)
+		So(f, ShouldEqual, Night)
+		_, err = ParseField("address")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestSweep(t *testing.T) {
+	Convey("Sweeps a field and restores the radiator", t, func() {
+		sender := &radiatortest.Sender{}
+		p := NewProber(sender, testOptions())
+		report, err := p.Sweep(context.Background(), Mode, []byte{0x05, 0x42}, nil)
+		So(err, ShouldBeNil)
+		So(report.Address, ShouldEqual, radiator.Address(0x2e04))
+		So(report.Results, ShouldHaveLength, 2)
+		So(report.Results[1].Value, ShouldEqual, 0x42)
+		So(report.Results[1].Packet, ShouldResemble, capture.Payload{0x57, 0x16, 0x0a, 0x2e, 0x04, 0x42, 40, 30, 20})
+
+		base := []byte{0x57, 0x16, 0x0a, 0x2e, 0x04, 0x60, 40, 30, 20}
+		sent := sender.Packets()
+		So(sent, ShouldHaveLength, 6)
+		So(sent[4], ShouldResemble, base)
+		So(sent[5], ShouldResemble, base)
+		So(report.Finished, ShouldHappenOnOrAfter, report.Started)
+	})
+
+	Convey("Collects traffic during each window", t, func() {
+		sender := &radiatortest.Sender{}
+		p := NewProber(sender, testOptions())
+		sender.OnSend = func(packet []byte) {
+			p.HandleFrame(shinywaffle.Frame{Payload: []byte{packet[5]}, CRCOK: true})
+		}
+		var notes []Result
+		report, err := p.Sweep(context.Background(), Day, []byte{0x01, 0x02}, func(r Result) string {
+			notes = append(notes, r)
+			return "beeped"
+		})
+		So(err, ShouldBeNil)
+		So(notes, ShouldHaveLength, 2)
+		So(report.Results[0].Note, ShouldEqual, "beeped")
+		So(report.Results[0].Observed, ShouldHaveLength, 2)
+		So(report.Results[1].Observed, ShouldHaveLength, 2)
+
+		// Traffic outside of a window is ignored.
+		p.HandleFrame(shinywaffle.Frame{Payload: []byte{0x01}})
+		So(report.Results[1].Observed, ShouldHaveLength, 2)
+	})
+
+	Convey("Failed probes are reported", t, func() {
+		sender := &radiatortest.Sender{Err: errors.New("channel busy")}
+		report, err := NewProber(sender, testOptions()).Sweep(context.Background(), Night, []byte{0x01}, nil)
+		So(err, ShouldNotBeNil)
+		So(report.Results[0].Error, ShouldEqual, "channel busy")
+
+		var buf bytes.Buffer
+		So(report.WriteText(&buf), ShouldBeNil)
+		So(buf.String(), ShouldContainSubstring, "not sent: channel busy")
+	})
+
+	Convey("Cancelling stops the sweep", t, func() {
+		sender := &radiatortest.Sender{}
+		ctx, cancel := context.WithCancel(context.Background())
+		report, err := NewProber(sender, testOptions()).Sweep(ctx, Mode, DefaultValues(Mode), func(Result) string {
+			cancel()
+			return ""
+		})
+		So(err, ShouldEqual, context.Canceled)
+		So(report.Results, ShouldHaveLength, 1)
+		// The radiator is still restored.
+		So(sender.Sent(), ShouldHaveLength, 4)
+	})
+
+	Convey("Invalid sweeps", t, func() {
+		p := NewProber(&radiatortest.Sender{}, testOptions())
+		_, err := p.Sweep(context.Background(), "address", []byte{0x01}, nil)
+		So(err, ShouldNotBeNil)
+
+		options := testOptions()
+		options.Base.Day = 100
+		_, err = NewProber(&radiatortest.Sender{}, options).Sweep(context.Background(), Mode, []byte{0x01}, nil)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,331 @@
+package shinywaffle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hatstand/shinywaffle/config"
+)
+
+// Configuration register names as used in the datasheet and SmartRF Studio.
+var registerNames = map[string]byte{
+	"IOCFG2":   IOCFG2,
+	"IOCFG1":   IOCFG1,
+	"IOCFG0":   IOCFG0,
+	"FIFOTHR":  FIFOTHR,
+	"SYNC1":    SYNC1,
+	"SYNC0":    SYNC0,
+	"PKTLEN":   PKTLEN,
+	"PKTCTRL1": PKTCTRL1,
+	"PKTCTRL0": PKTCTRL0,
+	"ADDR":     ADDR,
+	"CHANNR":   CHANNR,
+	"FSCTRL1":  FSCTRL1,
+	"FSCTRL0":  FSCTRL0,
+	"FREQ2":    FREQ2,
+	"FREQ1":    FREQ1,
+	"FREQ0":    FREQ0,
+	"MDMCFG4":  MDMCFG4,
+	"MDMCFG3":  MDMCFG3,
+	"MDMCFG2":  MDMCFG2,
+	"MDMCFG1":  MDMCFG1,
+	"MDMCFG0":  MDMCFG0,
+	"DEVIATN":  DEVIATN,
+	"MCSM2":    MCSM2,
+	"MCSM1":    MCSM1,
+	"MCSM0":    MCSM0,
+	"FOCCFG":   FOCCFG,
+	"BSCFG":    BSCFG,
+	"AGCCTRL2": AGCCTRL2,
+	"AGCCTRL1": AGCCTRL1,
+	"AGCCTRL0": AGCCTRL0,
+	"WOREVT1":  WOREVT1,
+	"WOREVT0":  WOREVT0,
+	"WORCTRL":  WORCTRL,
+	"FREND1":   FREND1,
+	"FREND0":   FREND0,
+	"FSCAL3":   FSCAL3,
+	"FSCAL2":   FSCAL2,
+	"FSCAL1":   FSCAL1,
+	"FSCAL0":   FSCAL0,
+	"RCCTRL1":  RCCTRL1,
+	"RCCTRL0":  RCCTRL0,
+	"FSTEST":   FSTEST,
+	"PTEST":    PTEST,
+	"AGCTEST":  AGCTEST,
+	"TEST2":    TEST2,
+	"TEST1":    TEST1,
+	"TEST0":    TEST0,
+}
+
+// Read only status registers that SmartRF Studio includes in its exports.
+var statusRegisterNames = map[string]bool{
+	"PARTNUM":        true,
+	"VERSION":        true,
+	"FREQEST":        true,
+	"LQI":            true,
+	"RSSI":           true,
+	"MARCSTATE":      true,
+	"WORTIME1":       true,
+	"WORTIME0":       true,
+	"PKTSTATUS":      true,
+	"VCO_VC_DAC":     true,
+	"TXBYTES":        true,
+	"RXBYTES":        true,
+	"RCCTRL1_STATUS": true,
+	"RCCTRL0_STATUS": true,
+}
+
+func registerName(address byte) string {
+	for name, a := range registerNames {
+		if a == address {
+			return name
+		}
+	}
+	return fmt.Sprintf("0x%02x", address)
+}
+
+// RegisterProfile is a set of values for the CC1101 configuration registers,
+// written in order by Init.
+type RegisterProfile struct {
+	Name      string
+	registers []register
+}
+
+// DefaultProfile returns the 868.3MHz 2-FSK profile used by the radiators.
+func DefaultProfile() *RegisterProfile {
+	return &RegisterProfile{
+		Name: "868.3MHz 2-FSK",
+		registers: []register{
+			{FSCTRL1, config.FSCTRL1},
+			{FSCTRL0, config.FSCTRL0},
+
+			{FREQ2, config.FREQ2},
+			{FREQ1, config.FREQ1},
+			{FREQ0, config.FREQ0},
+
+			{MDMCFG4, config.MDMCFG4},
+			{MDMCFG3, config.MDMCFG3},
+			{MDMCFG2, config.MDMCFG2},
+			{MDMCFG1, config.MDMCFG1},
+			{MDMCFG0, config.MDMCFG0},
+
+			{CHANNR, config.CHANNR},
+			{DEVIATN, config.DEVIATN},
+			{FREND1, config.FREND1},
+			{FREND0, config.FREND0},
+			{MCSM0, config.MCSM0},
+			{FOCCFG, config.FOCCFG},
+			{BSCFG, config.BSCFG},
+
+			{AGCCTRL2, config.AGCCTRL2},
+			{AGCCTRL1, config.AGCCTRL1},
+			{AGCCTRL0, config.AGCCTRL0},
+
+			{FSCAL3, config.FSCAL3},
+			{FSCAL2, config.FSCAL2},
+			{FSCAL1, config.FSCAL1},
+			{FSCAL0, config.FSCAL0},
+
+			{FSTEST, config.FSTEST},
+			{TEST2, config.TEST2},
+			{TEST1, config.TEST1},
+			{TEST0, config.TEST0},
+
+			{IOCFG2, config.IOCFG2},
+			{IOCFG1, config.IOCFG1},
+			{IOCFG0, config.IOCFG0},
+
+			// Two status bytes appended to payload: RSSI LQI and CRC OK.
+			{PKTCTRL1, config.PKTCTRL1},
+			// No address check, data whitening off, CRC enable, variable length packets.
+			{PKTCTRL0, config.PKTCTRL0},
+
+			{ADDR, config.ADDR},
+			// Max packet length 61 bytes.
+			{PKTLEN, config.PKTLEN},
+		},
+	}
+}
+
+// Value returns the value the profile sets the register at address to.
+func (p *RegisterProfile) Value(address byte) (byte, bool) {
+	for _, r := range p.registers {
+		if r.address == address {
+			return r.value, true
+		}
+	}
+	return 0, false
+}
+
+// setValue sets the register at address to value, replacing any value the
+// profile already sets it to.
+func (p *RegisterProfile) setValue(address byte, value byte) {
+	for i := range p.registers {
+		if p.registers[i].address == address {
+			p.registers[i].value = value
+			return
+		}
+	}
+	p.registers = append(p.registers, register{address, value})
+}
+
+// Validate checks that the profile is compatible with the driver.
+func (p *RegisterProfile) Validate() error {
+	if len(p.registers) == 0 {
+		return fmt.Errorf("profile %q sets no registers", p.Name)
+	}
+	seen := make(map[byte]bool)
+	for _, r := range p.registers {
+		if r.address > TEST0 {
+			return fmt.Errorf("0x%02x is not a configuration register", r.address)
+		}
+		if seen[r.address] {
+			return fmt.Errorf("%s set more than once", registerName(r.address))
+		}
+		seen[r.address] = true
+	}
+
+	for _, required := range []struct {
+		address byte
+		mask    byte
+		value   byte
+		reason  string
+	}{
+		// FREQ2[7:6] are always 0.
+		{FREQ2, 0xc0, 0x00, "frequency must be set"},
+		{FREQ1, 0x00, 0x00, "frequency must be set"},
+		{FREQ0, 0x00, 0x00, "frequency must be set"},
+		{IOCFG0, 0x3f, 0x07, "GDO0 must assert on receiving a packet with CRC OK"},
+		{IOCFG2, 0x3f, 0x06, "GDO2 must deassert at the end of a transmitted packet"},
+		{PKTCTRL1, 0x04, 0x04, "status bytes must be appended to received packets"},
+		{PKTCTRL0, 0x03, 0x01, "packets must be variable length"},
+	} {
+		v, ok := p.Value(required.address)
+		if !ok {
+			return fmt.Errorf("%s missing: %s", registerName(required.address), required.reason)
+		}
+		if v&required.mask != required.value {
+			return fmt.Errorf("%s is 0x%02x: %s", registerName(required.address), v, required.reason)
+		}
+	}
+	if hz := p.Settings(DefaultCrystalHz).FrequencyHz; band(hz) < 0 {
+		return fmt.Errorf("%.3fMHz is outside of the CC1101 frequency bands", hz/1e6)
+	}
+	return nil
+}
+
+func (p *RegisterProfile) set(name string, value uint64) error {
+	if statusRegisterNames[name] {
+		return nil
+	}
+	address, ok := registerNames[name]
+	if !ok {
+		return fmt.Errorf("unknown register: %s", name)
+	}
+	if value > 0xff {
+		return fmt.Errorf("%s value 0x%x out of range", name, value)
+	}
+	p.registers = append(p.registers, register{address, byte(value)})
+	return nil
+}
+
+// ParseSmartRFProfile parses register values exported from SmartRF Studio.
+//
+// Lines may be C defines from the standard header template:
+//
+//	#define SMARTRF_SETTING_FREQ2 0x21
+//
+// or start with a register name followed by its value, optionally separated
+// by "=" and followed by a description:
+//
+//	FREQ2 0x21 // Frequency Control Word, High Byte
+//
+// Status registers and any lines not naming a register are ignored.
+func ParseSmartRFProfile(name string, r io.Reader) (*RegisterProfile, error) {
+	p := &RegisterProfile{Name: name}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == '=' || r == ','
+		})
+		if len(fields) > 0 && fields[0] == "#define" {
+			fields = fields[1:]
+			if len(fields) > 0 {
+				fields[0] = strings.TrimPrefix(fields[0], "SMARTRF_SETTING_")
+			}
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		if _, ok := registerNames[fields[0]]; !ok && !statusRegisterNames[fields[0]] {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid value for %s: %w", n, fields[0], err)
+		}
+		if err := p.set(fields[0], value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ParseTextProfile parses a config.Profile textproto.
+func ParseTextProfile(text string) (*RegisterProfile, error) {
+	var pb config.Profile
+	if err := proto.UnmarshalText(text, &pb); err != nil {
+		return nil, fmt.Errorf("failed to parse profile: %w", err)
+	}
+	p := &RegisterProfile{Name: pb.GetName()}
+	for _, r := range pb.GetRegister() {
+		if statusRegisterNames[r.GetName()] {
+			return nil, fmt.Errorf("%s is a read only status register", r.GetName())
+		}
+		if err := p.set(r.GetName(), uint64(r.GetValue())); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadRegisterProfile reads a profile from a textproto file if it has a
+// .textproto or .pbtxt extension and a SmartRF Studio export otherwise.
+func LoadRegisterProfile(path string) (*RegisterProfile, error) {
+	switch filepath.Ext(path) {
+	case ".textproto", ".pbtxt":
+		text, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read profile: %w", err)
+		}
+		return ParseTextProfile(string(text))
+	default:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read profile: %w", err)
+		}
+		defer f.Close()
+		return ParseSmartRFProfile(filepath.Base(path), f)
+	}
+}
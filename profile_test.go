@@ -0,0 +1,132 @@
+package shinywaffle
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hatstand/shinywaffle/config"
+	"github.com/hatstand/shinywaffle/mocks"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDefaultProfile(t *testing.T) {
+	Convey("Default profile is valid", t, func() {
+		So(DefaultProfile().Validate(), ShouldBeNil)
+	})
+}
+
+func TestParseSmartRFProfile(t *testing.T) {
+	Convey("C header", t, func() {
+		p, err := ParseSmartRFProfile("header", strings.NewReader(`
+/* Address Config = No address check */
+#define SMARTRF_SETTING_IOCFG2           0x06
+#define SMARTRF_SETTING_IOCFG0           0x07
+#define SMARTRF_SETTING_PKTCTRL1         0x04
+#define SMARTRF_SETTING_PKTCTRL0         0x05
+#define SMARTRF_SETTING_FREQ2            0x10
+#define SMARTRF_SETTING_FREQ1            0xb1
+#define SMARTRF_SETTING_FREQ0            0x3b
+`))
+		So(err, ShouldBeNil)
+		So(p.Name, ShouldEqual, "header")
+		v, ok := p.Value(FREQ2)
+		So(ok, ShouldBeTrue)
+		So(v, ShouldEqual, 0x10)
+		_, ok = p.Value(MDMCFG4)
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("Go constants", t, func() {
+		f, err := os.Open("config/cc1101_868_3.go")
+		So(err, ShouldBeNil)
+		defer f.Close()
+
+		p, err := ParseSmartRFProfile("868", f)
+		So(err, ShouldBeNil)
+		// Every configuration register but none of the status registers.
+		So(p.registers, ShouldHaveLength, TEST0+1)
+		v, _ := p.Value(FREQ1)
+		So(v, ShouldEqual, config.FREQ1)
+		v, _ = p.Value(WORCTRL)
+		So(v, ShouldEqual, config.WORCTRL)
+	})
+
+	Convey("Invalid value", t, func() {
+		_, err := ParseSmartRFProfile("bad", strings.NewReader("FREQ2 0x1ff\n"))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Duplicate register", t, func() {
+		_, err := ParseSmartRFProfile("bad", strings.NewReader(`
+IOCFG2 0x06
+IOCFG0 0x07
+PKTCTRL1 0x04
+PKTCTRL0 0x05
+FREQ2 0x21
+FREQ1 0x65
+FREQ0 0x44
+FREQ0 0x45
+`))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestParseTextProfile(t *testing.T) {
+	Convey("Valid", t, func() {
+		p, err := ParseTextProfile(`
+name: "433.92MHz"
+register { name: "IOCFG2" value: 0x06 }
+register { name: "IOCFG0" value: 0x07 }
+register { name: "PKTCTRL1" value: 0x04 }
+register { name: "PKTCTRL0" value: 0x05 }
+register { name: "FREQ2" value: 0x10 }
+register { name: "FREQ1" value: 0xb0 }
+register { name: "FREQ0" value: 0x71 }
+`)
+		So(err, ShouldBeNil)
+		So(p.Name, ShouldEqual, "433.92MHz")
+		So(p.registers, ShouldHaveLength, 7)
+		So(p.registers[4], ShouldResemble, register{FREQ2, 0x10})
+	})
+
+	Convey("Unknown register", t, func() {
+		_, err := ParseTextProfile(`register { name: "FOO" value: 0x06 }`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Status register", t, func() {
+		_, err := ParseTextProfile(`register { name: "MARCSTATE" value: 0x06 }`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Incompatible GDO0 config", t, func() {
+		_, err := ParseTextProfile(`
+register { name: "IOCFG2" value: 0x06 }
+register { name: "IOCFG0" value: 0x06 }
+register { name: "PKTCTRL1" value: 0x04 }
+register { name: "PKTCTRL0" value: 0x05 }
+register { name: "FREQ2" value: 0x10 }
+register { name: "FREQ1" value: 0xb0 }
+register { name: "FREQ0" value: 0x71 }
+`)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "IOCFG0")
+	})
+}
+
+func TestInitWithProfile(t *testing.T) {
+	Convey("Init writes the profile", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		cc1101.profile = &RegisterProfile{
+			Name: "custom",
+			registers: []register{
+				{IOCFG2, 0x06},
+				{IOCFG0, 0x07},
+				{FREQ2, 0x10},
+			},
+		}
+		expectInit(bus, cc1101.profile.registers)
+		So(cc1101.Init(), ShouldBeNil)
+	}))
+}
@@ -0,0 +1,55 @@
+package radiator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Address is the 16 bit address a radiator is paired as.
+type Address uint16
+
+// ParseAddress parses a 4 digit hexadecimal address, optionally prefixed by 0x.
+func ParseAddress(s string) (Address, error) {
+	digits := strings.TrimPrefix(strings.ToLower(s), "0x")
+	if len(digits) != 4 {
+		return 0, fmt.Errorf("invalid radiator address %q: must be 4 hexadecimal digits", s)
+	}
+	v, err := strconv.ParseUint(digits, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid radiator address %q: %w", s, err)
+	}
+	return Address(v), nil
+}
+
+// AddressFromBytes decodes a big endian address as stored in the config.
+func AddressFromBytes(b []byte) (Address, error) {
+	if len(b) != 2 {
+		return 0, fmt.Errorf("invalid radiator address %x: must be 2 bytes", b)
+	}
+	return Address(b[0])<<8 | Address(b[1]), nil
+}
+
+// Bytes returns a big endian encoding of a, as stored in the config.
+func (a Address) Bytes() []byte {
+	return []byte{byte(a >> 8), byte(a)}
+}
+
+func (a Address) String() string {
+	return fmt.Sprintf("%04x", uint16(a))
+}
+
+// MarshalText encodes a as hexadecimal, e.g. in JSON.
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText decodes a hexadecimal address.
+func (a *Address) UnmarshalText(text []byte) error {
+	v, err := ParseAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
@@ -0,0 +1,74 @@
+package radiator
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAddress(t *testing.T) {
+	Convey("Parse", t, func() {
+		for _, s := range []string{"2bdc", "2BDC", "0x2bdc"} {
+			a, err := ParseAddress(s)
+			So(err, ShouldBeNil)
+			So(a, ShouldEqual, Address(0x2bdc))
+		}
+		for _, s := range []string{"", "2bd", "2bdc0", "zzzz", "0x"} {
+			_, err := ParseAddress(s)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Format", t, func() {
+		So(Address(0x2e04).String(), ShouldEqual, "2e04")
+		So(Address(0x0012).String(), ShouldEqual, "0012")
+		So(Address(0x2e04).Bytes(), ShouldResemble, []byte{0x2e, 0x04})
+	})
+
+	Convey("Bytes", t, func() {
+		a, err := AddressFromBytes([]byte{0x2e, 0x04})
+		So(err, ShouldBeNil)
+		So(a, ShouldEqual, Address(0x2e04))
+		_, err = AddressFromBytes([]byte{0x2e})
+		So(err, ShouldNotBeNil)
+		_, err = AddressFromBytes(nil)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("JSON", t, func() {
+		b, err := json.Marshal(map[string]Address{"a": 0x2e04})
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, `{"a":"2e04"}`)
+		var decoded map[string]Address
+		So(json.Unmarshal(b, &decoded), ShouldBeNil)
+		So(decoded["a"], ShouldEqual, Address(0x2e04))
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	Convey("Registry", t, func() {
+		r := NewRegistry()
+		So(r.Add(Info{Address: 0x2e04, Name: "Study", Zone: "Study", Watts: 1000}), ShouldBeNil)
+		So(r.Add(Info{Address: 0x2bdc, Zone: "Living Room"}), ShouldBeNil)
+		So(r.Add(Info{Address: 0x2bdb, Zone: "Living Room"}), ShouldBeNil)
+		So(r.Add(Info{Address: 0x2e04, Zone: "Kitchen"}), ShouldNotBeNil)
+
+		info, ok := r.Lookup(0x2e04)
+		So(ok, ShouldBeTrue)
+		So(info.Watts, ShouldEqual, 1000)
+		_, ok = r.Lookup(0x1234)
+		So(ok, ShouldBeFalse)
+
+		So(r.Addresses(), ShouldResemble, []Address{0x2bdb, 0x2bdc, 0x2e04})
+		So(r.Zone("Living Room"), ShouldHaveLength, 2)
+
+		seen := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+		So(r.Seen(0x2e04, seen, -60), ShouldBeTrue)
+		So(r.Seen(0x1234, seen, -60), ShouldBeFalse)
+		info, _ = r.Lookup(0x2e04)
+		So(info.LastSeen, ShouldEqual, seen)
+		So(info.LastRSSI, ShouldEqual, -60)
+	})
+}
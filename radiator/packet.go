@@ -0,0 +1,230 @@
+// Package radiator implements the over the air protocol spoken by the remote
+// controlled radiators and their wall mounted remotes.
+//
+// See data/protocol for the reverse engineered notes this is based on.
+package radiator
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Length is the size in bytes of every radiator packet.
+const Length = 9
+
+// Temperatures outside of this range are rejected. The wall remotes have been
+// observed sending setpoints between 7°C and 30.5°C.
+const (
+	MinTemperature = 5.0
+	MaxTemperature = 35.0
+)
+
+// Ident is the 3 byte prefix that identifies the kind of packet.
+type Ident [3]byte
+
+var (
+	// SettingIdent prefixes packets that change the settings of a paired radiator.
+	SettingIdent = Ident{0x57, 0x16, 0x0a}
+	// PairingIdent prefixes packets that assign an address to a radiator in pairing mode.
+	PairingIdent = Ident{0x57, 0x96, 0x0a}
+)
+
+func (i Ident) String() string {
+	switch i {
+	case SettingIdent:
+		return "SETTING"
+	case PairingIdent:
+		return "PAIRING"
+	default:
+		return fmt.Sprintf("UNKNOWN(%02x%02x%02x)", i[0], i[1], i[2])
+	}
+}
+
+// Mode is the operating mode of a radiator.
+type Mode byte
+
+// Radiator Modes.
+const (
+	Day     Mode = 0x05
+	Night   Mode = 0x03
+	Defrost Mode = 0x09
+	Off     Mode = 0x60
+	Auto    Mode = 0x11
+)
+
+// Valid reports whether m is one of the known radiator modes.
+func (m Mode) Valid() bool {
+	switch m {
+	case Day, Night, Defrost, Off, Auto:
+		return true
+	}
+	return false
+}
+
+func (m Mode) String() string {
+	switch m {
+	case Day:
+		return "DAY"
+	case Night:
+		return "NIGHT"
+	case Defrost:
+		return "DEFROST"
+	case Off:
+		return "OFF"
+	case Auto:
+		return "AUTO"
+	default:
+		return fmt.Sprintf("UNKNOWN(0x%02x)", byte(m))
+	}
+}
+
+// ParseMode returns the mode with the given name, as returned by String.
+func ParseMode(name string) (Mode, error) {
+	for _, m := range []Mode{Day, Night, Defrost, Off, Auto} {
+		if strings.EqualFold(name, m.String()) {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mode: %q", name)
+}
+
+// MarshalText encodes m by name, e.g. in JSON.
+func (m Mode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// Setting is the mode and setpoints that a radiator is commanded into.
+// Temperatures are in degrees Celsius with half degree resolution.
+type Setting struct {
+	Mode    Mode
+	Day     float32
+	Night   float32
+	Defrost float32
+}
+
+// Validate checks that the mode is known and every setpoint is representable.
+func (s Setting) Validate() error {
+	if !s.Mode.Valid() {
+		return fmt.Errorf("invalid mode: %v", s.Mode)
+	}
+	for _, t := range []struct {
+		name string
+		temp float32
+	}{
+		{"day", s.Day},
+		{"night", s.Night},
+		{"defrost", s.Defrost},
+	} {
+		if err := validateTemperature(t.temp); err != nil {
+			return fmt.Errorf("invalid %s temperature: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+func validateTemperature(temp float32) error {
+	if temp < MinTemperature || temp > MaxTemperature {
+		return fmt.Errorf("%.1f°C outside of range %.1f°C-%.1f°C", temp, MinTemperature, MaxTemperature)
+	}
+	if temp*2 != float32(math.Trunc(float64(temp*2))) {
+		return fmt.Errorf("%v°C is not a multiple of 0.5°C", temp)
+	}
+	return nil
+}
+
+// Temperatures are represented as bytes in half-degree intervals, i.e. 0.5C -> 1, 1C -> 2
+func encodeTemperature(temp float32) byte {
+	return byte(temp * 2)
+}
+
+func decodeTemperature(b byte) float32 {
+	return float32(b) / 2
+}
+
+// Packet is a single radiator packet, either a setting change or a pairing request.
+//
+// Wire format:
+//
+//	IDENT0 IDENT1 IDENT2 ADDR0 ADDR1 MODE DAYTEMP*2 NIGHTTEMP*2 DEFROSTTEMP*2
+type Packet struct {
+	Ident   Ident
+	Address Address
+	Setting
+}
+
+// NewSettingPacket returns a packet changing the radiator at address to setting.
+func NewSettingPacket(address Address, setting Setting) *Packet {
+	return &Packet{
+		Ident:   SettingIdent,
+		Address: address,
+		Setting: setting,
+	}
+}
+
+// NewPairingPacket returns a packet that pairs a radiator in pairing mode as
+// address with setting as its initial state.
+func NewPairingPacket(address Address, setting Setting) *Packet {
+	return &Packet{
+		Ident:   PairingIdent,
+		Address: address,
+		Setting: setting,
+	}
+}
+
+// IsPairing reports whether p is a pairing request rather than a setting change.
+func (p *Packet) IsPairing() bool {
+	return p.Ident == PairingIdent
+}
+
+// Validate checks that p can be sent to a radiator.
+func (p *Packet) Validate() error {
+	if p.Ident != SettingIdent && p.Ident != PairingIdent {
+		return fmt.Errorf("invalid ident: %v", p.Ident)
+	}
+	return p.Setting.Validate()
+}
+
+// Marshal validates p and encodes it in the radiator wire format.
+func (p *Packet) Marshal() ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return []byte{
+		p.Ident[0],
+		p.Ident[1],
+		p.Ident[2],
+		byte(p.Address >> 8),
+		byte(p.Address & 0xff),
+		byte(p.Mode),
+		encodeTemperature(p.Day),
+		encodeTemperature(p.Night),
+		encodeTemperature(p.Defrost),
+	}, nil
+}
+
+// Unmarshal decodes and validates a packet in the radiator wire format.
+func Unmarshal(data []byte) (*Packet, error) {
+	if len(data) != Length {
+		return nil, fmt.Errorf("invalid packet length: %d", len(data))
+	}
+	p := &Packet{
+		Ident:   Ident{data[0], data[1], data[2]},
+		Address: Address(data[3])<<8 | Address(data[4]),
+		Setting: Setting{
+			Mode:    Mode(data[5]),
+			Day:     decodeTemperature(data[6]),
+			Night:   decodeTemperature(data[7]),
+			Defrost: decodeTemperature(data[8]),
+		},
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Packet) String() string {
+	return fmt.Sprintf("%v %v %v day: %.1f night: %.1f defrost: %.1f",
+		p.Ident, p.Address, p.Mode, p.Day, p.Night, p.Defrost)
+}
@@ -0,0 +1,116 @@
+package radiator
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUnmarshal(t *testing.T) {
+	Convey("Setting packet", t, func() {
+		// study on
+		p, err := Unmarshal([]byte{87, 22, 10, 46, 4, 5, 61, 38, 14})
+		So(err, ShouldBeNil)
+		So(p.Ident, ShouldEqual, SettingIdent)
+		So(p.IsPairing(), ShouldBeFalse)
+		So(p.Address, ShouldEqual, 0x2e04)
+		So(p.Mode, ShouldEqual, Day)
+		So(p.Day, ShouldEqual, 30.5)
+		So(p.Night, ShouldEqual, 19)
+		So(p.Defrost, ShouldEqual, 7)
+	})
+
+	Convey("Pairing packet", t, func() {
+		p, err := Unmarshal([]byte{0x57, 0x96, 0x0a, 0x2b, 0x7e, 0x11, 0x28, 0x26, 0x14})
+		So(err, ShouldBeNil)
+		So(p.IsPairing(), ShouldBeTrue)
+		So(p.Address, ShouldEqual, 0x2b7e)
+		So(p.Mode, ShouldEqual, Auto)
+		So(p.Day, ShouldEqual, 20)
+		So(p.Night, ShouldEqual, 19)
+		So(p.Defrost, ShouldEqual, 10)
+	})
+
+	Convey("Wrong length", t, func() {
+		_, err := Unmarshal([]byte{87, 22, 10, 46, 4, 5, 61, 38})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Unknown ident", t, func() {
+		_, err := Unmarshal([]byte{87, 23, 10, 46, 4, 5, 61, 38, 14})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Unknown mode", t, func() {
+		_, err := Unmarshal([]byte{87, 22, 10, 46, 4, 6, 61, 38, 14})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Temperature out of range", t, func() {
+		_, err := Unmarshal([]byte{87, 22, 10, 46, 4, 5, 200, 38, 14})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestMarshal(t *testing.T) {
+	Convey("Setting packet", t, func() {
+		p := NewSettingPacket(0x2b76, Setting{Mode: Night, Day: 20, Night: 16.5, Defrost: 9.5})
+		data, err := p.Marshal()
+		So(err, ShouldBeNil)
+		So(data, ShouldResemble, []byte{0x57, 0x16, 0x0a, 0x2b, 0x76, 0x03, 40, 33, 19})
+	})
+
+	Convey("Pairing packet", t, func() {
+		p := NewPairingPacket(0x2bdc, Setting{Mode: Off, Day: 20, Night: 15, Defrost: 10})
+		data, err := p.Marshal()
+		So(err, ShouldBeNil)
+		So(data, ShouldResemble, []byte{0x57, 0x96, 0x0a, 0x2b, 0xdc, 0x60, 40, 30, 20})
+	})
+
+	Convey("Round trip", t, func() {
+		p := NewSettingPacket(0x2c2c, Setting{Mode: Defrost, Day: 21.5, Night: 17, Defrost: 8})
+		data, err := p.Marshal()
+		So(err, ShouldBeNil)
+		q, err := Unmarshal(data)
+		So(err, ShouldBeNil)
+		So(q, ShouldResemble, p)
+	})
+
+	Convey("Invalid mode", t, func() {
+		_, err := NewSettingPacket(0x2c2c, Setting{Mode: 0x42, Day: 20, Night: 15, Defrost: 10}).Marshal()
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Not a half degree", t, func() {
+		_, err := NewSettingPacket(0x2c2c, Setting{Mode: Day, Day: 20.2, Night: 15, Defrost: 10}).Marshal()
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Too cold", t, func() {
+		_, err := NewSettingPacket(0x2c2c, Setting{Mode: Day, Day: 20, Night: 15, Defrost: 0}).Marshal()
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Too hot", t, func() {
+		_, err := NewSettingPacket(0x2c2c, Setting{Mode: Day, Day: 40, Night: 15, Defrost: 10}).Marshal()
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestParseMode(t *testing.T) {
+	Convey("Names", t, func() {
+		for _, m := range []Mode{Day, Night, Defrost, Off, Auto} {
+			parsed, err := ParseMode(m.String())
+			So(err, ShouldBeNil)
+			So(parsed, ShouldEqual, m)
+		}
+		m, err := ParseMode("night")
+		So(err, ShouldBeNil)
+		So(m, ShouldEqual, Night)
+	})
+
+	Convey("Unknown", t, func() {
+		_, err := ParseMode("SUN")
+		So(err, ShouldNotBeNil)
+	})
+}
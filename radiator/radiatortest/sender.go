@@ -0,0 +1,75 @@
+// Package radiatortest provides a radiator.Sender for testing code which sends
+// packets without a radio.
+package radiatortest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hatstand/shinywaffle/radiator"
+)
+
+// Sent is a packet sent with a Sender.
+type Sent struct {
+	At     time.Time
+	Packet []byte
+}
+
+// Sender records the packets sent with it.
+type Sender struct {
+	// How long sending each packet takes.
+	Airtime time.Duration
+	// Returned by Send instead of sending, if set.
+	Err error
+	// Called with every packet sent, e.g. to simulate traffic in response.
+	OnSend func(packet []byte)
+
+	lock sync.Mutex
+	sent []Sent
+}
+
+var _ radiator.Sender = (*Sender)(nil)
+
+func (s *Sender) Send(packet []byte) error {
+	time.Sleep(s.Airtime)
+	s.lock.Lock()
+	err := s.Err
+	if err == nil {
+		s.sent = append(s.sent, Sent{At: time.Now(), Packet: packet})
+	}
+	s.lock.Unlock()
+	if err == nil && s.OnSend != nil {
+		s.OnSend(packet)
+	}
+	return err
+}
+
+// Sent returns the packets sent so far.
+func (s *Sender) Sent() []Sent {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Sent(nil), s.sent...)
+}
+
+// Packets returns the raw packets sent so far.
+func (s *Sender) Packets() [][]byte {
+	var packets [][]byte
+	for _, sent := range s.Sent() {
+		packets = append(packets, sent.Packet)
+	}
+	return packets
+}
+
+// Decoded returns the packets sent so far decoded, failing if any isn't a
+// valid radiator packet.
+func (s *Sender) Decoded() ([]*radiator.Packet, error) {
+	var packets []*radiator.Packet
+	for _, sent := range s.Sent() {
+		p, err := radiator.Unmarshal(sent.Packet)
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, p)
+	}
+	return packets, nil
+}
@@ -0,0 +1,103 @@
+package radiator
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Info describes a known radiator.
+type Info struct {
+	Address Address
+	// Friendly name, e.g. "Kitchen window".
+	Name string
+	// Zone the radiator heats.
+	Zone string
+	// Rated power, or 0 if unknown.
+	Watts int
+	// When a packet for the radiator was last received, and its signal
+	// strength in dBm.
+	LastSeen time.Time
+	LastRSSI int
+}
+
+// Registry is the set of known radiators. It is safe for concurrent use.
+type Registry struct {
+	lock      sync.Mutex
+	radiators map[Address]*Info
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		radiators: make(map[Address]*Info),
+	}
+}
+
+// Add registers a radiator, failing if its address is already in use.
+func (r *Registry) Add(info Info) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if existing, ok := r.radiators[info.Address]; ok {
+		return fmt.Errorf("radiator %v is already in %s", info.Address, existing.Zone)
+	}
+	r.radiators[info.Address] = &info
+	return nil
+}
+
+// Lookup returns the radiator at a.
+func (r *Registry) Lookup(a Address) (Info, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	info, ok := r.radiators[a]
+	if !ok {
+		return Info{}, false
+	}
+	return *info, true
+}
+
+// Seen records that a packet for the radiator at a was received. It reports
+// whether the radiator is known.
+func (r *Registry) Seen(a Address, at time.Time, rssi int) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	info, ok := r.radiators[a]
+	if !ok {
+		return false
+	}
+	info.LastSeen = at
+	info.LastRSSI = rssi
+	return true
+}
+
+// Zone returns the radiators in zone, ordered by address.
+func (r *Registry) Zone(zone string) []Info {
+	var infos []Info
+	for _, info := range r.All() {
+		if info.Zone == zone {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// Addresses returns the address of every radiator in ascending order.
+func (r *Registry) Addresses() []Address {
+	var addrs []Address
+	for _, info := range r.All() {
+		addrs = append(addrs, info.Address)
+	}
+	return addrs
+}
+
+// All returns every radiator, ordered by address.
+func (r *Registry) All() []Info {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	infos := make([]Info, 0, len(r.radiators))
+	for _, info := range r.radiators {
+		infos = append(infos, *info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Address < infos[j].Address })
+	return infos
+}
@@ -0,0 +1,6 @@
+package radiator
+
+// Sender transmits raw packets, as implemented by shinywaffle.CC1101.
+type Sender interface {
+	Send(packet []byte) error
+}
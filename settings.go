@@ -0,0 +1,372 @@
+package shinywaffle
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultCrystalHz is the crystal frequency of the common CC1101 boards.
+const DefaultCrystalHz = 26000000
+
+// PATABLE holds the output power settings.
+const PATABLE = 0x3e
+
+// Modulation is the MOD_FORMAT field of MDMCFG2.
+type Modulation byte
+
+const (
+	FSK2 Modulation = 0
+	GFSK Modulation = 1
+	ASK  Modulation = 3 // ASK/OOK
+	FSK4 Modulation = 4
+	MSK  Modulation = 7
+)
+
+func (m Modulation) String() string {
+	switch m {
+	case FSK2:
+		return "2-FSK"
+	case GFSK:
+		return "GFSK"
+	case ASK:
+		return "ASK/OOK"
+	case FSK4:
+		return "4-FSK"
+	case MSK:
+		return "MSK"
+	default:
+		return fmt.Sprintf("UNKNOWN(%d)", byte(m))
+	}
+}
+
+// Register values after reset, used when decoding profiles that leave them unset.
+var resetValues = map[byte]byte{
+	FREQ2:   0x1e,
+	FREQ1:   0xc4,
+	FREQ0:   0xec,
+	MDMCFG4: 0x8c,
+	MDMCFG3: 0x22,
+	MDMCFG2: 0x02,
+	DEVIATN: 0x47,
+}
+
+// Frequency bands supported by the CC1101 in Hz.
+var bands = []struct {
+	min, max float64
+	// Optimum PATABLE values for -30, -20, -15, -10, 0, 5, 7 and 10dBm taken
+	// from the datasheet.
+	paTable [8]byte
+}{
+	{300e6, 348e6, [8]byte{0x12, 0x0d, 0x1c, 0x34, 0x51, 0x85, 0xcb, 0xc2}},
+	{387e6, 464e6, [8]byte{0x12, 0x0e, 0x1d, 0x34, 0x60, 0x84, 0xc8, 0xc0}},
+	{779e6, 900e6, [8]byte{0x03, 0x0f, 0x1e, 0x27, 0x50, 0x81, 0xcb, 0xc2}},
+	{900e6, 928e6, [8]byte{0x03, 0x0e, 0x1e, 0x27, 0x8e, 0xcd, 0xc7, 0xc0}},
+}
+
+var paLevels = [8]int{-30, -20, -15, -10, 0, 5, 7, 10}
+
+// RadioSettings are the human readable settings encoded by the registers.
+type RadioSettings struct {
+	FrequencyHz   float64
+	DataRateBaud  float64
+	DeviationHz   float64
+	RxBandwidthHz float64
+	Modulation    Modulation
+}
+
+func (s RadioSettings) String() string {
+	return fmt.Sprintf("%.3fMHz %v %.2fkBaud deviation: %.1fkHz RX bandwidth: %.1fkHz",
+		s.FrequencyHz/1e6, s.Modulation, s.DataRateBaud/1e3, s.DeviationHz/1e3, s.RxBandwidthHz/1e3)
+}
+
+// decodeSettings decodes the settings from register values given by reg.
+func decodeSettings(reg func(address byte) byte, xtal float64) RadioSettings {
+	freq := uint32(reg(FREQ2))<<16 | uint32(reg(FREQ1))<<8 | uint32(reg(FREQ0))
+	mdmcfg4 := reg(MDMCFG4)
+	drateE := mdmcfg4 & 0x0f
+	chanbwE := mdmcfg4 >> 6
+	chanbwM := (mdmcfg4 >> 4) & 0x03
+	deviatn := reg(DEVIATN)
+	deviationE := (deviatn >> 4) & 0x07
+	deviationM := deviatn & 0x07
+	return RadioSettings{
+		FrequencyHz:   xtal / (1 << 16) * float64(freq),
+		DataRateBaud:  float64(256+int(reg(MDMCFG3))) * math.Pow(2, float64(drateE)) / (1 << 28) * xtal,
+		DeviationHz:   xtal / (1 << 17) * float64(8+deviationM) * math.Pow(2, float64(deviationE)),
+		RxBandwidthHz: rxBandwidth(chanbwE, chanbwM, xtal),
+		Modulation:    Modulation((reg(MDMCFG2) >> 4) & 0x07),
+	}
+}
+
+func rxBandwidth(e, m byte, xtal float64) float64 {
+	return xtal / (8 * float64(4+int(m)) * math.Pow(2, float64(e)))
+}
+
+// Settings decodes the profile assuming a crystal of xtal Hz.
+func (p *RegisterProfile) Settings(xtal float64) RadioSettings {
+	return decodeSettings(func(address byte) byte {
+		if v, ok := p.Value(address); ok {
+			return v
+		}
+		return resetValues[address]
+	}, xtal)
+}
+
+func frequencyRegisters(hz float64, xtal float64) ([]byte, error) {
+	if band(hz) < 0 {
+		return nil, fmt.Errorf("%.3fMHz is outside of the CC1101 frequency bands", hz/1e6)
+	}
+	freq := uint32(math.Round(hz * (1 << 16) / xtal))
+	if freq >= 1<<22 {
+		return nil, fmt.Errorf("%.3fMHz is too high for a %.0fMHz crystal", hz/1e6, xtal/1e6)
+	}
+	return []byte{byte(freq >> 16), byte(freq >> 8), byte(freq)}, nil
+}
+
+// dataRateRegisters returns DRATE_E and DRATE_M for baud.
+func dataRateRegisters(baud float64, xtal float64) (byte, byte, error) {
+	if baud <= 0 {
+		return 0, 0, fmt.Errorf("invalid data rate: %f", baud)
+	}
+	e := math.Floor(math.Log2(baud * (1 << 20) / xtal))
+	m := math.Round(baud*(1<<28)/(xtal*math.Pow(2, e))) - 256
+	if m >= 256 {
+		e++
+		m = 0
+	}
+	if e < 0 || e > 15 {
+		return 0, 0, fmt.Errorf("data rate %.1fBaud out of range", baud)
+	}
+	return byte(e), byte(m), nil
+}
+
+// deviationRegisters returns DEVIATION_E and DEVIATION_M for hz.
+func deviationRegisters(hz float64, xtal float64) (byte, byte, error) {
+	if hz <= 0 {
+		return 0, 0, fmt.Errorf("invalid deviation: %f", hz)
+	}
+	v := hz * (1 << 17) / xtal
+	e := math.Floor(math.Log2(v / 8))
+	m := math.Round(v/math.Pow(2, e)) - 8
+	if m >= 8 {
+		e++
+		m = 0
+	}
+	if e < 0 || e > 7 {
+		return 0, 0, fmt.Errorf("deviation %.1fHz out of range", hz)
+	}
+	return byte(e), byte(m), nil
+}
+
+// rxBandwidthRegisters returns CHANBW_E and CHANBW_M for the narrowest
+// receive filter that is at least hz wide.
+func rxBandwidthRegisters(hz float64, xtal float64) (byte, byte, error) {
+	best := math.Inf(1)
+	var bestE, bestM byte
+	for e := byte(0); e < 4; e++ {
+		for m := byte(0); m < 4; m++ {
+			bw := rxBandwidth(e, m, xtal)
+			if bw >= hz && bw < best {
+				best = bw
+				bestE, bestM = e, m
+			}
+		}
+	}
+	if math.IsInf(best, 1) {
+		return 0, 0, fmt.Errorf("RX bandwidth %.1fkHz out of range", hz/1e3)
+	}
+	return bestE, bestM, nil
+}
+
+// band returns the index into bands containing hz or -1.
+func band(hz float64) int {
+	for i, b := range bands {
+		if hz >= b.min && hz <= b.max {
+			return i
+		}
+	}
+	return -1
+}
+
+// paTableValue returns the PATABLE value for the highest power level at most dBm.
+func paTableValue(dBm int, hz float64) (byte, error) {
+	b := band(hz)
+	if b < 0 {
+		return 0, fmt.Errorf("%.3fMHz is outside of the CC1101 frequency bands", hz/1e6)
+	}
+	if dBm < paLevels[0] || dBm > paLevels[len(paLevels)-1] {
+		return 0, fmt.Errorf("TX power %ddBm out of range %d-%ddBm", dBm, paLevels[0], paLevels[len(paLevels)-1])
+	}
+	i := len(paLevels) - 1
+	for paLevels[i] > dBm {
+		i--
+	}
+	return bands[b].paTable[i], nil
+}
+
+func (c *CC1101) crystal() float64 {
+	if c.crystalHz == 0 {
+		return DefaultCrystalHz
+	}
+	return c.crystalHz
+}
+
+// SetCrystal sets the frequency of the crystal fitted to the board if it is
+// not the usual 26MHz.
+func (c *CC1101) SetCrystal(hz float64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.crystalHz = hz
+}
+
+// updateRegister replaces the bits of a register selected by mask.
+func (c *CC1101) updateRegister(address byte, mask byte, value byte) error {
+	old, err := c.ReadSingleByte(address)
+	if err != nil {
+		return err
+	}
+	return c.WriteSingleByte(address, old&^mask|value&mask)
+}
+
+// setRegister replaces the bits of a configuration register selected by mask
+// and keeps the result in the profile.
+func (c *CC1101) setRegister(address byte, mask byte, value byte) error {
+	old, err := c.ReadSingleByte(address)
+	if err != nil {
+		return err
+	}
+	value = old&^mask | value&mask
+	if err := c.WriteSingleByte(address, value); err != nil {
+		return err
+	}
+	c.keep(address, value)
+	return nil
+}
+
+// keep records values written to consecutive configuration registers from
+// address in the profile, so that Init and Wake don't restore the old
+// configuration.
+func (c *CC1101) keep(address byte, values ...byte) {
+	if c.profile == nil {
+		c.profile = DefaultProfile()
+	}
+	for i, v := range values {
+		c.profile.setValue(address+byte(i), v)
+	}
+}
+
+// SetFrequency sets the carrier frequency.
+func (c *CC1101) SetFrequency(hz float64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	freq, err := frequencyRegisters(hz, c.crystal())
+	if err != nil {
+		return err
+	}
+	if err := c.WriteBurst(FREQ2, freq); err != nil {
+		return err
+	}
+	c.keep(FREQ2, freq...)
+	return nil
+}
+
+// SetDataRate sets the symbol rate.
+func (c *CC1101) SetDataRate(baud float64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, m, err := dataRateRegisters(baud, c.crystal())
+	if err != nil {
+		return err
+	}
+	if err := c.setRegister(MDMCFG4, 0x0f, e); err != nil {
+		return err
+	}
+	if err := c.WriteSingleByte(MDMCFG3, m); err != nil {
+		return err
+	}
+	c.keep(MDMCFG3, m)
+	return nil
+}
+
+// SetDeviation sets the frequency deviation for FSK modulations.
+func (c *CC1101) SetDeviation(hz float64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, m, err := deviationRegisters(hz, c.crystal())
+	if err != nil {
+		return err
+	}
+	if err := c.WriteSingleByte(DEVIATN, e<<4|m); err != nil {
+		return err
+	}
+	c.keep(DEVIATN, e<<4|m)
+	return nil
+}
+
+// SetRxBandwidth sets the receive channel filter to the narrowest setting at least hz wide.
+func (c *CC1101) SetRxBandwidth(hz float64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, m, err := rxBandwidthRegisters(hz, c.crystal())
+	if err != nil {
+		return err
+	}
+	return c.setRegister(MDMCFG4, 0xf0, e<<6|m<<4)
+}
+
+// SetModulation sets the modulation format.
+func (c *CC1101) SetModulation(m Modulation) error {
+	switch m {
+	case FSK2, GFSK, ASK, FSK4, MSK:
+	default:
+		return fmt.Errorf("invalid modulation: %v", m)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.setRegister(MDMCFG2, 0x70, byte(m)<<4)
+}
+
+// SetTxPower sets the output power to the highest level at most dBm for the
+// current frequency band.
+func (c *CC1101) SetTxPower(dBm int) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	settings, err := c.settings()
+	if err != nil {
+		return err
+	}
+	pa, err := paTableValue(dBm, settings.FrequencyHz)
+	if err != nil {
+		return err
+	}
+	paTable := []byte{pa}
+	// PA_POWER in FREND0 selects the highest PATABLE index used.
+	paPower := byte(0)
+	if settings.Modulation == ASK {
+		// OOK switches between PATABLE[0] for a 0 and PATABLE[1] for a 1.
+		paTable = []byte{0x00, pa}
+		paPower = 1
+	}
+	if err := c.WriteBurst(PATABLE, paTable); err != nil {
+		return err
+	}
+	c.paTable = paTable
+	return c.setRegister(FREND0, 0x07, paPower)
+}
+
+// Settings reads back and decodes the active radio configuration.
+func (c *CC1101) Settings() (RadioSettings, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.settings()
+}
+
+func (c *CC1101) settings() (RadioSettings, error) {
+	values, err := c.ReadBurst(IOCFG2, TEST0-IOCFG2+1)
+	if err != nil {
+		return RadioSettings{}, fmt.Errorf("failed to read registers: %w", err)
+	}
+	return decodeSettings(func(address byte) byte {
+		return values[address-IOCFG2]
+	}, c.crystal()), nil
+}
@@ -0,0 +1,185 @@
+package shinywaffle
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hatstand/shinywaffle/mocks"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDecodeSettings(t *testing.T) {
+	Convey("Default profile", t, func() {
+		s := DefaultProfile().Settings(DefaultCrystalHz)
+		So(s.FrequencyHz, ShouldAlmostEqual, 868.285e6, 1e3)
+		So(s.DataRateBaud, ShouldAlmostEqual, 1199.5, 0.1)
+		So(s.DeviationHz, ShouldAlmostEqual, 19043, 1)
+		So(s.RxBandwidthHz, ShouldAlmostEqual, 58036, 1)
+		So(s.Modulation, ShouldEqual, FSK2)
+	})
+}
+
+func TestRegisterCalculations(t *testing.T) {
+	Convey("Frequency", t, func() {
+		freq, err := frequencyRegisters(868.3e6, DefaultCrystalHz)
+		So(err, ShouldBeNil)
+		So(freq, ShouldResemble, []byte{0x21, 0x65, 0x6a})
+
+		freq, err = frequencyRegisters(433.92e6, DefaultCrystalHz)
+		So(err, ShouldBeNil)
+		So(freq, ShouldResemble, []byte{0x10, 0xb0, 0x71})
+
+		_, err = frequencyRegisters(600e6, DefaultCrystalHz)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Data rate", t, func() {
+		e, m, err := dataRateRegisters(1199.5, DefaultCrystalHz)
+		So(err, ShouldBeNil)
+		So(e, ShouldEqual, 0x05)
+		So(m, ShouldEqual, 0x83)
+
+		e, m, err = dataRateRegisters(38383.5, DefaultCrystalHz)
+		So(err, ShouldBeNil)
+		So(e, ShouldEqual, 0x0a)
+		So(m, ShouldEqual, 0x83)
+
+		_, _, err = dataRateRegisters(0, DefaultCrystalHz)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Deviation", t, func() {
+		e, m, err := deviationRegisters(19043, DefaultCrystalHz)
+		So(err, ShouldBeNil)
+		So(e, ShouldEqual, 3)
+		So(m, ShouldEqual, 4)
+
+		e, m, err = deviationRegisters(47607, DefaultCrystalHz)
+		So(err, ShouldBeNil)
+		So(e, ShouldEqual, 4)
+		So(m, ShouldEqual, 7)
+
+		_, _, err = deviationRegisters(1e6, DefaultCrystalHz)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("RX bandwidth", t, func() {
+		e, m, err := rxBandwidthRegisters(58e3, DefaultCrystalHz)
+		So(err, ShouldBeNil)
+		So(e, ShouldEqual, 3)
+		So(m, ShouldEqual, 3)
+
+		e, m, err = rxBandwidthRegisters(100e3, DefaultCrystalHz)
+		So(err, ShouldBeNil)
+		So(rxBandwidth(e, m, DefaultCrystalHz), ShouldAlmostEqual, 101562.5, 0.1)
+
+		_, _, err = rxBandwidthRegisters(1e6, DefaultCrystalHz)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("TX power", t, func() {
+		pa, err := paTableValue(0, 868.3e6)
+		So(err, ShouldBeNil)
+		So(pa, ShouldEqual, 0x50)
+
+		// Rounds down to the next supported level.
+		pa, err = paTableValue(9, 433.92e6)
+		So(err, ShouldBeNil)
+		So(pa, ShouldEqual, 0xc8)
+
+		_, err = paTableValue(12, 868.3e6)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+// expectSettings expects the registers to be read back holding profile.
+func expectSettings(bus *mocks.MockSPI, profile *RegisterProfile) *gomock.Call {
+	values := make([]byte, TEST0-IOCFG2+2)
+	for address, v := range resetValues {
+		values[address-IOCFG2+1] = v
+	}
+	for _, r := range profile.registers {
+		values[r.address-IOCFG2+1] = r.value
+	}
+	return bus.EXPECT().TransferAndReceiveData(burstRead{IOCFG2, TEST0 - IOCFG2 + 1}).SetArg(0, values)
+}
+
+func TestSetters(t *testing.T) {
+	Convey("SetFrequency", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		bus.EXPECT().TransferAndReceiveData([]byte{FREQ2 | WRITE_BURST, 0x10, 0xb0, 0x71})
+		So(cc1101.SetFrequency(433.92e6), ShouldBeNil)
+	}))
+
+	Convey("SetDataRate", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		gomock.InOrder(
+			bus.EXPECT().TransferAndReceiveData([]byte{MDMCFG4 | READ_SINGLE_BYTE, 0x00}).SetArg(0, []byte{0x00, 0xf5}),
+			bus.EXPECT().TransferAndReceiveData([]byte{MDMCFG4 | WRITE_SINGLE_BYTE, 0xfa}),
+			bus.EXPECT().TransferAndReceiveData([]byte{MDMCFG3 | WRITE_SINGLE_BYTE, 0x83}),
+		)
+		So(cc1101.SetDataRate(38383.5), ShouldBeNil)
+	}))
+
+	Convey("SetRxBandwidth", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		gomock.InOrder(
+			bus.EXPECT().TransferAndReceiveData([]byte{MDMCFG4 | READ_SINGLE_BYTE, 0x00}).SetArg(0, []byte{0x00, 0xf5}),
+			// 101.6kHz: CHANBW_E = 3, CHANBW_M = 0
+			bus.EXPECT().TransferAndReceiveData([]byte{MDMCFG4 | WRITE_SINGLE_BYTE, 0xc5}),
+		)
+		So(cc1101.SetRxBandwidth(100e3), ShouldBeNil)
+	}))
+
+	Convey("SetDeviation", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		bus.EXPECT().TransferAndReceiveData([]byte{DEVIATN | WRITE_SINGLE_BYTE, 0x47})
+		So(cc1101.SetDeviation(47607), ShouldBeNil)
+	}))
+
+	Convey("SetModulation", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		gomock.InOrder(
+			bus.EXPECT().TransferAndReceiveData([]byte{MDMCFG2 | READ_SINGLE_BYTE, 0x00}).SetArg(0, []byte{0x00, 0x03}),
+			bus.EXPECT().TransferAndReceiveData([]byte{MDMCFG2 | WRITE_SINGLE_BYTE, 0x13}),
+		)
+		So(cc1101.SetModulation(GFSK), ShouldBeNil)
+	}))
+
+	Convey("SetTxPower", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		gomock.InOrder(
+			expectSettings(bus, DefaultProfile()),
+			// 0dBm in the 868MHz band.
+			bus.EXPECT().TransferAndReceiveData([]byte{PATABLE | WRITE_BURST, 0x50}),
+			bus.EXPECT().TransferAndReceiveData([]byte{FREND0 | READ_SINGLE_BYTE, 0x00}).SetArg(0, []byte{0x00, 0x11}),
+			bus.EXPECT().TransferAndReceiveData([]byte{FREND0 | WRITE_SINGLE_BYTE, 0x10}),
+		)
+		So(cc1101.SetTxPower(0), ShouldBeNil)
+		So(cc1101.paTable, ShouldResemble, []byte{0x50})
+		frend0, ok := cc1101.profile.Value(FREND0)
+		So(ok, ShouldBeTrue)
+		So(frend0, ShouldEqual, 0x10)
+	}))
+
+	Convey("Settings", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		expectSettings(bus, DefaultProfile())
+		s, err := cc1101.Settings()
+		So(err, ShouldBeNil)
+		So(s, ShouldResemble, DefaultProfile().Settings(DefaultCrystalHz))
+	}))
+
+	Convey("Changes are kept in the profile for Init", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		gomock.InOrder(
+			bus.EXPECT().TransferAndReceiveData([]byte{FREQ2 | WRITE_BURST, 0x10, 0xb0, 0x71}),
+			bus.EXPECT().TransferAndReceiveData([]byte{DEVIATN | WRITE_SINGLE_BYTE, 0x47}),
+		)
+		So(cc1101.SetFrequency(433.92e6), ShouldBeNil)
+		So(cc1101.SetDeviation(47607), ShouldBeNil)
+		settings := cc1101.profile.Settings(DefaultCrystalHz)
+		So(settings.FrequencyHz, ShouldAlmostEqual, 433.92e6, 1e3)
+		So(settings.DeviationHz, ShouldAlmostEqual, 47607, 1)
+
+		expectInit(bus, cc1101.profile.registers)
+		So(cc1101.Init(), ShouldBeNil)
+	}))
+
+	Convey("Invalid modulation", t, WithBus(t, func(bus *mocks.MockSPI, cc1101 *CC1101) {
+		So(cc1101.SetModulation(2), ShouldNotBeNil)
+	}))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hatstand/shinywaffle/control"
+	"github.com/hatstand/shinywaffle/simulation"
+	"go.uber.org/zap"
+)
+
+var config = flag.String("config", "config.textproto", "Path to controller config proto")
+var weatherPath = flag.String("weather", "", "Path to recorded weather as CSV lines of an RFC 3339 time and a temperature")
+var metarPath = flag.String("metar", "", "Path to METAR history as fetched from ogimet, used for the weather instead of -weather")
+var outside = flag.Float64("outside", 5, "Constant outside temperature if there is no recorded weather")
+var startTime = flag.String("start", "", "RFC 3339 time to start simulating from. Defaults to the start of the recorded weather")
+var days = flag.Float64("days", 7, "Days to simulate")
+var schedule = flag.String("schedule", "07:00-22:00", "Daily periods every zone is heated, e.g. 07:00-09:00,17:00-22:00")
+var initial = flag.Float64("initial", simulation.DefaultZone().Temperature, "Temperature of every room at the start")
+var heatLoss = flag.Float64("heat_loss", simulation.DefaultRoomModel().HeatLoss, "Heat lost from each room per degree warmer than outside, in W/K")
+var thermalMass = flag.Float64("thermal_mass", simulation.DefaultRoomModel().ThermalMass/1000, "Heat needed to warm each room by a degree, in kJ/K")
+var radiatorMass = flag.Float64("radiator_mass", simulation.DefaultRoomModel().RadiatorMass/1000, "Heat needed to warm each room's radiators by a degree, in kJ/K")
+var radiatorTransfer = flag.Float64("radiator_transfer", simulation.DefaultRoomModel().RadiatorTransfer, "Heat each room's radiators give off per degree warmer than the room, in W/K")
+var radiatorPower = flag.Float64("radiator_power", simulation.DefaultRoomModel().RadiatorPower, "Power of radiators without their own in the config, in W")
+var solarGain = flag.Float64("solar_gain", simulation.DefaultRoomModel().SolarGain, "Heat each room gains from the sun at midday, in W")
+var sensorInterval = flag.Duration("sensor_interval", simulation.DefaultOptions().SensorInterval, "How often the sensors report")
+var reportPath = flag.String("report", "", "Path to write the report to as JSON")
+var verbose = flag.Bool("log", false, "Log the controller's decisions")
+
+func loadWeather() (*simulation.Weather, error) {
+	path, read := *weatherPath, simulation.ReadWeather
+	if *metarPath != "" {
+		path, read = *metarPath, simulation.ReadMETARs
+	}
+	if path == "" {
+		return simulation.ConstantWeather(*outside), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return read(f)
+}
+
+func main() {
+	flag.Parse()
+
+	c, err := control.LoadConfig(*config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	weather, err := loadWeather()
+	if err != nil {
+		log.Fatalf("Failed to load weather: %v", err)
+	}
+	periods, err := simulation.ParsePeriods(*schedule)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	options := simulation.DefaultOptions()
+	options.Duration = time.Duration(*days * float64(24*time.Hour))
+	options.SensorInterval = *sensorInterval
+	options.Start = weather.Start()
+	if *startTime != "" {
+		options.Start, err = time.Parse(time.RFC3339, *startTime)
+		if err != nil {
+			log.Fatalf("Invalid -start: %v", err)
+		}
+	} else if options.Start.IsZero() {
+		options.Start = time.Now().Truncate(24 * time.Hour)
+	}
+
+	zone := simulation.Zone{
+		Model: simulation.RoomModel{
+			HeatLoss:         *heatLoss,
+			ThermalMass:      *thermalMass * 1000,
+			RadiatorMass:     *radiatorMass * 1000,
+			RadiatorTransfer: *radiatorTransfer,
+			RadiatorPower:    *radiatorPower,
+			SolarGain:        *solarGain,
+		},
+		Temperature: *initial,
+		Schedule:    periods,
+	}
+	zones := make(map[string]simulation.Zone)
+	for _, z := range c.GetZone() {
+		zones[z.GetName()] = zone
+	}
+
+	logger := zap.NewNop().Sugar()
+	if *verbose {
+		l, err := zap.NewDevelopment()
+		if err != nil {
+			log.Fatalf("Failed to create logger: %v", err)
+		}
+		logger = l.Sugar()
+	}
+	sim, err := simulation.New(c, zones, weather, options, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	began := time.Now()
+	report := sim.Run()
+	log.Printf("Simulated %v in %v", options.Duration, time.Since(began).Round(time.Millisecond))
+	if err := report.WriteText(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+
+	if *reportPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*reportPath, data, 0644); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	}
+}
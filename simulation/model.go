@@ -0,0 +1,97 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// RoomModel is a lumped-capacitance model of a room. The room and its
+// contents are one thermal mass, heated by its radiators and the sun and
+// losing heat to outdoors. The radiators are a second, smaller mass between
+// their elements and the room, which delays heat reaching the room as with
+// the oil-filled radiators this controls.
+type RoomModel struct {
+	// Heat lost to outdoors per degree warmer than outside, in W/K.
+	HeatLoss float64
+	// Heat needed to warm the room by a degree, in J/K.
+	ThermalMass float64
+	// Heat needed to warm the radiators by a degree, in J/K. Radiators with
+	// none heat the room directly.
+	RadiatorMass float64
+	// Heat the radiators give off per degree warmer than the room, in W/K.
+	RadiatorTransfer float64
+	// Power of radiators without their own in the config, in W.
+	RadiatorPower float64
+	// Heat gained from the sun at midday, in W.
+	SolarGain float64
+}
+
+// DefaultRoomModel is a room of a poorly insulated flat, which takes about a
+// day to cool to the outside temperature and warms by about a degree an hour
+// with a single radiator on.
+func DefaultRoomModel() RoomModel {
+	return RoomModel{
+		HeatLoss:         60,
+		ThermalMass:      4e6,
+		RadiatorMass:     40e3,
+		RadiatorTransfer: 40,
+		RadiatorPower:    1500,
+		SolarGain:        200,
+	}
+}
+
+// Validate checks that the model is physically possible.
+func (m RoomModel) Validate() error {
+	if m.ThermalMass <= 0 {
+		return fmt.Errorf("thermal mass must be positive")
+	}
+	if m.HeatLoss < 0 || m.RadiatorMass < 0 || m.RadiatorPower < 0 || m.SolarGain < 0 {
+		return fmt.Errorf("negative heat loss, radiator mass, radiator power or solar gain")
+	}
+	if m.RadiatorMass > 0 && m.RadiatorTransfer <= 0 {
+		return fmt.Errorf("radiators with thermal mass must transfer heat to the room")
+	}
+	return nil
+}
+
+// temperatures is the state of a simulated room.
+type temperatures struct {
+	room     float64
+	radiator float64
+}
+
+// Longest step of the model, which is integrated with Euler's method.
+const maxModelStep = 10 * time.Second
+
+// step advances the model by d, with power going into the radiators and
+// outside the outside temperature. solar is the fraction of the solar gain
+// the sun provides.
+func (m RoomModel) step(t *temperatures, power, outside, solar float64, d time.Duration) {
+	h := maxModelStep.Seconds()
+	if m.RadiatorMass > 0 {
+		// Fast enough for the radiators not to overshoot the room.
+		h = math.Min(h, 0.1*m.RadiatorMass/m.RadiatorTransfer)
+	}
+	for left := d.Seconds(); left > 0; left -= h {
+		dt := math.Min(h, left)
+		heat := power
+		if m.RadiatorMass > 0 {
+			heat = m.RadiatorTransfer * (t.radiator - t.room)
+			t.radiator += (power - heat) * dt / m.RadiatorMass
+		} else {
+			t.radiator = t.room
+		}
+		t.room += (heat + solar*m.SolarGain - m.HeatLoss*(t.room-outside)) * dt / m.ThermalMass
+	}
+}
+
+// solarFraction is a crude estimate of how much sun a room gets at t: none
+// at night and rising to all of it at midday, whatever the season or cloud.
+func solarFraction(t time.Time) float64 {
+	hours := float64(t.Hour()) + float64(t.Minute())/60
+	if hours < 6 || hours > 18 {
+		return 0
+	}
+	return math.Sin((hours - 6) / 12 * math.Pi)
+}
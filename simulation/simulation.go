@@ -0,0 +1,427 @@
+// Package simulation runs the heating controller against modelled rooms, so
+// that changes to control can be evaluated without heating the real flat.
+//
+// Each zone of a controller config is a RoomModel driven by recorded
+// weather. The controller reads simulated sensors, follows daily schedules
+// and commands simulated radiators, whose own thermostats heat the room when
+// it is below the setting they were sent. Days of simulated time run in
+// seconds, and the Report gives each zone's comfort error, energy used and
+// how often its radiators switched.
+package simulation
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hatstand/shinywaffle/control"
+	"github.com/hatstand/shinywaffle/radiator"
+	"go.uber.org/zap"
+	"google.golang.org/api/calendar/v3"
+)
+
+// Period is a daily period a zone is heated, as offsets from midnight.
+type Period struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParsePeriods parses comma separated periods of the form 07:00-09:30.
+func ParsePeriods(s string) ([]Period, error) {
+	var periods []Period
+	for _, p := range strings.Split(s, ",") {
+		parts := strings.Split(strings.TrimSpace(p), "-")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid period %q", p)
+		}
+		var offsets [2]time.Duration
+		for i, part := range parts {
+			t, err := time.Parse("15:04", part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid period %q: %w", p, err)
+			}
+			offsets[i] = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		}
+		if offsets[1] <= offsets[0] {
+			return nil, fmt.Errorf("period %q ends before it starts", p)
+		}
+		periods = append(periods, Period{offsets[0], offsets[1]})
+	}
+	return periods, nil
+}
+
+// Zone is how a zone of the config is simulated.
+type Zone struct {
+	Model RoomModel
+	// Temperature of the room and its radiators at the start.
+	Temperature float64
+	// When the zone is scheduled to be heated to its target temperature.
+	Schedule []Period
+}
+
+// DefaultZone is the default room, starting cold and heated through the day.
+func DefaultZone() Zone {
+	return Zone{
+		Model:       DefaultRoomModel(),
+		Temperature: 15,
+		Schedule:    []Period{{7 * time.Hour, 22 * time.Hour}},
+	}
+}
+
+type Options struct {
+	Start    time.Time
+	Duration time.Duration
+	// How often the controller runs, every minute as in
+	// control.ControlRadiators.
+	TickInterval time.Duration
+	// How often the sensors report.
+	SensorInterval time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Duration:       7 * 24 * time.Hour,
+		TickInterval:   time.Minute,
+		SensorInterval: 10 * time.Minute,
+	}
+}
+
+// ZoneReport is how well a zone was controlled.
+type ZoneReport struct {
+	Name string
+	// Mean difference between the temperature and the target while
+	// scheduled, in °C.
+	ComfortError float64
+	// Mean difference between the temperature and the target as each
+	// scheduled period starts, in °C.
+	StartError float64
+	// Total time scheduled.
+	Scheduled time.Duration
+	// Energy used by the zone's radiators, in kWh.
+	Energy float64
+	// Times the zone's radiators switched on or off.
+	Switches int
+	// Commands sent to the zone's radiators.
+	Commands int
+	// Extremes of the room temperature.
+	MinTemperature float64
+	MaxTemperature float64
+}
+
+// Report is the outcome of a simulation.
+type Report struct {
+	Start  time.Time
+	Finish time.Time
+	Zones  []ZoneReport
+}
+
+// Energy returns the energy used by every zone, in kWh.
+func (r *Report) Energy() float64 {
+	var total float64
+	for _, z := range r.Zones {
+		total += z.Energy
+	}
+	return total
+}
+
+// Zone returns the report of the named zone.
+func (r *Report) Zone(name string) (ZoneReport, bool) {
+	for _, z := range r.Zones {
+		if z.Name == name {
+			return z, true
+		}
+	}
+	return ZoneReport{}, false
+}
+
+// WriteText writes a table of the zones' reports to w.
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Simulated %s to %s\n\n", r.Start.Format(time.RFC3339), r.Finish.Format(time.RFC3339))
+	t := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(t, "ZONE\tCOMFORT ERROR\tSTART ERROR\tSCHEDULED\tENERGY\tSWITCHES\tCOMMANDS\tMIN\tMAX")
+	for _, z := range r.Zones {
+		fmt.Fprintf(t, "%s\t%.2f°C\t%.2f°C\t%v\t%.1fkWh\t%d\t%d\t%.1f°C\t%.1f°C\n",
+			z.Name, z.ComfortError, z.StartError, z.Scheduled, z.Energy, z.Switches, z.Commands, z.MinTemperature, z.MaxTemperature)
+	}
+	fmt.Fprintf(t, "TOTAL\t\t\t\t%.1fkWh\t\t\t\t\n", r.Energy())
+	return t.Flush()
+}
+
+// simRadiator is a simulated radiator, with its own thermostat.
+type simRadiator struct {
+	room    *room
+	watts   float64
+	setting radiator.Setting
+	heating bool
+}
+
+// setpoint returns the temperature the radiator's thermostat heats to in
+// its current mode, or false if it doesn't heat.
+func (r *simRadiator) setpoint() (float64, bool) {
+	switch r.setting.Mode {
+	case radiator.Day, radiator.Auto:
+		return float64(r.setting.Day), true
+	case radiator.Night:
+		return float64(r.setting.Night), true
+	case radiator.Defrost:
+		return float64(r.setting.Defrost), true
+	}
+	return 0, false
+}
+
+// room is a simulated zone.
+type room struct {
+	name   string
+	zone   Zone
+	target float64
+	temps  temperatures
+	// Last reported by the zone's sensor.
+	reading control.Reading
+
+	radiators []*simRadiator
+	report    ZoneReport
+	// Sum of the comfort error over each tick scheduled.
+	errorSum float64
+	// Sum of the error at the start of each scheduled period, and their
+	// number.
+	startErrorSum float64
+	starts        int
+	wasScheduled  bool
+}
+
+// Simulator runs a controller against simulated rooms.
+type Simulator struct {
+	options    Options
+	weather    *Weather
+	rooms      []*room
+	byName     map[string]*room
+	radiators  map[radiator.Address]*simRadiator
+	controller *control.Controller
+	now        time.Time
+}
+
+// New returns a simulator of config's zones in weather. Zones missing from
+// zones are simulated as the DefaultZone.
+func New(config *control.Config, zones map[string]Zone, weather *Weather, options Options, logger *zap.SugaredLogger) (*Simulator, error) {
+	if options.TickInterval <= 0 || options.SensorInterval <= 0 || options.Duration <= 0 {
+		return nil, fmt.Errorf("tick and sensor intervals and duration must be positive")
+	}
+	s := &Simulator{
+		options:   options,
+		weather:   weather,
+		byName:    make(map[string]*room),
+		radiators: make(map[radiator.Address]*simRadiator),
+		now:       options.Start,
+	}
+	// Each zone has its own schedule, so its calendar is named after it.
+	config = proto.Clone(config).(*control.Config)
+	for _, z := range config.GetZone() {
+		z.CalendarId = z.GetName()
+	}
+	registry, err := control.NewRegistry(config)
+	if err != nil {
+		return nil, err
+	}
+	for _, z := range config.GetZone() {
+		zone, ok := zones[z.GetName()]
+		if !ok {
+			zone = DefaultZone()
+		}
+		if err := zone.Model.Validate(); err != nil {
+			return nil, fmt.Errorf("zone %s: %w", z.GetName(), err)
+		}
+		r := &room{
+			name:   z.GetName(),
+			zone:   zone,
+			target: float64(z.GetTargetTemperature()),
+			temps:  temperatures{room: zone.Temperature, radiator: zone.Temperature},
+			report: ZoneReport{
+				Name:           z.GetName(),
+				MinTemperature: zone.Temperature,
+				MaxTemperature: zone.Temperature,
+			},
+		}
+		for _, info := range registry.Zone(z.GetName()) {
+			watts := float64(info.Watts)
+			if watts == 0 {
+				watts = zone.Model.RadiatorPower
+			}
+			sim := &simRadiator{room: r, watts: watts, setting: radiator.Setting{Mode: radiator.Off}}
+			r.radiators = append(r.radiators, sim)
+			s.radiators[info.Address] = sim
+		}
+		s.rooms = append(s.rooms, r)
+		s.byName[r.name] = r
+	}
+	sort.Slice(s.rooms, func(i, j int) bool { return s.rooms[i].name < s.rooms[j].name })
+
+	s.controller, err = control.NewControllerWithSources(config, s, control.Sources{
+		Sensors:  s,
+		Schedule: s,
+		Weather:  s,
+		Now:      s.Now,
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Controller returns the simulated controller.
+func (s *Simulator) Controller() *control.Controller {
+	return s.controller
+}
+
+// Now returns the simulated time.
+func (s *Simulator) Now() time.Time {
+	return s.now
+}
+
+// Temperature returns the named zone's room temperature.
+func (s *Simulator) Temperature(zone string) (float64, bool) {
+	r, ok := s.byName[zone]
+	if !ok {
+		return 0, false
+	}
+	return r.temps.room, true
+}
+
+// Set commands a simulated radiator, implementing control.RadiatorController.
+func (s *Simulator) Set(addr radiator.Address, setting radiator.Setting) error {
+	r, ok := s.radiators[addr]
+	if !ok {
+		return fmt.Errorf("no radiator at %v", addr)
+	}
+	r.setting = setting
+	r.room.report.Commands++
+	return nil
+}
+
+// Readings returns each zone's last sensor report, implementing
+// control.SensorSource.
+func (s *Simulator) Readings() ([]control.Reading, error) {
+	var readings []control.Reading
+	for _, r := range s.rooms {
+		readings = append(readings, r.reading)
+	}
+	return readings, nil
+}
+
+// OutsideTemperature returns the simulated weather, implementing
+// control.WeatherSource.
+func (s *Simulator) OutsideTemperature() (float64, error) {
+	return s.weather.Temperature(s.now), nil
+}
+
+// GetSchedule returns the periods a zone is scheduled from an hour ago to a
+// week ahead, like the calendar, implementing control.ScheduleSource.
+func (s *Simulator) GetSchedule(calendarId string) ([]*calendar.TimePeriod, error) {
+	r, ok := s.byName[calendarId]
+	if !ok {
+		return nil, fmt.Errorf("no such calendar: %s", calendarId)
+	}
+	var periods []*calendar.TimePeriod
+	from, to := s.now.Add(-time.Hour), s.now.Add(7*24*time.Hour)
+	for day := midnight(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, p := range r.zone.Schedule {
+			start, end := day.Add(p.Start), day.Add(p.End)
+			if end.After(from) && start.Before(to) {
+				periods = append(periods, &calendar.TimePeriod{
+					Start: start.Format(time.RFC3339),
+					End:   end.Format(time.RFC3339),
+				})
+			}
+		}
+	}
+	return periods, nil
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// scheduled reports whether r is scheduled to be heated at t.
+func (r *room) scheduled(t time.Time) bool {
+	offset := t.Sub(midnight(t))
+	for _, p := range r.zone.Schedule {
+		if offset >= p.Start && offset < p.End {
+			return true
+		}
+	}
+	return false
+}
+
+// Run simulates the whole duration and reports on each zone.
+func (s *Simulator) Run() *Report {
+	end := s.options.Start.Add(s.options.Duration)
+	for s.now.Before(end) {
+		s.Step()
+	}
+	return s.Report()
+}
+
+// Step runs the controller once and then simulates the rooms until it next
+// runs.
+func (s *Simulator) Step() {
+	for _, r := range s.rooms {
+		if r.reading.At.IsZero() || s.now.Sub(r.reading.At) >= s.options.SensorInterval {
+			r.reading = control.Reading{Zone: r.name, Temperature: r.temps.room, At: s.now}
+		}
+	}
+	s.controller.Tick()
+
+	d := s.options.TickInterval
+	outside := s.weather.Temperature(s.now)
+	solar := solarFraction(s.now)
+	for _, r := range s.rooms {
+		var power float64
+		for _, rad := range r.radiators {
+			setpoint, ok := rad.setpoint()
+			heating := ok && r.temps.room < setpoint
+			if heating != rad.heating {
+				r.report.Switches++
+				rad.heating = heating
+			}
+			if heating {
+				power += rad.watts
+			}
+		}
+		r.zone.Model.step(&r.temps, power, outside, solar, d)
+
+		r.report.Energy += power * d.Hours() / 1000
+		r.report.MinTemperature = math.Min(r.report.MinTemperature, r.temps.room)
+		r.report.MaxTemperature = math.Max(r.report.MaxTemperature, r.temps.room)
+		scheduled := r.scheduled(s.now)
+		if scheduled {
+			r.report.Scheduled += d
+			r.errorSum += math.Abs(r.target - r.temps.room)
+		}
+		if scheduled && !r.wasScheduled {
+			r.startErrorSum += math.Abs(r.target - r.temps.room)
+			r.starts++
+		}
+		r.wasScheduled = scheduled
+	}
+	s.now = s.now.Add(d)
+}
+
+// Report reports on each zone so far.
+func (s *Simulator) Report() *Report {
+	report := &Report{Start: s.options.Start, Finish: s.now}
+	for _, r := range s.rooms {
+		z := r.report
+		if ticks := float64(z.Scheduled) / float64(s.options.TickInterval); ticks > 0 {
+			z.ComfortError = r.errorSum / ticks
+		}
+		if r.starts > 0 {
+			z.StartError = r.startErrorSum / float64(r.starts)
+		}
+		report.Zones = append(report.Zones, z)
+	}
+	return report
+}
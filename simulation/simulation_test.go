@@ -0,0 +1,218 @@
+package simulation
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle/control"
+	"go.uber.org/zap"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// A Monday.
+var start = time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+
+func testConfig() *control.Config {
+	return &control.Config{
+		Zone: []*control.Zone{
+			{
+				Name:              "Study",
+				TargetTemperature: 20,
+				Radiator: []*control.Radiator{
+					{Address: []byte{0x12, 0x34}, Watts: 1500},
+				},
+			},
+			{
+				Name:              "Kitchen",
+				TargetTemperature: 19,
+				Radiator: []*control.Radiator{
+					{Address: []byte{0x56, 0x78}},
+					{Address: []byte{0x56, 0x79}},
+				},
+			},
+		},
+	}
+}
+
+func simulate(weather *Weather, zones map[string]Zone) *Report {
+	options := DefaultOptions()
+	options.Start = start
+	s, err := New(testConfig(), zones, weather, options, zap.NewNop().Sugar())
+	So(err, ShouldBeNil)
+	return s.Run()
+}
+
+func TestRoomModel(t *testing.T) {
+	Convey("Settles where the heat loss matches the radiators", t, func() {
+		m := DefaultRoomModel()
+		temps := temperatures{room: 15, radiator: 15}
+		for i := 0; i < 14*24; i++ {
+			m.step(&temps, 600, 5, 0, time.Hour)
+		}
+		So(temps.room, ShouldAlmostEqual, 5+600/m.HeatLoss, 0.01)
+		So(temps.radiator, ShouldAlmostEqual, temps.room+600/m.RadiatorTransfer, 0.01)
+	})
+
+	Convey("Radiators take time to warm the room", t, func() {
+		m := DefaultRoomModel()
+		temps := temperatures{room: 15, radiator: 15}
+		m.step(&temps, 1500, 15, 0, time.Minute)
+		So(temps.radiator, ShouldBeGreaterThan, 15)
+		So(temps.room-15, ShouldBeLessThan, 0.001)
+
+		m.RadiatorMass = 0
+		temps = temperatures{room: 15, radiator: 15}
+		m.step(&temps, 1500, 15, 0, time.Minute)
+		So(temps.room, ShouldAlmostEqual, 15+1500*60/m.ThermalMass, 1e-4)
+	})
+
+	Convey("Sun only shines in the day", t, func() {
+		So(solarFraction(start), ShouldEqual, 0)
+		So(solarFraction(start.Add(12*time.Hour)), ShouldEqual, 1)
+		So(solarFraction(start.Add(9*time.Hour)), ShouldBeBetween, 0, 1)
+	})
+
+	Convey("Invalid", t, func() {
+		m := DefaultRoomModel()
+		m.ThermalMass = 0
+		So(m.Validate(), ShouldNotBeNil)
+		m = DefaultRoomModel()
+		m.RadiatorTransfer = 0
+		So(m.Validate(), ShouldNotBeNil)
+	})
+}
+
+func TestWeather(t *testing.T) {
+	Convey("Interpolates between observations", t, func() {
+		w, err := NewWeather([]Observation{
+			{start.Add(time.Hour), 10},
+			{start, 4},
+		})
+		So(err, ShouldBeNil)
+		So(w.Temperature(start.Add(-time.Hour)), ShouldEqual, 4)
+		So(w.Temperature(start.Add(30*time.Minute)), ShouldEqual, 7)
+		So(w.Temperature(start.Add(2*time.Hour)), ShouldEqual, 10)
+		So(w.Start(), ShouldEqual, start)
+		So(w.End(), ShouldEqual, start.Add(time.Hour))
+	})
+
+	Convey("Reads recorded weather", t, func() {
+		w, err := ReadWeather(strings.NewReader("# time,temperature\n2020-01-06T00:00:00Z,4.5\n2020-01-06T01:00:00Z, 3.5\n"))
+		So(err, ShouldBeNil)
+		So(w.Temperature(start.Add(30*time.Minute)), ShouldEqual, 4)
+
+		_, err = ReadWeather(strings.NewReader("2020-01-06T00:00:00Z,warm\n"))
+		So(err, ShouldNotBeNil)
+		_, err = ReadWeather(strings.NewReader(""))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Reads METAR history", t, func() {
+		w, err := ReadMETARs(strings.NewReader(`# METARs from ogimet
+202001060020 METAR EGLC 060020Z 24008KT 9999 FEW030 06/04 Q1020=
+202001060050 METAR EGLC 060050Z 24007KT 9999 FEW030 M02/M04 Q1020=
+`))
+		So(err, ShouldBeNil)
+		So(w.Temperature(start.Add(20*time.Minute)), ShouldEqual, 6)
+		So(w.Temperature(start.Add(50*time.Minute)), ShouldEqual, -2)
+	})
+}
+
+func TestParsePeriods(t *testing.T) {
+	Convey("Parses periods", t, func() {
+		periods, err := ParsePeriods("07:00-09:30, 17:00-22:00")
+		So(err, ShouldBeNil)
+		So(periods, ShouldResemble, []Period{
+			{7 * time.Hour, 9*time.Hour + 30*time.Minute},
+			{17 * time.Hour, 22 * time.Hour},
+		})
+	})
+
+	Convey("Invalid", t, func() {
+		for _, s := range []string{"", "07:00", "07:00-25:00", "09:00-07:00"} {
+			_, err := ParsePeriods(s)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
+
+func TestSimulation(t *testing.T) {
+	Convey("Runs a week of control", t, func() {
+		began := time.Now()
+		report := simulate(ConstantWeather(5), nil)
+		So(time.Since(began), ShouldBeLessThan, 10*time.Second)
+		So(report.Finish, ShouldEqual, start.Add(7*24*time.Hour))
+		So(report.Zones, ShouldHaveLength, 2)
+
+		for _, z := range report.Zones {
+			So(z.Scheduled, ShouldEqual, 7*15*time.Hour)
+			// Includes warming up each morning.
+			So(z.ComfortError, ShouldBeBetween, 0, 2)
+			So(z.MaxTemperature, ShouldBeLessThan, 22)
+			So(z.Switches, ShouldBeGreaterThan, 14)
+			So(z.Commands, ShouldBeGreaterThanOrEqualTo, z.Switches/2)
+			So(z.Energy, ShouldBeGreaterThan, 0)
+		}
+		So(report.Energy(), ShouldAlmostEqual, report.Zones[0].Energy+report.Zones[1].Energy)
+
+		var text strings.Builder
+		So(report.WriteText(&text), ShouldBeNil)
+		So(text.String(), ShouldContainSubstring, "Kitchen")
+		So(text.String(), ShouldContainSubstring, "TOTAL")
+	})
+
+	Convey("Uses less energy when it's warmer", t, func() {
+		cold := simulate(ConstantWeather(0), nil)
+		mild := simulate(ConstantWeather(10), nil)
+		So(mild.Energy(), ShouldBeLessThan, cold.Energy())
+	})
+
+	Convey("Follows each zone's schedule", t, func() {
+		evenings := DefaultZone()
+		evenings.Schedule = []Period{{18 * time.Hour, 22 * time.Hour}}
+		report := simulate(ConstantWeather(5), map[string]Zone{"Study": evenings})
+		study, ok := report.Zone("Study")
+		So(ok, ShouldBeTrue)
+		kitchen, _ := report.Zone("Kitchen")
+		So(study.Scheduled, ShouldEqual, 7*4*time.Hour)
+		So(study.Energy, ShouldBeLessThan, kitchen.Energy)
+	})
+
+	Convey("Rejects invalid models", t, func() {
+		zone := DefaultZone()
+		zone.Model.ThermalMass = 0
+		_, err := New(testConfig(), map[string]Zone{"Study": zone}, ConstantWeather(5), DefaultOptions(), zap.NewNop().Sugar())
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestOptimalStart(t *testing.T) {
+	run := func(disable bool) *Report {
+		config := testConfig()
+		config.DisableOptimalStart = disable
+		// Long enough to heat the Study with its single radiator from cold.
+		config.MaxPreheatMinutes = 10 * 60
+		options := DefaultOptions()
+		options.Start = start
+		options.Duration = 14 * 24 * time.Hour
+		s, err := New(config, nil, ConstantWeather(5), options, zap.NewNop().Sugar())
+		So(err, ShouldBeNil)
+		return s.Run()
+	}
+
+	Convey("Rooms are warm as their periods start", t, func() {
+		late := run(true)
+		early := run(false)
+		for _, name := range []string{"Study", "Kitchen"} {
+			l, _ := late.Zone(name)
+			e, _ := early.Zone(name)
+			So(l.StartError, ShouldBeGreaterThan, 3)
+			// Including the first day before anything is learnt.
+			So(e.StartError, ShouldBeLessThan, 1.5)
+			So(e.ComfortError, ShouldBeLessThan, l.ComfortError)
+			So(e.Energy, ShouldBeGreaterThan, l.Energy)
+		}
+	})
+}
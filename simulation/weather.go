@@ -0,0 +1,130 @@
+package simulation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hatstand/shinywaffle/metar"
+)
+
+// Observation is an outside temperature at a time.
+type Observation struct {
+	Time        time.Time
+	Temperature float64
+}
+
+// Weather is the outside temperature over time, interpolated between
+// observations and held before the first and after the last.
+type Weather struct {
+	observations []Observation
+}
+
+// NewWeather returns the weather with the given observations, in any order.
+func NewWeather(observations []Observation) (*Weather, error) {
+	if len(observations) == 0 {
+		return nil, fmt.Errorf("no weather observations")
+	}
+	sorted := append([]Observation(nil), observations...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+	return &Weather{observations: sorted}, nil
+}
+
+// ConstantWeather returns weather which is always temp.
+func ConstantWeather(temp float64) *Weather {
+	return &Weather{observations: []Observation{{Temperature: temp}}}
+}
+
+// WeatherFromMETARs returns the weather from routine METAR reports.
+// Forecasts are ignored.
+func WeatherFromMETARs(metars []*metar.METAR) (*Weather, error) {
+	var observations []Observation
+	for _, m := range metars {
+		if m.ReportType != metar.Routine {
+			continue
+		}
+		observations = append(observations, Observation{
+			Time:        m.DateTime,
+			Temperature: float64(m.Temperature),
+		})
+	}
+	return NewWeather(observations)
+}
+
+// ReadMETARs reads the weather from METAR history in the text format
+// fetched by metar.FetchMETARs.
+func ReadMETARs(r io.Reader) (*Weather, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	metars, err := metar.ParseMETARs(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return WeatherFromMETARs(metars)
+}
+
+// ReadWeather reads recorded weather as CSV lines of an RFC 3339 time and a
+// temperature in °C. Lines starting with # are ignored.
+func ReadWeather(r io.Reader) (*Weather, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	var observations []Observation
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		temp, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		observations = append(observations, Observation{Time: t, Temperature: temp})
+	}
+	return NewWeather(observations)
+}
+
+// Start returns the time of the first observation.
+func (w *Weather) Start() time.Time {
+	return w.observations[0].Time
+}
+
+// End returns the time of the last observation.
+func (w *Weather) End() time.Time {
+	return w.observations[len(w.observations)-1].Time
+}
+
+// Temperature returns the outside temperature at t.
+func (w *Weather) Temperature(t time.Time) float64 {
+	obs := w.observations
+	i := sort.Search(len(obs), func(i int) bool {
+		return obs[i].Time.After(t)
+	})
+	if i == 0 {
+		return obs[0].Temperature
+	}
+	if i == len(obs) {
+		return obs[len(obs)-1].Temperature
+	}
+	before, after := obs[i-1], obs[i]
+	f := float64(t.Sub(before.Time)) / float64(after.Time.Sub(before.Time))
+	return before.Temperature + f*(after.Temperature-before.Temperature)
+}
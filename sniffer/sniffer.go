@@ -0,0 +1,132 @@
+// Package sniffer decodes radiator traffic received by a CC1101 into events,
+// such as someone changing a radiator's setting with its wall remote.
+package sniffer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hatstand/shinywaffle"
+	"github.com/hatstand/shinywaffle/radiator"
+)
+
+// The wall remotes send each packet several times in quick succession.
+const repeatWindow = 5 * time.Second
+
+// Event is a decoded radiator packet.
+type Event struct {
+	Time time.Time `json:"time"`
+	// Zone the radiator is configured in, or empty if it is unknown.
+	Zone    string           `json:"zone,omitempty"`
+	Address radiator.Address `json:"address"`
+	// Whether the packet paired a radiator rather than changing its setting.
+	Pairing bool             `json:"pairing,omitempty"`
+	Setting radiator.Setting `json:"setting"`
+	// The setting last seen for the radiator, if any.
+	Previous *radiator.Setting `json:"previous,omitempty"`
+	RSSI     int               `json:"rssi"`
+	LQI      byte              `json:"lqi"`
+}
+
+func (e *Event) String() string {
+	zone := e.Zone
+	if zone == "" {
+		zone = "unknown zone"
+	}
+	kind := "set"
+	if e.Pairing {
+		kind = "paired"
+	}
+	return fmt.Sprintf("%s %v (%s) %s %v day: %.1f night: %.1f defrost: %.1f rssi: %ddBm",
+		e.Time.Format(time.RFC3339), e.Address, zone, kind,
+		e.Setting.Mode, e.Setting.Day, e.Setting.Night, e.Setting.Defrost, e.RSSI)
+}
+
+// Sniffer decodes frames into events, attributing them to zones by radiator
+// address and dropping repeated packets.
+type Sniffer struct {
+	registry *radiator.Registry
+	last     map[radiator.Address]*Event
+}
+
+// New returns a sniffer looking up radiators in registry, which may be nil.
+// Received packets are recorded in the registry as radiators being seen.
+func New(registry *radiator.Registry) *Sniffer {
+	return &Sniffer{
+		registry: registry,
+		last:     make(map[radiator.Address]*Event),
+	}
+}
+
+// Decode returns the event for a received frame, or nil if the frame repeats
+// an earlier one.
+func (s *Sniffer) Decode(f shinywaffle.Frame) (*Event, error) {
+	if !f.CRCOK {
+		return nil, fmt.Errorf("CRC check failed")
+	}
+	p, err := radiator.Unmarshal(f.Payload)
+	if err != nil {
+		return nil, err
+	}
+	e := &Event{
+		Time:    f.Time,
+		Address: p.Address,
+		Pairing: p.IsPairing(),
+		Setting: p.Setting,
+		RSSI:    f.RSSI,
+		LQI:     f.LQI,
+	}
+	if s.registry != nil {
+		if info, ok := s.registry.Lookup(p.Address); ok {
+			e.Zone = info.Zone
+			s.registry.Seen(p.Address, f.Time, f.RSSI)
+		}
+	}
+	last, ok := s.last[p.Address]
+	if ok && last.Pairing == e.Pairing && last.Setting == e.Setting && e.Time.Sub(last.Time) < repeatWindow {
+		last.Time = e.Time
+		return nil, nil
+	}
+	if ok {
+		previous := last.Setting
+		e.Previous = &previous
+	}
+	s.last[p.Address] = e
+	return e, nil
+}
+
+// Run decodes frames until the channel is closed or ctx is cancelled.
+// Frames that fail to decode are passed to onError, which may be nil.
+func (s *Sniffer) Run(ctx context.Context, frames <-chan shinywaffle.Frame, onError func(shinywaffle.Frame, error)) <-chan *Event {
+	events := make(chan *Event)
+	go func() {
+		defer close(events)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case f, ok := <-frames:
+				if !ok {
+					return
+				}
+				e, err := s.Decode(f)
+				if err != nil {
+					if onError != nil {
+						onError(f, err)
+					}
+					continue
+				}
+				if e == nil {
+					continue
+				}
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events
+}
@@ -0,0 +1,134 @@
+package sniffer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle"
+	"github.com/hatstand/shinywaffle/emulator"
+	"github.com/hatstand/shinywaffle/radiator"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	studyOn        = []byte{87, 22, 10, 46, 4, 5, 61, 38, 14}
+	studyOff       = []byte{87, 22, 10, 46, 4, 5, 40, 38, 14}
+	kitchenPairing = []byte{0x57, 0x96, 0x0a, 0x2b, 0x7e, 0x11, 0x28, 0x26, 0x14}
+)
+
+func frame(payload []byte, t time.Time) shinywaffle.Frame {
+	return shinywaffle.Frame{Payload: payload, RSSI: -60, LQI: 3, CRCOK: true, Time: t}
+}
+
+func TestDecode(t *testing.T) {
+	zones := radiator.NewRegistry()
+	zones.Add(radiator.Info{Address: 0x2e04, Zone: "Study"})
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	Convey("Setting change", t, func() {
+		s := New(zones)
+		e, err := s.Decode(frame(studyOn, start))
+		So(err, ShouldBeNil)
+		So(e, ShouldResemble, &Event{
+			Time:    start,
+			Zone:    "Study",
+			Address: 0x2e04,
+			Setting: radiator.Setting{Mode: radiator.Day, Day: 30.5, Night: 19, Defrost: 7},
+			RSSI:    -60,
+			LQI:     3,
+		})
+
+		e, err = s.Decode(frame(studyOff, start.Add(time.Minute)))
+		So(err, ShouldBeNil)
+		So(e.Setting.Day, ShouldEqual, 20)
+		So(e.Previous, ShouldResemble, &radiator.Setting{Mode: radiator.Day, Day: 30.5, Night: 19, Defrost: 7})
+	})
+
+	Convey("Repeats are dropped", t, func() {
+		s := New(zones)
+		e, err := s.Decode(frame(studyOn, start))
+		So(err, ShouldBeNil)
+		So(e, ShouldNotBeNil)
+		for i := 1; i <= 3; i++ {
+			e, err = s.Decode(frame(studyOn, start.Add(time.Duration(i)*time.Second)))
+			So(err, ShouldBeNil)
+			So(e, ShouldBeNil)
+		}
+		// The same setting again later is a new event.
+		e, err = s.Decode(frame(studyOn, start.Add(time.Minute)))
+		So(err, ShouldBeNil)
+		So(e, ShouldNotBeNil)
+	})
+
+	Convey("Pairing in an unknown zone", t, func() {
+		s := New(zones)
+		e, err := s.Decode(frame(kitchenPairing, start))
+		So(err, ShouldBeNil)
+		So(e.Pairing, ShouldBeTrue)
+		So(e.Zone, ShouldEqual, "")
+		So(e.Address, ShouldEqual, 0x2b7e)
+		So(e.Setting.Mode, ShouldEqual, radiator.Auto)
+	})
+
+	Convey("Invalid frames", t, func() {
+		s := New(zones)
+		f := frame(studyOn, start)
+		f.CRCOK = false
+		_, err := s.Decode(f)
+		So(err, ShouldNotBeNil)
+		_, err = s.Decode(frame([]byte{0x01, 0x02}, start))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("JSON", t, func() {
+		e, err := New(zones).Decode(frame(studyOn, start))
+		So(err, ShouldBeNil)
+		b, err := json.Marshal(e)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldContainSubstring, `"Mode":"DAY"`)
+		So(string(b), ShouldContainSubstring, `"zone":"Study"`)
+		So(string(b), ShouldContainSubstring, `"address":"2e04"`)
+	})
+}
+
+func TestRun(t *testing.T) {
+	Convey("Sniffs a radio", t, func() {
+		radio := emulator.New()
+		cc1101, err := shinywaffle.New(radio, radio.GDO0(), radio.GDO2(), shinywaffle.DefaultProfile())
+		So(err, ShouldBeNil)
+		defer cc1101.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		frames, err := cc1101.Listen(ctx)
+		So(err, ShouldBeNil)
+		decodeErrors := make(chan error, 1)
+		registry := radiator.NewRegistry()
+		registry.Add(radiator.Info{Address: 0x2e04, Zone: "Study"})
+		events := New(registry).Run(ctx, frames, func(_ shinywaffle.Frame, err error) {
+			decodeErrors <- err
+		})
+
+		So(radio.Receive(emulator.Packet{Payload: []byte{0x42}}), ShouldBeTrue)
+		So(<-decodeErrors, ShouldNotBeNil)
+		// Wait for the driver to return to RX.
+		for radio.State() != emulator.StateRX {
+			time.Sleep(time.Millisecond)
+		}
+		So(radio.Receive(emulator.Packet{Payload: studyOn, RSSI: -70}), ShouldBeTrue)
+		e := <-events
+		So(e.Zone, ShouldEqual, "Study")
+		So(e.RSSI, ShouldEqual, -70)
+		info, _ := registry.Lookup(0x2e04)
+		So(info.LastRSSI, ShouldEqual, -70)
+
+		cancel()
+		for range events {
+		}
+		for range frames {
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/hatstand/shinywaffle/control"
 	"github.com/hatstand/shinywaffle/wirelesstag"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/metric/instrument"
@@ -65,3 +66,43 @@ func NewPublisher(mp metric.MeterProvider, logger *zap.SugaredLogger) *Publisher
 		logger: logger,
 	}
 }
+
+// PublishTransmitStats exports the radio transmit queue counters.
+func (p *Publisher) PublishTransmitStats(stats func() control.TransmitStats) error {
+	m := p.mp.Meter("github.com/hatstand/shinywaffle", metric.WithSchemaURL("custom.googleapis.com/shinywaffle"))
+
+	depth, err := m.AsyncInt64().Gauge("transmit_queue_depth", instrument.WithDescription("Radiators with commands waiting to be sent"))
+	if err != nil {
+		return fmt.Errorf("failed to create gauge: %w", err)
+	}
+	sent, err := m.AsyncInt64().Counter("transmit_sent", instrument.WithDescription("Packets transmitted"))
+	if err != nil {
+		return fmt.Errorf("failed to create counter: %w", err)
+	}
+	failed, err := m.AsyncInt64().Counter("transmit_failed", instrument.WithDescription("Packets the radio failed to send"))
+	if err != nil {
+		return fmt.Errorf("failed to create counter: %w", err)
+	}
+	coalesced, err := m.AsyncInt64().Counter("transmit_coalesced", instrument.WithDescription("Commands replaced by a newer command for the same radiator"))
+	if err != nil {
+		return fmt.Errorf("failed to create counter: %w", err)
+	}
+	dropped, err := m.AsyncInt64().Counter("transmit_dropped", instrument.WithDescription("Commands dropped by the transmit queue"))
+	if err != nil {
+		return fmt.Errorf("failed to create counter: %w", err)
+	}
+	airtime, err := m.AsyncFloat64().Gauge("transmit_airtime", instrument.WithUnit("s"), instrument.WithDescription("Time spent transmitting in the duty cycle window"))
+	if err != nil {
+		return fmt.Errorf("failed to create gauge: %w", err)
+	}
+
+	return m.RegisterCallback([]instrument.Asynchronous{depth, sent, failed, coalesced, dropped, airtime}, func(ctx context.Context) {
+		s := stats()
+		depth.Observe(ctx, int64(s.QueueDepth))
+		sent.Observe(ctx, int64(s.Sent))
+		failed.Observe(ctx, int64(s.Failed))
+		coalesced.Observe(ctx, int64(s.Coalesced))
+		dropped.Observe(ctx, int64(s.Dropped))
+		airtime.Observe(ctx, s.Airtime.Seconds())
+	})
+}
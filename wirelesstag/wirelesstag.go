@@ -32,6 +32,21 @@ type Tag struct {
 	Humidity         float64 `json:"cap"`
 	Type             int     `json:"tagType"`
 	ID               int     `json:"slaveId"`
+	// When the tag last reported, as a Windows FILETIME.
+	LastComm int64 `json:"lastComm"`
+}
+
+// Windows FILETIMEs count 100ns intervals since 1601.
+const fileTimeUnixEpoch = 116444736000000000
+
+// LastCommunication returns when the tag last reported, or the zero time if
+// unknown.
+func (t *Tag) LastCommunication() time.Time {
+	if t.LastComm <= fileTimeUnixEpoch {
+		return time.Time{}
+	}
+	ticks := t.LastComm - fileTimeUnixEpoch
+	return time.Unix(ticks/1e7, ticks%1e7*100)
 }
 
 type TagList struct {
@@ -38,3 +38,14 @@ func TestCacheDir(t *testing.T) {
 		So(path, ShouldEndWith, ".credentials/mytaglist.json")
 	})
 }
+
+func TestLastCommunication(t *testing.T) {
+	Convey("Converts from a Windows FILETIME", t, func() {
+		tag := &Tag{LastComm: 132223104000000000}
+		So(tag.LastCommunication().Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)), ShouldBeTrue)
+	})
+
+	Convey("Unknown", t, func() {
+		So((&Tag{}).LastCommunication().IsZero(), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,198 @@
+package shinywaffle
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// WORCTRL fields.
+	WORCTRL_RC_PD  = 0x80 // Power down the RC oscillator used for Wake-on-Radio
+	WORCTRL_EVENT1 = 0x70 // Time from the RC oscillator waking the crystal to RX
+	WORCTRL_RC_CAL = 0x08 // Calibrate the RC oscillator
+	WORCTRL_RES    = 0x03 // Resolution of EVENT0
+
+	// MCSM2 RX_TIME value that disables the RX timeout.
+	RX_TIME_NONE = 0x07
+
+	// How long to wait for the crystal to start when waking up.
+	wakeTimeout = 10 * time.Millisecond
+)
+
+// Registers that lose their values in SLEEP and have to be restored.
+var sleepVolatileRegisters = []byte{FSTEST, PTEST, AGCTEST, TEST2, TEST1, TEST0}
+
+// Multipliers of the RX timeout for each WOR_RES from the datasheet.
+var rxTimeoutFactors = [4]float64{1, 5, 9, 13}
+
+// event0Period returns the time represented by one unit of EVENT0.
+func event0Period(res byte, xtal float64) float64 {
+	return 750 / xtal * math.Pow(2, float64(5*res))
+}
+
+// rxTimeout returns how long the radio listens for each Wake-on-Radio poll.
+func rxTimeout(event0 uint16, res byte, rxTime byte, xtal float64) float64 {
+	return float64(event0) * 750 / xtal / 8 * rxTimeoutFactors[res] / math.Pow(2, float64(rxTime))
+}
+
+// worRegisters returns EVENT0 and WOR_RES for polling every interval and
+// RX_TIME for the shortest RX timeout of at least timeout.
+func worRegisters(interval, timeout time.Duration, xtal float64) (event0 uint16, res byte, rxTime byte, err error) {
+	if interval <= 0 || timeout <= 0 {
+		return 0, 0, 0, fmt.Errorf("invalid Wake-on-Radio interval %v and RX timeout %v", interval, timeout)
+	}
+	for res = 0; res < 4; res++ {
+		e := math.Round(interval.Seconds() / event0Period(res, xtal))
+		if e <= math.MaxUint16 {
+			event0 = uint16(e)
+			break
+		}
+	}
+	if res == 4 || event0 == 0 {
+		return 0, 0, 0, fmt.Errorf("Wake-on-Radio interval %v out of range", interval)
+	}
+
+	for rxTime = RX_TIME_NONE - 1; ; rxTime-- {
+		if rxTimeout(event0, res, rxTime, xtal) >= timeout.Seconds() {
+			return event0, res, rxTime, nil
+		}
+		if rxTime == 0 {
+			return 0, 0, 0, fmt.Errorf("RX timeout %v too long for an interval of %v", timeout, interval)
+		}
+	}
+}
+
+// EnableWakeOnRadio makes the radio sleep and wake every interval to listen
+// for rxTimeout rather than stay in RX, trading latency for battery life.
+// Packets must be sent with a preamble longer than interval to be caught.
+// It takes effect immediately if Listen is running and otherwise once it starts.
+func (c *CC1101) EnableWakeOnRadio(interval, rxTimeout time.Duration) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	event0, res, rxTime, err := worRegisters(interval, rxTimeout, c.crystal())
+	if err != nil {
+		return err
+	}
+	if err := c.wake(); err != nil {
+		return err
+	}
+	for _, r := range []register{
+		{WOREVT1, byte(event0 >> 8)},
+		{WOREVT0, byte(event0)},
+		// EVENT1 of 7 gives the crystal ~1.4ms to start.
+		{WORCTRL, WORCTRL_EVENT1 | WORCTRL_RC_CAL | res},
+		{MCSM2, rxTime},
+	} {
+		if err := c.WriteSingleByte(r.address, r.value); err != nil {
+			return fmt.Errorf("failed to write %s: %w", registerName(r.address), err)
+		}
+	}
+	c.wakeOnRadio = true
+	if c.listening {
+		return c.enterWakeOnRadio()
+	}
+	return nil
+}
+
+// DisableWakeOnRadio wakes the radio and returns to continuous RX if Listen is running.
+func (c *CC1101) DisableWakeOnRadio() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.wakeOnRadio = false
+	if err := c.wake(); err != nil {
+		return err
+	}
+	if err := c.updateRegister(WORCTRL, WORCTRL_RC_PD, WORCTRL_RC_PD); err != nil {
+		return err
+	}
+	if err := c.WriteSingleByte(MCSM2, RX_TIME_NONE); err != nil {
+		return err
+	}
+	if c.listening {
+		return c.SetRx()
+	}
+	return nil
+}
+
+// enterWakeOnRadio starts polling from IDLE with an empty RX FIFO.
+func (c *CC1101) enterWakeOnRadio() error {
+	if err := c.wake(); err != nil {
+		return err
+	}
+	c.FlushRx()
+	if _, err := c.Strobe(SWORRST); err != nil {
+		return err
+	}
+	if _, err := c.Strobe(SWOR); err != nil {
+		return err
+	}
+	c.sleeping = true
+	return nil
+}
+
+// Sleep powers the radio down to its lowest power state. Any later use wakes it.
+func (c *CC1101) Sleep() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.wake(); err != nil {
+		return err
+	}
+	if err := c.SetIdle(); err != nil {
+		return err
+	}
+	if _, err := c.Strobe(SPWD); err != nil {
+		return err
+	}
+	c.sleeping = true
+	return nil
+}
+
+// Wake brings the radio out of SLEEP into IDLE.
+func (c *CC1101) Wake() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.wake()
+}
+
+// wake waits for the crystal to start after pulling CSn low and restores the
+// registers that are lost in SLEEP. It does nothing if the radio is awake.
+func (c *CC1101) wake() error {
+	if !c.sleeping {
+		return nil
+	}
+	deadline := time.Now().Add(wakeTimeout)
+	for {
+		status, err := c.Strobe(SIDLE)
+		if err != nil {
+			return fmt.Errorf("failed to wake radio: %w", err)
+		}
+		if status&CHIP_RDYN == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for radio to wake")
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+	c.sleeping = false
+
+	if c.profile != nil {
+		for _, address := range sleepVolatileRegisters {
+			if v, ok := c.profile.Value(address); ok {
+				if err := c.WriteSingleByte(address, v); err != nil {
+					return fmt.Errorf("failed to restore %s: %w", registerName(address), err)
+				}
+			}
+		}
+	}
+	if c.paTable != nil {
+		if err := c.WriteBurst(PATABLE, c.paTable); err != nil {
+			return fmt.Errorf("failed to restore PATABLE: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package shinywaffle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hatstand/shinywaffle/emulator"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWakeOnRadioRegisters(t *testing.T) {
+	Convey("Short interval", t, func() {
+		event0, res, rxTime, err := worRegisters(500*time.Millisecond, 5*time.Millisecond, DefaultCrystalHz)
+		So(err, ShouldBeNil)
+		So(event0, ShouldEqual, 17333)
+		So(res, ShouldEqual, 0)
+		// 62.5ms / 2^3
+		So(rxTime, ShouldEqual, 3)
+	})
+
+	Convey("Long interval", t, func() {
+		event0, res, rxTime, err := worRegisters(5*time.Second, 50*time.Millisecond, DefaultCrystalHz)
+		So(err, ShouldBeNil)
+		So(event0, ShouldEqual, 5417)
+		So(res, ShouldEqual, 1)
+		So(rxTime, ShouldEqual, 0)
+	})
+
+	Convey("Invalid", t, func() {
+		_, _, _, err := worRegisters(0, time.Millisecond, DefaultCrystalHz)
+		So(err, ShouldNotBeNil)
+		_, _, _, err = worRegisters(500*time.Millisecond, 100*time.Millisecond, DefaultCrystalHz)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestWakeOnRadio(t *testing.T) {
+	Convey("Listen with Wake-on-Radio", t, WithEmulator(t, func(radio *emulator.Radio, cc1101 *CC1101) {
+		So(cc1101.EnableWakeOnRadio(500*time.Millisecond, 5*time.Millisecond), ShouldBeNil)
+		So(radio.Register(WOREVT1), ShouldEqual, 17333>>8)
+		So(radio.Register(WOREVT0), ShouldEqual, 17333&0xff)
+		So(radio.Register(WORCTRL)&WORCTRL_RC_PD, ShouldEqual, 0)
+		So(radio.Register(MCSM2), ShouldEqual, 3)
+		// Not polling until Listen starts.
+		So(radio.WakeOnRadio(), ShouldBeFalse)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		frames, err := cc1101.Listen(ctx)
+		So(err, ShouldBeNil)
+		So(radio.WakeOnRadio(), ShouldBeTrue)
+
+		So(radio.Receive(emulator.Packet{Payload: []byte{0x42}}), ShouldBeTrue)
+		f := <-frames
+		So(f.Payload, ShouldResemble, []byte{0x42})
+
+		// Sending wakes the radio and then returns to polling.
+		So(cc1101.Send([]byte{0x43}), ShouldBeNil)
+		So(radio.Sent(), ShouldHaveLength, 1)
+		So(radio.WakeOnRadio(), ShouldBeTrue)
+
+		So(cc1101.DisableWakeOnRadio(), ShouldBeNil)
+		So(radio.WakeOnRadio(), ShouldBeFalse)
+		So(radio.State(), ShouldEqual, emulator.StateRX)
+		So(radio.Register(WORCTRL)&WORCTRL_RC_PD, ShouldEqual, WORCTRL_RC_PD)
+
+		cancel()
+		for range frames {
+		}
+	}))
+
+	Convey("Sleep", t, WithEmulator(t, func(radio *emulator.Radio, cc1101 *CC1101) {
+		So(cc1101.SetTxPower(10), ShouldBeNil)
+		So(cc1101.Sleep(), ShouldBeNil)
+		So(radio.State(), ShouldEqual, emulator.StateSleep)
+		So(radio.Receive(emulator.Packet{Payload: []byte{0x42}}), ShouldBeFalse)
+		So(cc1101.Wake(), ShouldBeNil)
+		So(radio.State(), ShouldEqual, emulator.StateIdle)
+		// Registers lost in SLEEP are restored.
+		v, _ := DefaultProfile().Value(TEST2)
+		So(radio.Register(TEST2), ShouldEqual, v)
+		So(radio.PATable()[0], ShouldEqual, 0xc2)
+	}))
+}